@@ -0,0 +1,130 @@
+package gormpher
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errNotFound = errors.New("not found")
+
+// ActionError is the error of action with the HTTP status code, such as 404 when
+// the object is not found, 403 when the action is rejected by Authorizer.
+type ActionError struct {
+	Code int
+	Err  error
+}
+
+func (e *ActionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ActionError) Unwrap() error {
+	return e.Err
+}
+
+// handleActionError respond the error of action, 500 when err is not ActionError.
+func handleActionError(c *gin.Context, err error) {
+	var e *ActionError
+	if errors.As(err, &e) {
+		handleError(c, e.Code, e.Err)
+		return
+	}
+	handleError(c, http.StatusInternalServerError, err)
+}
+
+// checkAction check obj is built, and the action is allowed by AllowMethods.
+func (obj *WebObject) checkAction(action int) error {
+	if obj.modelElem == nil {
+		return fmt.Errorf("%s is not built, register or build it before", obj.Name)
+	}
+	allowMethods := obj.AllowMethods
+	if allowMethods == 0 {
+		allowMethods = GET | CREATE | EDIT | DELETE | QUERY | BATCH
+	}
+	if allowMethods&action == 0 {
+		return &ActionError{Code: http.StatusMethodNotAllowed, Err: fmt.Errorf("%s is not allowed", ActionName(action))}
+	}
+	return nil
+}
+
+// Get return the object of key as the get route, for the other APIs such as GraphQL.
+// The Authorizer, GetDB, Scope, tenant, Cache, BeforeRender and FieldPolicies are applied
+// with c, the unreadable fields are removed. The errors are ActionError mostly.
+func (obj *WebObject) Get(c *gin.Context, key string) (any, error) {
+	if err := obj.checkAction(GET); err != nil {
+		return nil, err
+	}
+	val, err := obj.getObject(c, key)
+	if err != nil {
+		return nil, err
+	}
+	return obj.stripUnreadable(c, val)
+}
+
+// Query return the objects of form as the query route, the filters, orders and
+// search fields which are not allowed are ignored, the unreadable and unselected
+// fields are removed from items.
+func (obj *WebObject) Query(c *gin.Context, form QueryForm) (QueryResult[any], error) {
+	var r QueryResult[any]
+	if err := obj.checkAction(QUERY); err != nil {
+		return r, err
+	}
+	if err := obj.authorizeAction(c, QUERY, nil); err != nil {
+		return r, err
+	}
+	db, err := obj.getDB(c, QUERY)
+	if err != nil {
+		return r, &ActionError{Code: http.StatusForbidden, Err: err}
+	}
+
+	form.fixPosLimit()
+	hiddenFields, err := obj.prepareForm(c, &form)
+	if err != nil {
+		return r, err
+	}
+
+	db, cancel := obj.QueryLimits.withTimeout(c, db)
+	defer cancel()
+
+	if r, err = obj.queryObjects(c, db, nil, &form); err != nil {
+		return r, err
+	}
+	r.Items, err = obj.stripFields(r.Items, hiddenFields)
+	return r, err
+}
+
+// Create create the object of vals as the create route, vals is the JSON object decoded
+// by encoding/json, such as the numbers are float64. Return the created object without
+// the unreadable fields.
+func (obj *WebObject) Create(c *gin.Context, vals map[string]any) (any, error) {
+	if err := obj.checkAction(CREATE); err != nil {
+		return nil, err
+	}
+	val, err := obj.createObject(c, vals)
+	if err != nil {
+		return nil, err
+	}
+	return obj.stripUnreadable(c, val)
+}
+
+// Update update the EditFields of vals as the edit route, vals is the JSON object decoded
+// by encoding/json. The object with VersionField requires the version value in vals.
+func (obj *WebObject) Update(c *gin.Context, key string, vals map[string]any) error {
+	if err := obj.checkAction(EDIT); err != nil {
+		return err
+	}
+	_, err := obj.updateObject(c, key, vals, "")
+	return err
+}
+
+// Delete delete the object of key as the delete route. The object with VersionField
+// requires the version value in vals, such as {"version": 3}, vals is nil otherwise.
+func (obj *WebObject) Delete(c *gin.Context, key string, vals map[string]any) error {
+	if err := obj.checkAction(DELETE); err != nil {
+		return err
+	}
+	return obj.deleteObject(c, key, vals, "")
+}
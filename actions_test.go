@@ -0,0 +1,101 @@
+package gormpher
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestObjectActions(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tnote{})
+	db.Create(&tnote{ID: 1, OwnerID: 1, Title: "alice-1"})
+	db.Create(&tnote{ID: 2, OwnerID: 2, Title: "bob-1"})
+
+	obj := WebObject{
+		Name:         "note",
+		Model:        tnote{},
+		EditFields:   []string{"Title"},
+		FilterFields: []string{"Title"},
+		AllowMethods: GET | CREATE | EDIT | DELETE | QUERY,
+		GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+		Authorizer: AuthorizerFunc(func(ctx *gin.Context, obj *WebObject, action int, vptr any) error {
+			if note, ok := vptr.(*tnote); ok && note.OwnerID != ctx.GetUint("userId") {
+				return errors.New("not owner")
+			}
+			return nil
+		}),
+		FieldPolicies: []FieldPolicy{
+			{Field: "OwnerID", CanRead: func(ctx *gin.Context) bool { return false }},
+		},
+	}
+
+	// the object is not built
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	c.Set("userId", uint(1))
+	_, err := obj.Get(c, "1")
+	assert.ErrorContains(t, err, "note is not built")
+	assert.Nil(t, obj.Build())
+
+	// get
+	val, err := obj.Get(c, "1")
+	assert.Nil(t, err)
+	data, _ := json.Marshal(val)
+	assert.JSONEq(t, `{"id":1,"title":"alice-1"}`, string(data))
+
+	var e *ActionError
+	_, err = obj.Get(c, "2")
+	assert.ErrorAs(t, err, &e)
+	assert.Equal(t, http.StatusForbidden, e.Code)
+	assert.Equal(t, "not owner", err.Error())
+
+	_, err = obj.Get(c, "100")
+	assert.ErrorAs(t, err, &e)
+	assert.Equal(t, http.StatusNotFound, e.Code)
+
+	// query
+	r, err := obj.Query(c, QueryForm{Filters: []Filter{{Name: "title", Op: "=", Value: "bob-1"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, r.Total)
+	assert.Equal(t, DefaultQueryLimit, r.Limit)
+	data, _ = json.Marshal(r.Items)
+	assert.JSONEq(t, `[{"id":2,"title":"bob-1"}]`, string(data))
+
+	// create, update and delete
+	_, err = obj.Create(c, map[string]any{"title": "alice-2", "ownerId": float64(2)})
+	assert.ErrorAs(t, err, &e)
+	assert.Equal(t, http.StatusForbidden, e.Code)
+
+	val, err = obj.Create(c, map[string]any{"title": "alice-2", "ownerId": float64(1)})
+	assert.Nil(t, err)
+	data, _ = json.Marshal(val)
+	assert.JSONEq(t, `{"id":3,"title":"alice-2"}`, string(data))
+
+	assert.Nil(t, obj.Update(c, "3", map[string]any{"title": "alice-3"}))
+	err = obj.Update(c, "3", map[string]any{"ownerId": float64(2)})
+	assert.ErrorAs(t, err, &e)
+	assert.Equal(t, http.StatusBadRequest, e.Code)
+
+	err = obj.Delete(c, "2", nil)
+	assert.ErrorAs(t, err, &e)
+	assert.Equal(t, http.StatusForbidden, e.Code)
+	assert.Nil(t, obj.Delete(c, "3", nil))
+
+	var count int64
+	db.Model(&tnote{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	// query is not allowed
+	obj.AllowMethods = GET
+	_, err = obj.Query(c, QueryForm{})
+	assert.ErrorAs(t, err, &e)
+	assert.Equal(t, http.StatusMethodNotAllowed, e.Code)
+}
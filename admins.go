@@ -1,13 +1,15 @@
 package gormpher
 
 import (
+	"bytes"
 	"embed"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"path"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -48,22 +50,63 @@ type AdminManager struct {
 	Names        []string
 }
 
-// RegisterObjectsWithAdmin quickly Register Admin by webobjects
-func RegisterObjectsWithAdmin(r *gin.RouterGroup, objs []WebObject) {
+// RegisterObjectsWithAdmin quickly Register Admin by webobjects, all the objs are validated
+// before registering as RegisterObjects, and the routes must not conflict with admin routes.
+func RegisterObjectsWithAdmin(r *gin.RouterGroup, objs []WebObject) error {
+	routes, err := buildRoutes(objs)
+	errs := []error{err}
+	for _, route := range routes {
+		if route.method != http.MethodGet {
+			continue
+		}
+		for _, adminRoute := range adminRoutes {
+			if routesConflict(route.path, adminRoute) {
+				errs = append(errs, fmt.Errorf("RegisterObjectWithAdmin route GET %s conflicts with admin route GET %s", route.path, adminRoute))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
 	m := AdminManager{}
+	for _, route := range routes {
+		r.Handle(route.method, route.path, route.handler)
+	}
 	for _, obj := range objs {
-		m.RegisterObject(r, obj)
+		m.Names = append(m.Names, obj.Name)
+		m.AdminObjects = append(m.AdminObjects, woToAo(obj))
 	}
 	RegisterAdminHandler(r, &m)
+	return nil
 }
 
-func (m *AdminManager) RegisterObject(r *gin.RouterGroup, obj WebObject) {
+// adminRoutes is the GET routes of RegisterAdminHandler.
+var adminRoutes = []string{"object_names", "object/:name", "client.ts", "assets/*filepath", "resources/*filepath", "v1", "v2"}
+
+// routesConflict check the routes conflict in gin, such as the same routes, the different
+// wildcards at the same segment, or the catch-all with the other segment.
+func routesConflict(a, b string) bool {
+	as := strings.Split(strings.Trim(a, "/"), "/")
+	bs := strings.Split(strings.Trim(b, "/"), "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		aw, bw := strings.IndexAny(as[i], ":*") == 0, strings.IndexAny(bs[i], ":*") == 0
+		return (aw && bw) || strings.HasPrefix(as[i], "*") || strings.HasPrefix(bs[i], "*")
+	}
+	return len(as) == len(bs)
+}
+
+func (m *AdminManager) RegisterObject(r *gin.RouterGroup, obj WebObject) error {
 	if err := obj.RegisterObject((gin.IRouter)(r)); err != nil {
-		log.Fatalf("RegisterObjectWithAdmin [%s] fail %v\n", obj.Name, err)
+		return fmt.Errorf("RegisterObjectWithAdmin [%s] fail %w", obj.Name, err)
 	}
 
 	m.Names = append(m.Names, obj.Name)
 	m.AdminObjects = append(m.AdminObjects, woToAo(obj))
+	return nil
 }
 
 // convert WebObject to AdminObject
@@ -120,6 +163,7 @@ func woToAo(wo WebObject) AdminObject {
 func RegisterAdminHandler(r *gin.RouterGroup, m *AdminManager) {
 	r.GET("object_names", m.handleObjectNames)
 	r.GET("object/:name", m.handleObjectFields)
+	r.GET("client.ts", m.handleTypeScript)
 
 	// old admin assets
 	r.GET("/assets/*filepath", func(ctx *gin.Context) {
@@ -177,7 +221,10 @@ func (m *AdminManager) handleObjectNames(c *gin.Context) {
 	"edits": ["name"],
 
 	"primaryKey": "id",
+	"readonly": ["age"],
 }
+
+The fields hidden by FieldPolicies are excluded, and the locked fields are listed in readonly.
 */
 func (m *AdminManager) handleObjectFields(c *gin.Context) {
 	name := c.Param("name")
@@ -194,6 +241,10 @@ func (m *AdminManager) handleObjectFields(c *gin.Context) {
 
 	for _, obj := range m.AdminObjects {
 		if obj.webObject.Name == name {
+			// hide the fields by FieldPolicies of current request
+			unreadables := obj.webObject.unreadableFields(c)
+			unwritables := obj.webObject.unwritableFields(c)
+
 			rt := obj.webObject.modelElem
 			for i := 0; i < rt.NumField(); i++ {
 				f := rt.Field(i)
@@ -202,6 +253,9 @@ func (m *AdminManager) handleObjectFields(c *gin.Context) {
 				if jsonTag == "-" {
 					continue
 				}
+				if _, ok := unreadables[f.Name]; ok {
+					continue
+				}
 
 				jsonName := obj.fieldToJSONs[f.Name]
 				typeVal := convertGoTypeToJS(f.Type.Kind())
@@ -211,10 +265,26 @@ func (m *AdminManager) handleObjectFields(c *gin.Context) {
 				goTypes = append(goTypes, f.Type.String())
 			}
 
-			result["searchs"] = obj.Searchs
-			result["filters"] = obj.Filters
-			result["orders"] = obj.Orders
-			result["edits"] = obj.Edits
+			readonly := make([]string, 0)
+			for f := range unwritables {
+				readonly = append(readonly, obj.fieldToJSONs[f])
+			}
+			sort.Strings(readonly)
+
+			hidden := make(map[string]struct{})
+			for f := range unreadables {
+				hidden[obj.fieldToJSONs[f]] = struct{}{}
+			}
+			locked := make(map[string]struct{})
+			for f := range unwritables {
+				locked[obj.fieldToJSONs[f]] = struct{}{}
+			}
+
+			result["searchs"] = excludeNames(obj.Searchs, hidden)
+			result["filters"] = excludeNames(obj.Filters, hidden)
+			result["orders"] = excludeNames(obj.Orders, hidden)
+			result["edits"] = excludeNames(excludeNames(obj.Edits, hidden), locked)
+			result["readonly"] = readonly
 			result["primaryKey"] = obj.webObject.jsonPKName
 			break
 		}
@@ -227,6 +297,31 @@ func (m *AdminManager) handleObjectFields(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// handleTypeScript serve the generated TypeScript client of objects.
+func (m *AdminManager) handleTypeScript(c *gin.Context) {
+	objs := make([]WebObject, 0, len(m.AdminObjects))
+	for _, ao := range m.AdminObjects {
+		objs = append(objs, *ao.webObject)
+	}
+	var buf bytes.Buffer
+	if err := GenerateTypeScript(&buf, objs); err != nil {
+		handleError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/typescript; charset=utf-8", buf.Bytes())
+}
+
+// excludeNames return the names not in excludes.
+func excludeNames(names []string, excludes map[string]struct{}) []string {
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		if _, ok := excludes[name]; !ok {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
 // Support javascript type: string, number, boolean, object, any
 func convertGoTypeToJS(kind reflect.Kind) string {
 	switch kind {
@@ -0,0 +1,151 @@
+package gormpher
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AuditLog is a record of write on WebObject, need to be migrated by user:
+//
+//	db.AutoMigrate(&gormpher.AuditLog{})
+type AuditLog struct {
+	ID        uint            `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Object    string          `json:"object" gorm:"size:64;index"`
+	Action    string          `json:"action" gorm:"size:16"`
+	ObjectKey string          `json:"key" gorm:"size:128;index"`
+	Actor     string          `json:"actor" gorm:"size:128;index"`
+	Changes   json.RawMessage `json:"changes"` // map[string]AuditChange
+}
+
+// AuditChange is the change of a field, Old is nil for create and New is nil for delete.
+type AuditChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// AuditRecorder record the AuditLog of WebObject writes.
+// tx is the transaction of the write, the write is rolled back when error is returned.
+type AuditRecorder interface {
+	Record(ctx *gin.Context, tx *gorm.DB, log *AuditLog) error
+}
+
+// AuditRecorderFunc is an adapter to allow the use of ordinary functions as AuditRecorder.
+type AuditRecorderFunc func(ctx *gin.Context, tx *gorm.DB, log *AuditLog) error
+
+func (f AuditRecorderFunc) Record(ctx *gin.Context, tx *gorm.DB, log *AuditLog) error {
+	return f(ctx, tx, log)
+}
+
+// DBAuditRecorder persist AuditLog in the same transaction of the write.
+type DBAuditRecorder struct{}
+
+func (DBAuditRecorder) Record(ctx *gin.Context, tx *gorm.DB, log *AuditLog) error {
+	return tx.Session(&gorm.Session{NewDB: true}).Create(log).Error
+}
+
+// AuditObject return a read-only WebObject to query the audit trail, such as:
+//
+//	audit := AuditObject(db)
+//	RegisterObject(r, &audit)
+//	POST /audit_log {"filters": [{"name": "object", "op": "=", "value": "product"}]}
+func AuditObject(db *gorm.DB) WebObject {
+	return WebObject{
+		Name:         "audit_log",
+		Model:        AuditLog{},
+		AllowMethods: GET | QUERY,
+		FilterFields: []string{"CreatedAt", "Object", "Action", "ObjectKey", "Actor"},
+		OrderFields:  []string{"ID", "CreatedAt"},
+		SearchFields: []string{"ObjectKey", "Actor"},
+		GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+	}
+}
+
+// getKey return the primary key value of vptr as string.
+func (obj *WebObject) getKey(vptr any) string {
+	rv := reflect.ValueOf(vptr)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	fv := rv.FieldByName(obj.jsonToFields[obj.jsonPKName])
+	if !fv.IsValid() {
+		return ""
+	}
+	return fmt.Sprintf("%v", fv.Interface())
+}
+
+// recordAudit record the write of action with Audit, oldVal is nil for create and newVal is nil for delete.
+func (obj *WebObject) recordAudit(c *gin.Context, tx *gorm.DB, action int, oldVal, newVal any) error {
+	if obj.Audit == nil {
+		return nil
+	}
+
+	changes, err := diffObjects(oldVal, newVal)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	var key string
+	if newVal != nil {
+		key = obj.getKey(newVal)
+	} else {
+		key = obj.getKey(oldVal)
+	}
+
+	return obj.Audit.Record(c, tx, &AuditLog{
+		Object:    obj.Name,
+		Action:    ActionName(action),
+		ObjectKey: key,
+		Actor:     obj.getActor(c),
+		Changes:   data,
+	})
+}
+
+// diffObjects return the changed fields (json format key) between oldVal and newVal.
+func diffObjects(oldVal, newVal any) (map[string]AuditChange, error) {
+	oldVals, err := toJSONMap(oldVal)
+	if err != nil {
+		return nil, err
+	}
+	newVals, err := toJSONMap(newVal)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]AuditChange)
+	for k, v := range oldVals {
+		if nv, ok := newVals[k]; !ok || !reflect.DeepEqual(v, nv) {
+			changes[k] = AuditChange{Old: v, New: newVals[k]}
+		}
+	}
+	for k, v := range newVals {
+		if _, ok := oldVals[k]; !ok {
+			changes[k] = AuditChange{New: v}
+		}
+	}
+	return changes, nil
+}
+
+func toJSONMap(v any) (map[string]any, error) {
+	vals := make(map[string]any)
+	if v == nil {
+		return vals, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &vals); err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
@@ -0,0 +1,104 @@
+package gormpher
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func initAuditTest(t *testing.T, recorder AuditRecorder) (*TestClient, *gorm.DB) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{}, AuditLog{})
+
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 9})
+	db.Create(&tuser{ID: 2, Name: "bob", Age: 10})
+	db.Create(&tuser{ID: 3, Name: "clash", Age: 11})
+
+	r := gin.Default()
+	audit := AuditObject(db)
+	RegisterObjects(r, []WebObject{
+		{
+			Name:       "user",
+			Model:      tuser{},
+			EditFields: []string{"Name", "Age"},
+			GetDB:      func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			Audit:      recorder,
+			GetActor: func(ctx *gin.Context) string {
+				return ctx.GetHeader("X-User")
+			},
+		},
+		audit,
+	})
+	return NewTestClient(r), db
+}
+
+func TestAuditLog(t *testing.T) {
+	c, db := initAuditTest(t, DBAuditRecorder{})
+
+	err := c.CallPut("/user", map[string]any{"name": "dave", "age": 12}, nil)
+	assert.Nil(t, err)
+	err = c.CallPatch("/user/1", map[string]any{"age": 20}, nil)
+	assert.Nil(t, err)
+	err = c.CallDelete("/user/2", nil, nil)
+	assert.Nil(t, err)
+	err = c.CallDelete("/user", []string{"3", "4"}, nil)
+	assert.Nil(t, err)
+
+	var logs []AuditLog
+	db.Order("id").Find(&logs)
+	assert.Len(t, logs, 5)
+
+	assert.Equal(t, "user", logs[0].Object)
+	assert.Equal(t, "create", logs[0].Action)
+	assert.Equal(t, "4", logs[0].ObjectKey)
+
+	var changes map[string]AuditChange
+	json.Unmarshal(logs[1].Changes, &changes)
+	assert.Equal(t, "edit", logs[1].Action)
+	assert.Equal(t, "1", logs[1].ObjectKey)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, float64(9), changes["age"].Old)
+	assert.Equal(t, float64(20), changes["age"].New)
+
+	json.Unmarshal(logs[2].Changes, &changes)
+	assert.Equal(t, "delete", logs[2].Action)
+	assert.Equal(t, "bob", changes["name"].Old)
+	assert.Nil(t, changes["name"].New)
+
+	assert.Equal(t, "batch", logs[3].Action)
+	assert.Equal(t, "batch", logs[4].Action)
+
+	// Query the audit trail
+	var res QueryResult[[]AuditLog]
+	err = c.CallPost("/audit_log", QueryForm{
+		Filters: []Filter{{Name: "action", Op: "=", Value: "batch"}},
+	}, &res)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, res.Total)
+	assert.ElementsMatch(t, []string{"3", "4"}, []string{res.Items[0].ObjectKey, res.Items[1].ObjectKey})
+}
+
+func TestAuditLogRollback(t *testing.T) {
+	c, db := initAuditTest(t, AuditRecorderFunc(func(ctx *gin.Context, tx *gorm.DB, log *AuditLog) error {
+		if log.Actor == "" {
+			return errors.New("actor is required")
+		}
+		return DBAuditRecorder{}.Record(ctx, tx, log)
+	}))
+
+	err := c.CallPatch("/user/1", map[string]any{"age": 20}, nil)
+	assert.NotNil(t, err)
+
+	var user tuser
+	db.First(&user, 1)
+	assert.Equal(t, 9, user.Age)
+
+	var count int64
+	db.Model(&AuditLog{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
@@ -0,0 +1,82 @@
+package gormpher
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Authorizer decide whether the request can perform the action on WebObject.
+// The action is one of GET, CREATE, EDIT, DELETE, QUERY, BATCH, VIEW and IMPORT, vptr is:
+// - the loaded object for GET, EDIT and DELETE
+// - the decoded object for CREATE
+// - nil for QUERY, BATCH, VIEW and IMPORT, use ctx.FullPath() to tell views apart
+//
+// Every row of import is authorized again as CREATE or EDIT.
+//
+// Return error to reject the request with 403.
+type Authorizer interface {
+	Authorize(ctx *gin.Context, obj *WebObject, action int, vptr any) error
+}
+
+// AuthorizerFunc is an adapter to allow the use of ordinary functions as Authorizer.
+type AuthorizerFunc func(ctx *gin.Context, obj *WebObject, action int, vptr any) error
+
+func (f AuthorizerFunc) Authorize(ctx *gin.Context, obj *WebObject, action int, vptr any) error {
+	return f(ctx, obj, action, vptr)
+}
+
+// ScopeFunc constrain the rows visible to the request, such as:
+//
+//	func(ctx *gin.Context, db *gorm.DB, action int) *gorm.DB {
+//		return db.Where("owner_id", ctx.GetUint("userId"))
+//	}
+//
+// It's applied to every action except CREATE, after the tenant constraint.
+type ScopeFunc func(ctx *gin.Context, db *gorm.DB, action int) *gorm.DB
+
+// getDB return the db of action, constrained by tenant and Scope.
+// Return error when the tenant of request can't be resolved.
+func (obj *WebObject) getDB(c *gin.Context, action int) (*gorm.DB, error) {
+	db := obj.openDB(c, action)
+	if action == CREATE {
+		return db, nil
+	}
+
+	if obj.TenantField != "" {
+		tenant, err := obj.getTenant(c)
+		if err != nil {
+			return nil, err
+		}
+		db = obj.withTenant(db, tenant)
+	}
+	if obj.Scope != nil {
+		db = obj.Scope(c, db, action)
+	}
+	if obj.TenantField != "" || obj.Scope != nil {
+		// new session, so the constrained db can be reused by multiple statements
+		db = db.Session(&gorm.Session{})
+	}
+	return db, nil
+}
+
+// authorize check the action with Authorizer, abort with 403 when rejected.
+func (obj *WebObject) authorize(c *gin.Context, action int, vptr any) bool {
+	if err := obj.authorizeAction(c, action, vptr); err != nil {
+		handleActionError(c, err)
+		return false
+	}
+	return true
+}
+
+// authorizeAction check the action with Authorizer, return 403 ActionError when rejected.
+func (obj *WebObject) authorizeAction(c *gin.Context, action int, vptr any) error {
+	if obj.Authorizer == nil {
+		return nil
+	}
+	if err := obj.Authorizer.Authorize(c, obj, action, vptr); err != nil {
+		return &ActionError{Code: http.StatusForbidden, Err: err}
+	}
+	return nil
+}
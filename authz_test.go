@@ -0,0 +1,148 @@
+package gormpher
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type tnote struct {
+	ID      uint   `json:"id" gorm:"primarykey"`
+	OwnerID uint   `json:"ownerId"`
+	Title   string `json:"title"`
+}
+
+func initAuthzTest(t *testing.T, obj WebObject) (*TestClient, *gorm.DB) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tnote{})
+
+	db.Create(&tnote{ID: 1, OwnerID: 1, Title: "alice-1"})
+	db.Create(&tnote{ID: 2, OwnerID: 1, Title: "alice-2"})
+	db.Create(&tnote{ID: 3, OwnerID: 2, Title: "bob-1"})
+
+	r := gin.Default()
+	// mock authentication, current user from header
+	r.Use(func(ctx *gin.Context) {
+		if ctx.GetHeader("X-User") == "bob" {
+			ctx.Set("userId", uint(2))
+		} else {
+			ctx.Set("userId", uint(1))
+		}
+	})
+
+	obj.Name = "note"
+	obj.Model = tnote{}
+	obj.EditFields = []string{"Title"}
+	obj.GetDB = func(c *gin.Context, isCreate bool) *gorm.DB { return db }
+	err := RegisterObject(r, &obj)
+	assert.Nil(t, err)
+
+	return NewTestClient(r), db
+}
+
+func TestAuthorizer(t *testing.T) {
+	var actions []int
+	c, db := initAuthzTest(t, WebObject{
+		Authorizer: AuthorizerFunc(func(ctx *gin.Context, obj *WebObject, action int, vptr any) error {
+			actions = append(actions, action)
+			switch action {
+			case BATCH:
+				return errors.New("batch delete is not allowed")
+			case GET, EDIT, DELETE, CREATE:
+				if vptr.(*tnote).OwnerID != ctx.GetUint("userId") {
+					return errors.New("not owner")
+				}
+			}
+			return nil
+		}),
+	})
+
+	var note tnote
+	err := c.CallGet("/note/1", nil, &note)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice-1", note.Title)
+
+	err = c.CallGet("/note/3", nil, &note)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "not owner")
+
+	err = c.CallPatch("/note/3", map[string]any{"title": "hacked"}, nil)
+	assert.NotNil(t, err)
+
+	err = c.CallDelete("/note/3", nil, nil)
+	assert.NotNil(t, err)
+
+	err = c.CallPut("/note", map[string]any{"ownerId": 2, "title": "fake"}, nil)
+	assert.NotNil(t, err)
+
+	err = c.CallPut("/note", map[string]any{"ownerId": 1, "title": "new"}, nil)
+	assert.Nil(t, err)
+
+	err = c.CallDelete("/note", []string{"3"}, nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "batch delete is not allowed")
+
+	var count int64
+	db.Model(&tnote{}).Count(&count)
+	assert.Equal(t, int64(4), count)
+
+	var res QueryResult[[]tnote]
+	err = c.CallPost("/note", nil, &res)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, res.Total)
+
+	assert.Contains(t, actions, QUERY)
+	assert.Contains(t, actions, BATCH)
+}
+
+func TestScope(t *testing.T) {
+	c, db := initAuthzTest(t, WebObject{
+		Scope: func(ctx *gin.Context, db *gorm.DB, action int) *gorm.DB {
+			return db.Where("owner_id", ctx.GetUint("userId"))
+		},
+	})
+
+	// Query
+	{
+		var res QueryResult[[]tnote]
+		err := c.CallPost("/note", nil, &res)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, res.Total)
+	}
+	// Get
+	{
+		err := c.CallGet("/note/1", nil, nil)
+		assert.Nil(t, err)
+		err = c.CallGet("/note/3", nil, nil)
+		assert.NotNil(t, err)
+	}
+	// Update
+	{
+		err := c.CallPatch("/note/3", map[string]any{"title": "hacked"}, nil)
+		assert.NotNil(t, err)
+		err = c.CallPatch("/note/2", map[string]any{"title": "changed"}, nil)
+		assert.Nil(t, err)
+
+		var note tnote
+		db.First(&note, 3)
+		assert.Equal(t, "bob-1", note.Title)
+	}
+	// Delete
+	{
+		err := c.CallDelete("/note/3", nil, nil)
+		assert.NotNil(t, err)
+	}
+	// Batch delete, only delete own notes
+	{
+		err := c.CallDelete("/note", []string{"1", "3"}, nil)
+		assert.Nil(t, err)
+
+		var count int64
+		db.Model(&tnote{}).Count(&count)
+		assert.Equal(t, int64(2), count)
+	}
+}
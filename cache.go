@@ -0,0 +1,263 @@
+package gormpher
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cache is the read-through cache of get and query, such as LRUCache, or the
+// cache shared by instances. The values are the loaded models, before the
+// authorization, BeforeRender and rendering of request.
+type Cache interface {
+	Get(key string) (any, bool)
+	Set(key string, val any, ttl time.Duration)
+	// DeletePrefix delete the values of keys with prefix, for the invalidation of object.
+	DeletePrefix(prefix string)
+}
+
+// DefaultCacheTTL is the default CacheTTL of WebObject.
+const DefaultCacheTTL = time.Minute
+
+// checkCacheKey check CacheKey is set, the rows may depend on the request by GetDB,
+// Scope, Authorizer or the Prepare of views, which can't be checked, otherwise the
+// rows of one user are served to the others.
+func (obj *WebObject) checkCacheKey() error {
+	if obj.Cache != nil && obj.CacheKey == nil {
+		return fmt.Errorf("%s Cache requires CacheKey", obj.Name)
+	}
+	return nil
+}
+
+// cachePrefix return the prefix of cache keys of obj, such as "v1/user|".
+func (obj *WebObject) cachePrefix() string {
+	return filepath.Join(obj.Group, obj.Name) + "|"
+}
+
+// cacheKey return the cache key of request, with the CacheKey variation and tenant.
+func (obj *WebObject) cacheKey(c *gin.Context, action string, key string) (string, bool) {
+	if obj.Cache == nil {
+		return "", false
+	}
+	var vary string
+	if obj.CacheKey != nil {
+		vary = obj.CacheKey(c)
+	}
+	var tenant any
+	if obj.TenantField != "" {
+		var err error
+		if tenant, err = obj.getTenant(c); err != nil {
+			return "", false
+		}
+	}
+	return fmt.Sprintf("%s%s|%s|%v|%s", obj.cachePrefix(), action, vary, tenant, key), true
+}
+
+// queryCacheKey return the normalized form of query, the filters are sorted.
+func queryCacheKey(view *QueryView, form *QueryForm) (string, error) {
+	filters := make([]string, 0, len(form.Filters))
+	for _, f := range form.Filters {
+		v, err := json.Marshal(f.Value)
+		if err != nil {
+			return "", err
+		}
+		filters = append(filters, fmt.Sprintf("%s %s %s", f.Name, strings.ToLower(f.Op), v))
+	}
+	sort.Strings(filters)
+	orders := make([]string, 0, len(form.Orders))
+	for _, o := range form.Orders {
+		orders = append(orders, o.Name+" "+strings.ToLower(o.Op))
+	}
+	var viewName string
+	if view != nil {
+		viewName = view.Name
+	}
+
+	data, err := json.Marshal([]any{viewName, filters, orders, form.Keyword, form.searchFields,
+		form.Pagination, form.Pos, form.Limit, form.ViewFields})
+	return string(data), err
+}
+
+// getCache copy the cached value of key to vptr, the value is deep copied,
+// so it can be modified by BeforeRender.
+func (obj *WebObject) getCache(key string, vptr any) bool {
+	v, ok := obj.Cache.Get(key)
+	if !ok {
+		return false
+	}
+	reflect.ValueOf(vptr).Elem().Set(deepCopy(reflect.ValueOf(v).Elem(), nil))
+	return true
+}
+
+// setCache set the deep copy of value of vptr.
+func (obj *WebObject) setCache(key string, vptr any) {
+	rv := reflect.ValueOf(vptr).Elem()
+	cp := reflect.New(rv.Type())
+	cp.Elem().Set(deepCopy(rv, nil))
+	ttl := obj.CacheTTL
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	obj.Cache.Set(key, cp.Interface(), ttl)
+}
+
+// deepCopy return the copy of v, the pointers, slices, maps and interfaces are copied,
+// the unexported fields are copied shallowly. The copied pointers are kept in copied,
+// for the cycles of pointers.
+func deepCopy(v reflect.Value, copied map[uintptr]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		if copied == nil {
+			copied = make(map[uintptr]reflect.Value)
+		}
+		if cp, ok := copied[v.Pointer()]; ok {
+			return cp
+		}
+		cp := reflect.New(v.Type().Elem())
+		copied[v.Pointer()] = cp
+		cp.Elem().Set(deepCopy(v.Elem(), copied))
+		return cp
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(deepCopy(v.Elem(), copied))
+		return cp
+	case reflect.Struct:
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if f := cp.Field(i); f.CanSet() {
+				f.Set(deepCopy(v.Field(i), copied))
+			}
+		}
+		return cp
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(deepCopy(v.Index(i), copied))
+		}
+		return cp
+	case reflect.Array:
+		cp := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(deepCopy(v.Index(i), copied))
+		}
+		return cp
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			cp.SetMapIndex(iter.Key(), deepCopy(iter.Value(), copied))
+		}
+		return cp
+	}
+	return v
+}
+
+// invalidateCache delete the cached values of obj after writes.
+func (obj *WebObject) invalidateCache() {
+	if obj.Cache != nil {
+		obj.Cache.DeletePrefix(obj.cachePrefix())
+	}
+}
+
+// LRUCache is the Cache in memory, the least recently used values are evicted over size.
+type LRUCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+	now   func() time.Time
+}
+
+type lruEntry struct {
+	key      string
+	val      any
+	expireAt time.Time // zero is never expired
+}
+
+// NewLRUCache return the LRUCache of max size.
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		now:   time.Now,
+	}
+}
+
+func (l *LRUCache) Get(key string) (any, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*lruEntry)
+	if !entry.expireAt.IsZero() && !l.now().Before(entry.expireAt) {
+		l.remove(e)
+		return nil, false
+	}
+	l.ll.MoveToFront(e)
+	return entry.val, true
+}
+
+// Set set the value of key, the zero ttl is never expired.
+func (l *LRUCache) Set(key string, val any, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := &lruEntry{key: key, val: val}
+	if ttl > 0 {
+		entry.expireAt = l.now().Add(ttl)
+	}
+	if e, ok := l.items[key]; ok {
+		e.Value = entry
+		l.ll.MoveToFront(e)
+		return
+	}
+	l.items[key] = l.ll.PushFront(entry)
+	for l.size > 0 && l.ll.Len() > l.size {
+		l.remove(l.ll.Back())
+	}
+}
+
+func (l *LRUCache) DeletePrefix(prefix string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, e := range l.items {
+		if strings.HasPrefix(key, prefix) {
+			l.remove(e)
+		}
+	}
+}
+
+// Len return the number of values, including the expired ones.
+func (l *LRUCache) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ll.Len()
+}
+
+func (l *LRUCache) remove(e *list.Element) {
+	l.ll.Remove(e)
+	delete(l.items, e.Value.(*lruEntry).key)
+}
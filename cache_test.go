@@ -0,0 +1,194 @@
+package gormpher
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestLRUCache(t *testing.T) {
+	now := time.Now()
+	cache := NewLRUCache(2)
+	cache.now = func() time.Time { return now }
+
+	cache.Set("user|a", 1, 0)
+	cache.Set("user|b", 2, time.Second)
+	v, ok := cache.Get("user|a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	// b is the least recently used
+	cache.Set("user|c", 3, 0)
+	_, ok = cache.Get("user|b")
+	assert.False(t, ok)
+	assert.Equal(t, 2, cache.Len())
+
+	cache.Set("user|c", 4, time.Second)
+	now = now.Add(time.Second)
+	_, ok = cache.Get("user|c")
+	assert.False(t, ok)
+
+	cache.Set("user2|a", 5, 0)
+	cache.DeletePrefix("user|")
+	_, ok = cache.Get("user|a")
+	assert.False(t, ok)
+	v, ok = cache.Get("user2|a")
+	assert.True(t, ok)
+	assert.Equal(t, 5, v)
+}
+
+func TestCache(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{})
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 9})
+	db.Create(&tuser{ID: 2, Name: "bob", Age: 10})
+
+	cache := NewLRUCache(100)
+	r := gin.New()
+	err := RegisterObjects(r, []WebObject{
+		{
+			Name:         "user",
+			Model:        tuser{},
+			EditFields:   []string{"Name"},
+			FilterFields: []string{"Age"},
+			GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			Cache:        cache,
+			CacheTTL:     time.Minute,
+			CacheKey: func(c *gin.Context) string {
+				return c.GetHeader("X-User")
+			},
+			BeforeRender: func(ctx *gin.Context, vptr any) error {
+				// the cached values are not modified
+				vptr.(*tuser).Name += "!"
+				return nil
+			},
+		},
+	})
+	assert.Nil(t, err)
+	client := NewTestClient(r)
+
+	get := func(path string) tuser {
+		var user tuser
+		err := client.CallGet(path, nil, &user)
+		assert.Nil(t, err)
+		return user
+	}
+	query := func(body string) QueryResult[[]tuser] {
+		var result QueryResult[[]tuser]
+		err := client.CallPost("/user", json.RawMessage(body), &result)
+		assert.Nil(t, err)
+		return result
+	}
+
+	assert.Equal(t, "alice!", get("/user/1").Name)
+	assert.Equal(t, 2, query(`{"filters":[{"name":"age","op":">=","value":9}]}`).Total)
+	assert.Equal(t, 2, cache.Len())
+
+	// the writes out of gormpher are not visible until invalidated
+	db.Model(&tuser{}).Where("id", 1).Update("name", "alice2")
+	db.Create(&tuser{ID: 3, Name: "clash", Age: 11})
+	assert.Equal(t, "alice!", get("/user/1").Name)
+	assert.Equal(t, 2, query(`{"filters":[{"name":"age","op":">=","value":9}]}`).Total)
+	assert.Equal(t, 3, query(`{"filters":[{"name":"age","op":">","value":8}]}`).Total)
+	assert.Equal(t, 3, cache.Len())
+
+	var ok bool
+	err = client.CallPatch("/user/2", map[string]any{"name": "bob2"}, &ok)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, cache.Len())
+	assert.Equal(t, "alice2!", get("/user/1").Name)
+	assert.Equal(t, 3, query(`{"filters":[{"name":"age","op":">=","value":9}]}`).Total)
+
+	err = client.CallDelete("/user/3", nil, &ok)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, query(`{"filters":[{"name":"age","op":">=","value":9}]}`).Total)
+
+	err = client.CallPut("/user", tuser{ID: 4, Name: "dave", Age: 12}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, query(`{"filters":[{"name":"age","op":">=","value":9}]}`).Total)
+
+	err = client.CallDelete("/user", []string{"4"}, &ok)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, query(`{"filters":[{"name":"age","op":">=","value":9}]}`).Total)
+
+	// the variation of cache key
+	assert.Equal(t, "alice2!", get("/user/1").Name)
+	db.Model(&tuser{}).Where("id", 1).Update("name", "alice3")
+	assert.Equal(t, "alice2!", get("/user/1").Name)
+	req, _ := http.NewRequest(http.MethodGet, "/user/1", nil)
+	req.Header.Set("X-User", "bob")
+	w := client.SendReq("/user/1", req)
+	assert.Contains(t, w.Body.String(), "alice3!")
+}
+
+func TestCacheRequiresCacheKey(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	obj := WebObject{
+		Name:  "user",
+		Model: tuser{},
+		GetDB: func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+		Cache: NewLRUCache(10),
+	}
+	assert.ErrorContains(t, obj.Build(), "user Cache requires CacheKey")
+
+	obj.CacheKey = func(c *gin.Context) string { return "" }
+	assert.Nil(t, obj.Build())
+}
+
+func TestCacheDeepCopy(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{}, ttask{})
+	db.Create(&tuser{ID: 1, Name: "alice"})
+	db.Create(&ttask{ID: 1, Title: "write", OwnerID: 1})
+
+	r := gin.New()
+	err := RegisterObjects(r, []WebObject{
+		{
+			Name:     "task",
+			Model:    ttask{},
+			GetDB:    func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			Cache:    NewLRUCache(10),
+			CacheKey: func(c *gin.Context) string { return "" },
+			BeforeRender: func(ctx *gin.Context, vptr any) error {
+				// the nested values of cache are not modified
+				vptr.(*ttask).Owner.Name += "!"
+				return nil
+			},
+		},
+	})
+	assert.Nil(t, err)
+	client := NewTestClient(r)
+
+	for i := 0; i < 2; i++ {
+		var task ttask
+		assert.Nil(t, client.CallGet("/task/1", nil, &task))
+		assert.Equal(t, "alice!", task.Owner.Name)
+
+		var result QueryResult[[]ttask]
+		assert.Nil(t, client.CallPost("/task", QueryForm{}, &result))
+		assert.Equal(t, "alice!", result.Items[0].Owner.Name)
+	}
+
+	type node struct {
+		Tags  []string
+		Attrs map[string]any
+		Next  *node
+		at    time.Time
+	}
+	n := &node{Tags: []string{"a"}, Attrs: map[string]any{"k": []int{1}}, at: time.Now()}
+	n.Next = n
+	cp := deepCopy(reflect.ValueOf(n), nil).Interface().(*node)
+	cp.Tags[0] = "b"
+	cp.Attrs["k"].([]int)[0] = 2
+	assert.Equal(t, "a", n.Tags[0])
+	assert.Equal(t, 1, n.Attrs["k"].([]int)[0])
+	assert.True(t, cp.Next == cp && cp != n)
+	assert.Equal(t, n.at, cp.at)
+}
@@ -0,0 +1,50 @@
+package gormpher
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// getActor return the actor of request by GetActor.
+func (obj *WebObject) getActor(c *gin.Context) string {
+	if obj.GetActor == nil {
+		return ""
+	}
+	return obj.GetActor(c)
+}
+
+// trackChanges return true when the old and new objects of writes are needed,
+// for audit log or change events.
+func (obj *WebObject) trackChanges() bool {
+	return obj.Audit != nil || obj.Stream != nil || obj.Publisher != nil || len(obj.Webhooks) > 0
+}
+
+// transaction run fn in a transaction when the write need to be recorded
+// by Audit, Publisher or Webhooks, so the write and the records are committed together.
+func (obj *WebObject) transaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if obj.Audit == nil && obj.Publisher == nil && len(obj.Webhooks) == 0 {
+		return fn(db)
+	}
+	return db.Transaction(fn)
+}
+
+// recordChanges record the write in transaction with Audit, Publisher and Webhooks,
+// oldVal is nil for create and newVal is nil for delete.
+func (obj *WebObject) recordChanges(c *gin.Context, tx *gorm.DB, action int, oldVal, newVal any) error {
+	if err := obj.recordAudit(c, tx, action, oldVal, newVal); err != nil {
+		return err
+	}
+	if obj.Publisher == nil && len(obj.Webhooks) == 0 {
+		return nil
+	}
+	ev, err := obj.newEvent(c, action, oldVal, newVal)
+	if err != nil {
+		return err
+	}
+	if obj.Publisher != nil {
+		if err := obj.Publisher.Publish(c, tx, ev); err != nil {
+			return err
+		}
+	}
+	return obj.enqueueWebhooks(tx, ev)
+}
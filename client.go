@@ -0,0 +1,235 @@
+package gormpher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// ClientError is the error response of WebObject API.
+type ClientError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *ClientError) Error() string {
+	return fmt.Sprintf("%d %s", e.StatusCode, e.Message)
+}
+
+// RequestOption set the request of Client, such as IfMatch.
+type RequestOption func(req *http.Request)
+
+// IfMatch return the option of If-Match header, etag is the ETag of GetWithETag
+// or the version value, for the update and delete of object with VersionField.
+func IfMatch(etag string) RequestOption {
+	if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+		etag = `"` + etag + `"`
+	}
+	return func(req *http.Request) {
+		req.Header.Set("If-Match", etag)
+	}
+}
+
+// Client is the typed client of WebObject API, such as:
+//
+//	users := NewClient[User]("http://localhost:8080/api/user")
+//	user, err := users.Get(ctx, 1)
+//	r, err := users.Query(ctx, QueryForm{Filters: []Filter{{Name: "age", Op: ">=", Value: 18}}})
+//
+// The object with VersionField is updated and deleted with IfMatch:
+//
+//	user, etag, err := users.GetWithETag(ctx, 1)
+//	err = users.Update(ctx, 1, map[string]any{"name": "alice"}, IfMatch(etag))
+type Client[T any] struct {
+	BaseURL    string       // the URL of object, such as "http://localhost:8080/api/user"
+	HTTPClient *http.Client // http.DefaultClient when nil
+	Header     http.Header  // the headers of every request, such as Authorization
+
+	obj WebObject // for the json names of model
+}
+
+// NewClient return the client of object at baseURL, T is the model of object.
+func NewClient[T any](baseURL string) *Client[T] {
+	c := &Client[T]{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Header:  make(http.Header),
+	}
+	rt := reflect.TypeOf((*T)(nil)).Elem()
+	c.obj.modelElem = rt
+	c.obj.jsonToFields = make(map[string]string)
+	c.obj.jsonToKinds = make(map[string]reflect.Kind)
+	c.obj.parseFields(rt)
+	return c
+}
+
+// Get return the object of key.
+func (c *Client[T]) Get(ctx context.Context, key any) (*T, error) {
+	val, _, err := c.GetWithETag(ctx, key)
+	return val, err
+}
+
+// GetWithETag return the object of key and the ETag, for IfMatch.
+func (c *Client[T]) GetWithETag(ctx context.Context, key any) (*T, string, error) {
+	var val T
+	header, err := c.do(ctx, http.MethodGet, c.keyURL(key), nil, &val)
+	if err != nil {
+		return nil, "", err
+	}
+	return &val, header.Get("ETag"), nil
+}
+
+// Create create the object and return the created one.
+func (c *Client[T]) Create(ctx context.Context, val *T) (*T, error) {
+	var created T
+	if _, err := c.do(ctx, http.MethodPut, c.BaseURL, val, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Update update the object of key, the keys of vals are json names or field names,
+// such as {"name": "alice"} or {"Name": "alice"}.
+func (c *Client[T]) Update(ctx context.Context, key any, vals map[string]any, opts ...RequestOption) error {
+	body := make(map[string]any, len(vals))
+	for k, v := range vals {
+		body[c.jsonName(k)] = v
+	}
+	_, err := c.do(ctx, http.MethodPatch, c.keyURL(key), body, nil, opts...)
+	return err
+}
+
+// UpdateFields update the fields of val, the key is the primary key of val.
+// The version field of VersionField is sent as the fields, such as "Version".
+func (c *Client[T]) UpdateFields(ctx context.Context, val *T, fields ...string) error {
+	rv := reflect.ValueOf(val).Elem()
+	vals := make(map[string]any, len(fields))
+	for _, name := range fields {
+		fv := rv.FieldByName(name)
+		if !fv.IsValid() {
+			fv = rv.FieldByName(c.obj.jsonToFields[name])
+		}
+		if !fv.IsValid() {
+			return fmt.Errorf("unknown field %s", name)
+		}
+		vals[name] = fv.Interface()
+	}
+	return c.Update(ctx, c.obj.getKey(val), vals)
+}
+
+// Delete delete the object of key.
+func (c *Client[T]) Delete(ctx context.Context, key any, opts ...RequestOption) error {
+	_, err := c.do(ctx, http.MethodDelete, c.keyURL(key), nil, nil, opts...)
+	return err
+}
+
+// BatchDelete delete the objects of keys. The objects with VersionField require
+// the pairs of key and version, such as map[string]any{"id": 1, "version": 3}.
+func (c *Client[T]) BatchDelete(ctx context.Context, keys []any) error {
+	form := make([]any, len(keys))
+	for i, key := range keys {
+		if pair, ok := key.(map[string]any); ok {
+			form[i] = pair
+		} else {
+			form[i] = fmt.Sprintf("%v", key)
+		}
+	}
+	_, err := c.do(ctx, http.MethodDelete, c.BaseURL, form, nil)
+	return err
+}
+
+// Query query the objects with form.
+func (c *Client[T]) Query(ctx context.Context, form QueryForm) (*QueryResult[[]T], error) {
+	return c.QueryView(ctx, "", form)
+}
+
+// QueryView query the objects with the POST view of name, the object query when name is empty.
+func (c *Client[T]) QueryView(ctx context.Context, name string, form QueryForm) (*QueryResult[[]T], error) {
+	u := c.BaseURL
+	if name != "" {
+		u += "/" + url.PathEscape(name)
+	}
+	var r QueryResult[[]T]
+	if _, err := c.do(ctx, http.MethodPost, u, form, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (c *Client[T]) keyURL(key any) string {
+	return c.BaseURL + "/" + url.PathEscape(fmt.Sprintf("%v", key))
+}
+
+// jsonName return the json name of field name, the json name is returned as is.
+func (c *Client[T]) jsonName(name string) string {
+	if _, ok := c.obj.jsonToFields[name]; ok {
+		return name
+	}
+	if jsonName, ok := fieldJSONName(c.obj.modelElem, name); ok {
+		return jsonName
+	}
+	return name
+}
+
+// do send the request and decode the response into result, return the response header.
+func (c *Client[T]) do(ctx context.Context, method, u string, body, result any, opts ...RequestOption) (http.Header, error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range c.Header {
+		req.Header[k] = append([]string(nil), vs...)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &e) != nil || e.Error == "" {
+			e.Error = strings.TrimSpace(string(data))
+		}
+		if e.Error == "" {
+			e.Error = http.StatusText(resp.StatusCode)
+		}
+		return nil, &ClientError{StatusCode: resp.StatusCode, Message: e.Error}
+	}
+	if result == nil || len(data) == 0 {
+		return resp.Header, nil
+	}
+	return resp.Header, json.Unmarshal(data, result)
+}
@@ -0,0 +1,176 @@
+package gormpher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func initClientTest(t *testing.T) (*gin.Engine, *gorm.DB) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{})
+
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 9})
+	db.Create(&tuser{ID: 2, Name: "bob", Age: 10})
+	db.Create(&tuser{ID: 3, Name: "clash", Age: 11})
+
+	r := gin.Default()
+	r.Use(func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer token" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		}
+	})
+	RegisterObjects(r.Group("api"), []WebObject{
+		{
+			Name:         "user",
+			Model:        tuser{},
+			EditFields:   []string{"Name", "Age"},
+			FilterFields: []string{"Age"},
+			OrderFields:  []string{"Age"},
+			GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			Views: []QueryView{
+				{
+					Name: "adults",
+					Prepare: func(db *gorm.DB, c *gin.Context) (*gorm.DB, *QueryForm, error) {
+						db, form, err := DefaultPrepareQuery(db, c)
+						return db.Where("age >= ?", 10), form, err
+					},
+				},
+			},
+		},
+	})
+	return r, db
+}
+
+func TestClientCRUD(t *testing.T) {
+	r, db := initClientTest(t)
+	ctx := context.Background()
+
+	users := NewClient[tuser]("http://example.com/api/user/")
+	users.HTTPClient = &http.Client{Transport: NewTestClient(r)}
+	users.Header.Set("Authorization", "Bearer token")
+
+	user, err := users.Get(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", user.Name)
+
+	user, err = users.Create(ctx, &tuser{Name: "dave", Age: 20})
+	assert.Nil(t, err)
+	assert.Equal(t, uint(4), user.ID)
+
+	// json name and field name
+	err = users.Update(ctx, 4, map[string]any{"name": "dave2", "Age": 21})
+	assert.Nil(t, err)
+	user.Name = "dave3"
+	err = users.UpdateFields(ctx, user, "Name")
+	assert.Nil(t, err)
+
+	user, err = users.Get(ctx, "4")
+	assert.Nil(t, err)
+	assert.Equal(t, tuser{ID: 4, Name: "dave3", Age: 21}, *user)
+
+	err = users.UpdateFields(ctx, user, "Unknown")
+	assert.EqualError(t, err, "unknown field Unknown")
+
+	err = users.Delete(ctx, 4)
+	assert.Nil(t, err)
+	_, err = users.Get(ctx, 4)
+	var e *ClientError
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, http.StatusNotFound, e.StatusCode)
+	assert.Equal(t, "not found", e.Message)
+
+	err = users.BatchDelete(ctx, []any{1, "2"})
+	assert.Nil(t, err)
+	var count int64
+	db.Model(&tuser{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// without Authorization
+	users.Header.Del("Authorization")
+	_, err = users.Get(ctx, 3)
+	assert.EqualError(t, err, "401 unauthorized")
+}
+
+func TestClientQuery(t *testing.T) {
+	r, _ := initClientTest(t)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	ctx := context.Background()
+	users := NewClient[tuser](srv.URL + "/api/user")
+	users.Header.Set("Authorization", "Bearer token")
+
+	result, err := users.Query(ctx, QueryForm{
+		Filters: []Filter{{Name: "age", Op: ">=", Value: 10}},
+		Orders:  []Order{{Name: "age", Op: "desc"}},
+		Limit:   1,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.Total)
+	assert.Equal(t, 1, result.Limit)
+	assert.Equal(t, []tuser{{ID: 3, Name: "clash", Age: 11}}, result.Items)
+
+	result, err = users.QueryView(ctx, "adults", QueryForm{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.Total)
+	assert.Len(t, result.Items, 2)
+
+	_, err = users.QueryView(ctx, "unknown", QueryForm{})
+	assert.Equal(t, http.StatusNotFound, err.(*ClientError).StatusCode)
+}
+
+func TestClientVersion(t *testing.T) {
+	type tdoc struct {
+		ID      uint   `json:"id" gorm:"primarykey"`
+		Title   string `json:"title"`
+		Version int    `json:"version"`
+	}
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tdoc{})
+	db.Create(&tdoc{ID: 1, Title: "draft", Version: 1})
+
+	r := gin.New()
+	err := RegisterObjects(r, []WebObject{
+		{
+			Name:         "doc",
+			Model:        tdoc{},
+			EditFields:   []string{"Title", "Version"},
+			VersionField: "Version",
+			GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+		},
+	})
+	assert.Nil(t, err)
+	ctx := context.Background()
+
+	docs := NewClient[tdoc]("http://example.com/doc")
+	docs.HTTPClient = &http.Client{Transport: NewTestClient(r)}
+
+	doc, etag, err := docs.GetWithETag(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, "draft", doc.Title)
+	assert.Equal(t, `"1"`, etag)
+
+	err = docs.Update(ctx, 1, map[string]any{"title": "final"})
+	var e *ClientError
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, http.StatusPreconditionRequired, e.StatusCode)
+
+	err = docs.Update(ctx, 1, map[string]any{"title": "final"}, IfMatch(etag))
+	assert.Nil(t, err)
+
+	// the stale version
+	err = docs.Delete(ctx, 1, IfMatch(etag))
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, http.StatusPreconditionFailed, e.StatusCode)
+
+	err = docs.Delete(ctx, 1, IfMatch("2"))
+	assert.Nil(t, err)
+}
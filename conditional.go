@@ -0,0 +1,147 @@
+package gormpher
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getLastModified return the UpdatedAt of vptr, return zero time when model has no UpdatedAt.
+// The collections have no Last-Modified, as the deletes don't change the latest UpdatedAt.
+func (obj *WebObject) getLastModified(vptr any) (lastModified time.Time) {
+	f, ok := obj.modelElem.FieldByName("UpdatedAt")
+	if !ok || (f.Type != timeType && f.Type != reflect.PtrTo(timeType)) {
+		return lastModified
+	}
+
+	rv := reflect.ValueOf(vptr)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return lastModified
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return lastModified
+	}
+
+	fv := rv.FieldByIndex(f.Index)
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return lastModified
+		}
+		fv = fv.Elem()
+	}
+	lastModified = fv.Interface().(time.Time)
+	// the modification in the current second is a weak validator, as the later
+	// modifications in the same second have the same Last-Modified, see RFC 7232 section 2.2.2
+	if time.Since(lastModified) < time.Second {
+		return time.Time{}
+	}
+	return lastModified
+}
+
+// getRepresentationETag return the ETag of VersionField for the representation of request,
+// the media type and unreadable fields are appended as the variant, such as `"3+1a2b3c4d"`,
+// so the representations of the same version are not revalidated by each other.
+// The plain JSON with all fields is `"3"`, same as the ETag of writes. The variant of
+// the object with preloads is the hash of body, as the related rows have their own versions.
+func (obj *WebObject) getRepresentationETag(c *gin.Context, vptr any, body []byte) string {
+	etag := obj.getETag(vptr)
+	if etag == "" {
+		return ""
+	}
+	if len(obj.preloads) > 0 {
+		sum := sha1.Sum(body)
+		return `"` + strings.Trim(etag, `"`) + "+" + hex.EncodeToString(sum[:8]) + `"`
+	}
+
+	var variant []string
+	for f := range obj.unreadableFields(c) {
+		variant = append(variant, f)
+	}
+	sort.Strings(variant)
+	if isJSONAPI(c) {
+		variant = append([]string{MimeJSONAPI}, variant...)
+	}
+	if len(variant) == 0 {
+		return etag
+	}
+	sum := sha1.Sum([]byte(strings.Join(variant, ",")))
+	return `"` + strings.Trim(etag, `"`) + "+" + hex.EncodeToString(sum[:4]) + `"`
+}
+
+// setVary write the Vary header of get and query, the response varies by
+// Accept with JSON:API, and by the credentials with the access control.
+func (obj *WebObject) setVary(c *gin.Context) {
+	var vary []string
+	if obj.JSONAPI {
+		vary = append(vary, "Accept")
+	}
+	if len(obj.FieldPolicies) > 0 || obj.Authorizer != nil || obj.Scope != nil || obj.TenantField != "" {
+		vary = append(vary, "Authorization", "Cookie")
+	}
+	if len(vary) > 0 {
+		c.Header("Vary", strings.Join(vary, ", "))
+	}
+}
+
+// renderConditional render val as JSON with ETag, Last-Modified and Cache-Control headers,
+// respond 304 when If-None-Match or If-Modified-Since is satisfied.
+// The ETag is a hash of the response body when etag is empty.
+func renderConditional(c *gin.Context, val any, etag string, lastModified time.Time, cacheControl string) {
+	body, err := json.Marshal(val)
+	if err != nil {
+		handleError(c, http.StatusInternalServerError, err)
+		return
+	}
+	renderConditionalBody(c, body, etag, lastModified, cacheControl)
+}
+
+// renderConditionalBody render the JSON body, same as renderConditional.
+func renderConditionalBody(c *gin.Context, body []byte, etag string, lastModified time.Time, cacheControl string) {
+	if etag == "" {
+		sum := sha1.Sum(body)
+		etag = `"` + hex.EncodeToString(sum[:]) + `"`
+	}
+
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if cacheControl != "" {
+		c.Header("Cache-Control", cacheControl)
+	}
+
+	if isNotModified(c.Request, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// isNotModified check If-None-Match first, If-Modified-Since is ignored
+// when If-None-Match is present, see RFC 7232 section 6.
+func isNotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag = strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+		return matchETag(inm, etag)
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		return !lastModified.Truncate(time.Second).After(t)
+	}
+	return false
+}
@@ -0,0 +1,261 @@
+package gormpher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestConditionalGet(t *testing.T) {
+	type Product struct {
+		ID        uint      `json:"id" gorm:"primarykey"`
+		UpdatedAt time.Time `json:"updatedAt"`
+		Name      string    `json:"name"`
+	}
+
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(Product{})
+	modified := time.Now().Add(-time.Minute)
+	db.Create(&Product{ID: 1, Name: "apple", UpdatedAt: modified})
+	db.Create(&Product{ID: 2, Name: "banana", UpdatedAt: modified})
+	db.Create(&Product{ID: 3, Name: "cherry"})
+
+	r := gin.Default()
+	err := RegisterObject(r, &WebObject{
+		Model:        Product{},
+		CacheControl: "private, max-age=10",
+		GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+		Views: []QueryView{
+			{
+				Name:         "all",
+				Method:       http.MethodGet,
+				CacheControl: "public, max-age=60",
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	send := func(method, path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Get
+	{
+		w := send(http.MethodGet, "/product/1", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "private, max-age=10", w.Header().Get("Cache-Control"))
+		assert.NotEmpty(t, w.Header().Get("Last-Modified"))
+		assert.Contains(t, w.Body.String(), `"name":"apple"`)
+
+		etag := w.Header().Get("ETag")
+		assert.NotEmpty(t, etag)
+
+		w = send(http.MethodGet, "/product/1", map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+
+		w = send(http.MethodGet, "/product/2", map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+		w = send(http.MethodGet, "/product/1", map[string]string{"If-Modified-Since": future})
+		assert.Equal(t, http.StatusNotModified, w.Code)
+
+		past := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+		w = send(http.MethodGet, "/product/1", map[string]string{"If-Modified-Since": past})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// modified in the current second
+		w = send(http.MethodGet, "/product/3", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Last-Modified"))
+		w = send(http.MethodGet, "/product/3", map[string]string{"If-Modified-Since": future})
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	// Query
+	{
+		w := send(http.MethodPost, "/product", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "private, max-age=10", w.Header().Get("Cache-Control"))
+		assert.Empty(t, w.Header().Get("Last-Modified"))
+
+		etag := w.Header().Get("ETag")
+		w = send(http.MethodPost, "/product", map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusNotModified, w.Code)
+
+		// changed after update
+		db.Model(&Product{}).Where("id", 2).Update("name", "pear")
+		w = send(http.MethodPost, "/product", map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, strings.Contains(w.Body.String(), "pear"))
+
+		// the delete doesn't change the latest UpdatedAt of rows
+		future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+		etag = w.Header().Get("ETag")
+		db.Delete(&Product{}, 1)
+		w = send(http.MethodPost, "/product", map[string]string{"If-Modified-Since": future})
+		assert.Equal(t, http.StatusOK, w.Code)
+		w = send(http.MethodPost, "/product", map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "apple")
+	}
+	// View
+	{
+		w := send(http.MethodGet, "/product/all", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestConditionalGetVersion(t *testing.T) {
+	type Product struct {
+		ID      uint   `json:"id" gorm:"primarykey"`
+		Name    string `json:"name"`
+		Version int    `json:"version"`
+	}
+
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(Product{})
+	db.Create(&Product{ID: 1, Name: "apple", Version: 5})
+
+	r := gin.Default()
+	err := RegisterObject(r, &WebObject{
+		Model:        Product{},
+		VersionField: "Version",
+		GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+	})
+	assert.Nil(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/product/1", nil)
+	req.Header.Set("If-None-Match", `"5"`)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, `"5"`, w.Header().Get("ETag"))
+	assert.Empty(t, w.Header().Get("Last-Modified"))
+}
+
+func TestConditionalGetRepresentation(t *testing.T) {
+	type Product struct {
+		ID      uint   `json:"id" gorm:"primarykey"`
+		Name    string `json:"name"`
+		Price   int    `json:"price"`
+		Version int    `json:"version"`
+	}
+
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(Product{})
+	db.Create(&Product{ID: 1, Name: "apple", Price: 10, Version: 5})
+
+	r := gin.Default()
+	err := RegisterObject(r, &WebObject{
+		Model:        Product{},
+		EditFields:   []string{"Name"},
+		VersionField: "Version",
+		JSONAPI:      true,
+		GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+		FieldPolicies: []FieldPolicy{
+			{Field: "Price", CanRead: func(c *gin.Context) bool { return c.GetHeader("X-Role") == "admin" }},
+		},
+	})
+	assert.Nil(t, err)
+
+	get := func(accept, role, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/product/1", nil)
+		req.Header.Set("Accept", accept)
+		req.Header.Set("X-Role", role)
+		req.Header.Set("If-None-Match", ifNoneMatch)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	admin := get("application/json", "admin", "")
+	assert.Equal(t, `"5"`, admin.Header().Get("ETag"))
+	assert.Equal(t, "Accept, Authorization, Cookie", admin.Header().Get("Vary"))
+
+	// the other representations of the same version have different ETags
+	user := get("application/json", "", "")
+	assert.NotContains(t, user.Body.String(), "price")
+	jsonAPI := get(MimeJSONAPI, "admin", "")
+	etags := []string{admin.Header().Get("ETag"), user.Header().Get("ETag"), jsonAPI.Header().Get("ETag")}
+	assert.Len(t, map[string]bool{etags[0]: true, etags[1]: true, etags[2]: true}, 3)
+
+	assert.Equal(t, http.StatusOK, get("application/json", "", etags[0]).Code)
+	assert.Equal(t, http.StatusOK, get(MimeJSONAPI, "admin", etags[0]).Code)
+	assert.Equal(t, http.StatusNotModified, get("application/json", "", etags[1]).Code)
+
+	// the ETag of representation is accepted by If-Match
+	req := httptest.NewRequest(http.MethodPatch, "/product/1", strings.NewReader(`{"name":"pear"}`))
+	req.Header.Set("If-Match", etags[1])
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestConditionalGetPreload(t *testing.T) {
+	type Owner struct {
+		ID   uint   `json:"id" gorm:"primarykey"`
+		Name string `json:"name"`
+	}
+	type Pet struct {
+		ID      uint   `json:"id" gorm:"primarykey"`
+		Name    string `json:"name"`
+		Version int    `json:"version"`
+		OwnerID uint   `json:"ownerId"`
+		Owner   *Owner `json:"owner" gorm:"foreignKey:OwnerID"`
+	}
+
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(Owner{}, Pet{})
+	db.Create(&Owner{ID: 1, Name: "alice"})
+	db.Create(&Pet{ID: 1, Name: "tom", Version: 1, OwnerID: 1})
+
+	r := gin.Default()
+	err := RegisterObject(r, &WebObject{
+		Model:        Pet{},
+		EditFields:   []string{"Name"},
+		VersionField: "Version",
+		GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+	})
+	assert.Nil(t, err)
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/pet/1", nil)
+		req.Header.Set("If-None-Match", ifNoneMatch)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("")
+	etag := w.Header().Get("ETag")
+	assert.True(t, strings.HasPrefix(etag, `"1+`))
+	assert.Equal(t, http.StatusNotModified, get(etag).Code)
+
+	// the related row is changed, the version of pet is not
+	db.Model(&Owner{}).Where("id", 1).Update("name", "bob")
+	w = get(etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "bob")
+
+	// the ETag of body is accepted by If-Match
+	req := httptest.NewRequest(http.MethodPatch, "/pet/1", strings.NewReader(`{"name":"jerry"}`))
+	req.Header.Set("If-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,376 @@
+package gormpher
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// Event types
+const (
+	EventCreated = "created"
+	EventUpdated = "updated"
+	EventDeleted = "deleted"
+)
+
+const (
+	DefaultEventBufferSize = 1024
+	eventSubscriberBuffer  = 64
+	eventHeartbeat         = 15 * time.Second
+)
+
+// Event is a change of WebObject produced by create, update, delete and batch delete.
+type Event struct {
+	ID     uint64         `json:"id"`
+	Type   string         `json:"type"` // created, updated or deleted
+	Object string         `json:"object"`
+	Key    string         `json:"key"`
+	Actor  string         `json:"actor,omitempty"`
+	Time   time.Time      `json:"time"`
+	Data   map[string]any `json:"data"` // json format key, the old object for deleted
+}
+
+// EventStream broadcast events to subscribers, and keep the recent events
+// in a ring buffer, so subscribers can resume with Last-Event-ID.
+// It can be shared by multiple WebObjects.
+type EventStream struct {
+	mu          sync.Mutex
+	buf         []Event
+	lastID      uint64
+	subscribers map[chan Event]struct{}
+}
+
+// NewEventStream return EventStream keeping the recent size events.
+func NewEventStream(size int) *EventStream {
+	if size <= 0 {
+		size = DefaultEventBufferSize
+	}
+	return &EventStream{
+		buf:         make([]Event, size),
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Publish assign the id of ev and broadcast it. The slow subscriber is
+// closed instead of blocking the publisher, it can resume with Last-Event-ID.
+func (s *EventStream) Publish(ev Event) Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	ev.ID = s.lastID
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	s.buf[(ev.ID-1)%uint64(len(s.buf))] = ev
+
+	for ch := range s.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ev
+}
+
+// Subscribe return the buffered events after lastID, and the channel of new events.
+// Zero lastID subscribes the new events only. The cancel func must be called when done.
+func (s *EventStream) Subscribe(lastID uint64) (backlog []Event, ch <-chan Event, cancel func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lastID == 0 {
+		lastID = s.lastID
+	}
+	size := uint64(len(s.buf))
+	from := lastID + 1
+	if s.lastID >= size && from <= s.lastID-size {
+		from = s.lastID - size + 1
+	}
+	for id := from; id <= s.lastID; id++ {
+		backlog = append(backlog, s.buf[(id-1)%size])
+	}
+
+	c := make(chan Event, eventSubscriberBuffer)
+	s.subscribers[c] = struct{}{}
+	return backlog, c, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subscribers[c]; ok {
+			delete(s.subscribers, c)
+			close(c)
+		}
+	}
+}
+
+func eventType(action int) string {
+	switch action {
+	case CREATE:
+		return EventCreated
+	case EDIT:
+		return EventUpdated
+	default:
+		return EventDeleted
+	}
+}
+
+// newEvent return the event of write, oldVal is nil for create and newVal is nil for delete.
+func (obj *WebObject) newEvent(c *gin.Context, action int, oldVal, newVal any) (Event, error) {
+	val := newVal
+	if val == nil {
+		val = oldVal
+	}
+	data, err := toJSONMap(val)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		Type:   eventType(action),
+		Object: obj.Name,
+		Key:    obj.getKey(val),
+		Actor:  obj.getActor(c),
+		Time:   time.Now(),
+		Data:   data,
+	}, nil
+}
+
+// notify publish the write to Stream after commit.
+func (obj *WebObject) notify(c *gin.Context, action int, oldVal, newVal any) {
+	if obj.Stream == nil {
+		return
+	}
+	ev, err := obj.newEvent(c, action, oldVal, newVal)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	obj.Stream.Publish(ev)
+}
+
+// handleEvents stream the events of obj with Server-Sent Events, such as:
+//
+//	GET /product/events?filters=[{"name":"enabled","op":"=","value":true}]
+//	Last-Event-ID: 10
+func handleEvents(c *gin.Context, obj *WebObject) {
+	if !obj.authorize(c, QUERY, nil) {
+		return
+	}
+
+	var tenant any
+	if obj.TenantField != "" {
+		var err error
+		if tenant, err = obj.getTenant(c); err != nil {
+			handleError(c, http.StatusForbidden, err)
+			return
+		}
+	}
+
+	var filters []Filter
+	if v := c.Query("filters"); v != "" {
+		if err := json.Unmarshal([]byte(v), &filters); err != nil {
+			handleError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+	filters = obj.stripEventFilters(c, filters)
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+	lastID, _ := strconv.ParseUint(lastEventID, 10, 64)
+
+	backlog, ch, cancel := obj.Stream.Subscribe(lastID)
+	defer cancel()
+
+	unreadables := obj.unreadableFields(c)
+	send := func(ev Event) {
+		if ev.Object != obj.Name {
+			return
+		}
+		if obj.TenantField != "" && !matchValue("=", ev.Data[obj.tenantJsonName], tenant) {
+			return
+		}
+		if !matchFilters(filters, ev.Data) {
+			return
+		}
+		if !obj.authorizeEvent(c, ev) {
+			return
+		}
+		if len(unreadables) > 0 {
+			data := make(map[string]any, len(ev.Data))
+			for k, v := range ev.Data {
+				data[k] = v
+			}
+			for f := range unreadables {
+				delete(data, obj.getJsonName(f))
+			}
+			ev.Data = data
+		}
+		c.Render(-1, sse.Event{Id: strconv.FormatUint(ev.ID, 10), Event: ev.Type, Data: ev})
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	for _, ev := range backlog {
+		send(ev)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			c.Writer.WriteString(": heartbeat\n\n")
+			c.Writer.Flush()
+		case ev, ok := <-ch:
+			if !ok {
+				// too slow, the client should reconnect with Last-Event-ID
+				return
+			}
+			send(ev)
+			c.Writer.Flush()
+		}
+	}
+}
+
+// authorizeEvent check the row of ev with Authorizer, same as GET.
+func (obj *WebObject) authorizeEvent(c *gin.Context, ev Event) bool {
+	if obj.Authorizer == nil {
+		return true
+	}
+	data, err := json.Marshal(ev.Data)
+	if err != nil {
+		return false
+	}
+	val := reflect.New(obj.modelElem).Interface()
+	if err := json.Unmarshal(data, val); err != nil {
+		return false
+	}
+	return obj.Authorizer.Authorize(c, obj, GET, val) == nil
+}
+
+// stripEventFilters keep the filters of FilterFields, same as query.
+func (obj *WebObject) stripEventFilters(c *gin.Context, filters []Filter) []Filter {
+	unreadables := obj.unreadableFields(c)
+	filterFields := make(map[string]struct{})
+	for _, k := range obj.FilterFields {
+		if _, ok := unreadables[k]; !ok {
+			filterFields[k] = struct{}{}
+		}
+	}
+
+	var stripFilters []Filter
+	for _, f := range filters {
+		field, ok := obj.jsonToFields[f.Name]
+		if !ok {
+			continue
+		}
+		if _, ok := filterFields[field]; !ok {
+			continue
+		}
+		stripFilters = append(stripFilters, f)
+	}
+	return stripFilters
+}
+
+// matchFilters check data (json format key) with all filters in memory.
+func matchFilters(filters []Filter, data map[string]any) bool {
+	for _, f := range filters {
+		if !matchValue(f.Op, data[f.Name], f.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchValue compare v with the filter value, support the ops of Filter.
+func matchValue(op string, v, value any) bool {
+	switch strings.ToLower(op) {
+	case "=", "equal":
+		return compareValue(v, value) == 0
+	case "<>", "!=", "not_equal":
+		return compareValue(v, value) != 0
+	case ">", "greater":
+		return compareValue(v, value) > 0
+	case ">=", "greater_or_equal":
+		return compareValue(v, value) >= 0
+	case "<", "less":
+		return compareValue(v, value) < 0
+	case "<=", "less_or_equal":
+		return compareValue(v, value) <= 0
+	case "in", "not_in":
+		found := false
+		if values, ok := value.([]any); ok {
+			for _, item := range values {
+				if compareValue(v, item) == 0 {
+					found = true
+					break
+				}
+			}
+		}
+		return found == (strings.ToLower(op) == "in")
+	case "like":
+		pattern := regexp.QuoteMeta(fmt.Sprintf("%v", value))
+		pattern = strings.ReplaceAll(pattern, "%", ".*")
+		pattern = strings.ReplaceAll(pattern, "_", ".")
+		matched, _ := regexp.MatchString("(?is)^"+pattern+"$", fmt.Sprintf("%v", v))
+		return matched
+	}
+	return false
+}
+
+// compareValue compare number as float64, otherwise compare as string.
+func compareValue(a, b any) int {
+	af, aok := toFloat(a)
+	bf, bok := toFloat(b)
+	if aok && bok {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,210 @@
+package gormpher
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// readEvents read n events from the SSE stream of url.
+func readEvents(t *testing.T, u string, lastEventID string, n int) []Event {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	var events []Event
+	scanner := bufio.NewScanner(resp.Body)
+	for len(events) < n && scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		var ev Event
+		err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &ev)
+		assert.Nil(t, err)
+		events = append(events, ev)
+	}
+	return events
+}
+
+func TestEventStream(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{})
+
+	stream := NewEventStream(3)
+
+	r := gin.Default()
+	RegisterObjects(r, []WebObject{
+		{
+			Name:         "user",
+			Model:        tuser{},
+			EditFields:   []string{"Name", "Age"},
+			FilterFields: []string{"Age"},
+			GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			GetActor:     func(ctx *gin.Context) string { return "admin" },
+			Stream:       stream,
+		},
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	c := NewTestClient(r)
+	c.CallPut("/user", map[string]any{"name": "alice", "age": 9}, nil)
+	c.CallPut("/user", map[string]any{"name": "bob", "age": 10}, nil)
+	c.CallPatch("/user/1", map[string]any{"age": 20}, nil)
+	c.CallDelete("/user/2", nil, nil)
+
+	// resume from ring buffer
+	events := readEvents(t, srv.URL+"/user/events", "1", 3)
+	assert.Len(t, events, 3)
+	assert.Equal(t, uint64(2), events[0].ID)
+	assert.Equal(t, EventCreated, events[0].Type)
+	assert.Equal(t, "bob", events[0].Data["name"])
+
+	assert.Equal(t, EventUpdated, events[1].Type)
+	assert.Equal(t, "1", events[1].Key)
+	assert.Equal(t, "admin", events[1].Actor)
+	assert.Equal(t, float64(20), events[1].Data["age"])
+
+	assert.Equal(t, EventDeleted, events[2].Type)
+	assert.Equal(t, "2", events[2].Key)
+
+	events = readEvents(t, srv.URL+"/user/events", "3", 1)
+	assert.Equal(t, uint64(4), events[0].ID)
+
+	// live events with filters, the buffered events are not replayed without Last-Event-ID
+	assert.True(t, waitSubscribers(stream, 0))
+	done := make(chan []Event)
+	go func() {
+		filters := url.QueryEscape(`[{"name":"age","op":">=","value":18}]`)
+		done <- readEvents(t, srv.URL+"/user/events?filters="+filters, "", 2)
+	}()
+	assert.True(t, waitSubscribers(stream, 1))
+
+	c.CallPut("/user", map[string]any{"name": "clash", "age": 10}, nil)
+	c.CallPut("/user", map[string]any{"name": "dave", "age": 30}, nil)
+	c.CallDelete("/user", []string{"1", "3"}, nil)
+
+	events = <-done
+	assert.Len(t, events, 2)
+	assert.Equal(t, "dave", events[0].Data["name"])
+	assert.Equal(t, EventDeleted, events[1].Type)
+	assert.Equal(t, "1", events[1].Key)
+}
+
+// waitSubscribers wait for the number of subscribers of stream to be n.
+func waitSubscribers(stream *EventStream, n int) bool {
+	for i := 0; i < 100; i++ {
+		stream.mu.Lock()
+		count := len(stream.subscribers)
+		stream.mu.Unlock()
+		if count == n {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestEventStreamSubscribe(t *testing.T) {
+	stream := NewEventStream(3)
+	for i := 0; i < 5; i++ {
+		stream.Publish(Event{Type: EventCreated})
+	}
+
+	backlog, _, cancel := stream.Subscribe(0)
+	defer cancel()
+	assert.Empty(t, backlog)
+
+	// the events before 3 are dropped from the ring buffer
+	backlog, _, cancel2 := stream.Subscribe(1)
+	defer cancel2()
+	assert.Len(t, backlog, 3)
+	assert.Equal(t, uint64(3), backlog[0].ID)
+
+	backlog, _, cancel3 := stream.Subscribe(4)
+	defer cancel3()
+	assert.Len(t, backlog, 1)
+	assert.Equal(t, uint64(5), backlog[0].ID)
+}
+
+func TestEventStreamAuthorizer(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{})
+
+	stream := NewEventStream(10)
+	objs := []WebObject{
+		{
+			Name:       "user",
+			Model:      tuser{},
+			EditFields: []string{"Name", "Age"},
+			GetDB:      func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			Stream:     stream,
+			Authorizer: AuthorizerFunc(func(ctx *gin.Context, obj *WebObject, action int, vptr any) error {
+				if action == GET && vptr.(*tuser).Age < 18 {
+					return errors.New("forbidden")
+				}
+				return nil
+			}),
+		},
+	}
+	r := gin.New()
+	assert.Nil(t, RegisterObjects(r, objs))
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	c := NewTestClient(r)
+	c.CallPut("/user", map[string]any{"name": "alice", "age": 9}, nil)
+	c.CallPut("/user", map[string]any{"name": "bob", "age": 20}, nil)
+	events := readEvents(t, srv.URL+"/user/events", "1", 1)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "bob", events[0].Data["name"])
+
+	// the events can't be scoped
+	objs[0].Scope = func(ctx *gin.Context, db *gorm.DB, action int) *gorm.DB { return db }
+	err := RegisterObjects(gin.New(), objs)
+	assert.ErrorContains(t, err, "Stream can't be used with Scope")
+}
+
+func TestMatchFilters(t *testing.T) {
+	data := map[string]any{"name": "alice", "age": float64(10), "enabled": true}
+
+	tests := []struct {
+		filter Filter
+		expect bool
+	}{
+		{Filter{Name: "name", Op: "=", Value: "alice"}, true},
+		{Filter{Name: "name", Op: "<>", Value: "alice"}, false},
+		{Filter{Name: "age", Op: ">", Value: 9}, true},
+		{Filter{Name: "age", Op: "<=", Value: "9"}, false},
+		{Filter{Name: "age", Op: "in", Value: []any{float64(1), float64(10)}}, true},
+		{Filter{Name: "age", Op: "not_in", Value: []any{float64(1), float64(10)}}, false},
+		{Filter{Name: "name", Op: "like", Value: "%LIC%"}, true},
+		{Filter{Name: "name", Op: "like", Value: "b%"}, false},
+		{Filter{Name: "enabled", Op: "=", Value: true}, true},
+		{Filter{Name: "enabled", Op: "unknown", Value: true}, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expect, matchFilters([]Filter{tt.filter}, data), tt.filter)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"flag"
+	"log"
 	"math/rand"
 	"time"
 
@@ -52,9 +53,13 @@ func main() {
 
 	objs := GetWebObjects(db)
 	// visit API: http://localhost:8890/api
-	gormpher.RegisterObjects(r, objs)
+	if err := gormpher.RegisterObjects(r, objs); err != nil {
+		log.Fatal(err)
+	}
 	// visit Admin: http://localhost:8890/admin/v1
-	gormpher.RegisterObjectsWithAdmin(r.Group("admin"), objs)
+	if err := gormpher.RegisterObjectsWithAdmin(r.Group("admin"), objs); err != nil {
+		log.Fatal(err)
+	}
 
 	r.Run(addr)
 }
@@ -0,0 +1,568 @@
+package gormpher
+
+import (
+	"archive/zip"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const DefaultMaxExportRows = 10000
+
+// Export format
+const (
+	ExportCSV    = "csv"
+	ExportXLSX   = "xlsx"
+	ExportNDJSON = "ndjson" // stream the JSON objects, one per line
+)
+
+const (
+	MimeCSV    = "text/csv"
+	MimeXLSX   = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	MimeNDJSON = "application/x-ndjson"
+)
+
+// HeaderExportTruncated is set to "true" when the rows of export are over MaxExportRows,
+// or in the trailer when the stream of NDJSON is aborted.
+const HeaderExportTruncated = "X-Export-Truncated"
+
+// the response is flushed every exportFlushRows rows
+const exportFlushRows = 100
+
+// the NDJSON is queried in pages of exportPageRows rows, with the preloads of page
+const exportPageRows = 100
+
+var errExportFormat = errors.New("invalid export format")
+
+// exportColumn is a column of export, index is the field index of model.
+type exportColumn struct {
+	name  string
+	index []int
+}
+
+// getExportFormat return the export format from "export" option or Accept header,
+// empty means not export.
+func getExportFormat(c *gin.Context, form *QueryForm) (string, error) {
+	switch strings.ToLower(form.Export) {
+	case "":
+	case ExportCSV:
+		return ExportCSV, nil
+	case ExportXLSX:
+		return ExportXLSX, nil
+	case ExportNDJSON:
+		return ExportNDJSON, nil
+	default:
+		return "", errExportFormat
+	}
+
+	accept := c.GetHeader("Accept")
+	if strings.Contains(accept, MimeCSV) {
+		return ExportCSV, nil
+	}
+	if strings.Contains(accept, MimeXLSX) {
+		return ExportXLSX, nil
+	}
+	if strings.Contains(accept, MimeNDJSON) {
+		return ExportNDJSON, nil
+	}
+	return "", nil
+}
+
+// handleExportObjects stream all rows of query as CSV or XLSX, up to MaxExportRows,
+// the X-Export-Truncated header is set when there are more rows. NDJSON is streamed
+// by handleStreamObjects. The rows are scanned one by one and written at once, the
+// query is canceled when the client is disconnected. The pagination of form is
+// ignored, and the preloads are not exported.
+func handleExportObjects(c *gin.Context, db *gorm.DB, obj *WebObject, form *QueryForm, format string, hiddenFields map[string]struct{}) {
+	if format == ExportNDJSON {
+		handleStreamObjects(c, db, obj, form, hiddenFields)
+		return
+	}
+
+	limit := obj.MaxExportRows
+	if limit <= 0 {
+		limit = DefaultMaxExportRows
+	}
+
+	model := reflect.New(obj.modelElem).Interface()
+	db = db.WithContext(c.Request.Context())
+
+	// probe the row after limit, the header must be sent before the rows
+	probe, err := buildQuery(db, obj, form).Model(model).Offset(limit).Limit(1).Rows()
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+	truncated := probe.Next()
+	probe.Close()
+	if truncated {
+		c.Header(HeaderExportTruncated, "true")
+	}
+
+	rows, err := buildQuery(db, obj, form).Model(model).Limit(limit).Rows()
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+	defer rows.Close()
+
+	var w exportWriter
+	switch format {
+	case ExportXLSX:
+		c.Header("Content-Type", MimeXLSX)
+		w = newXLSXWriter(c.Writer, obj.Name)
+	default:
+		c.Header("Content-Type", MimeCSV+"; charset=utf-8")
+		w = newCSVWriter(c.Writer)
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, obj.Name, format))
+	c.Status(http.StatusOK)
+
+	// the status is sent, the error only aborts the stream.
+	err = w.WriteHeader(obj.exportColumns(hiddenFields, form.ViewFields))
+	n := 0
+	if err == nil {
+		err = obj.scanObjects(db, rows, func(vptr any) error {
+			if obj.BeforeRender != nil {
+				if err := obj.beforeRender(c, vptr); err != nil {
+					return err
+				}
+			}
+			if err := w.WriteRow(vptr); err != nil {
+				return err
+			}
+			if n++; n%exportFlushRows == 0 {
+				c.Writer.Flush()
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// handleStreamObjects stream all rows of query as NDJSON, the rows are queried in pages
+// with the preloads, and rendered same as the items of query. The stream is not limited
+// by MaxExportRows, the X-Export-Truncated trailer is set when it's aborted by error,
+// such as the statement timeout or the disconnected client.
+func handleStreamObjects(c *gin.Context, db *gorm.DB, obj *WebObject, form *QueryForm, hiddenFields map[string]struct{}) {
+	// order by primary key at last, so the pages are stable
+	tableName := db.NamingStrategy.TableName(obj.modelElem.Name())
+	db = buildQuery(db.WithContext(c.Request.Context()), obj, form).
+		Order(fmt.Sprintf("%s.%s", tableName, obj.gormPKName))
+	for _, v := range obj.preloads {
+		db = db.Preload(v)
+	}
+	// new session, so the query can be reused by pages
+	db = db.Session(&gorm.Session{})
+
+	page := func(offset int) (reflect.Value, error) {
+		items := reflect.New(reflect.SliceOf(obj.modelElem))
+		err := db.Offset(offset).Limit(exportPageRows).Find(items.Interface()).Error
+		return items.Elem(), err
+	}
+
+	items, err := page(0)
+	if err != nil {
+		handleQueryError(c, err)
+		return
+	}
+	c.Header("Content-Type", MimeNDJSON)
+	c.Header("Trailer", HeaderExportTruncated)
+	c.Status(http.StatusOK)
+
+	// the status is sent, the error only aborts the stream.
+	w := &ndjsonWriter{c: c, obj: obj, hiddenFields: hiddenFields}
+	for offset := 0; err == nil; {
+		for i := 0; i < items.Len() && err == nil; i++ {
+			if err = c.Request.Context().Err(); err != nil {
+				break
+			}
+			vptr := items.Index(i).Addr().Interface()
+			if obj.BeforeRender != nil {
+				if err = obj.beforeRender(c, vptr); err != nil {
+					break
+				}
+			}
+			err = w.WriteRow(vptr)
+		}
+		c.Writer.Flush()
+		if err != nil || items.Len() < exportPageRows {
+			break
+		}
+		offset += exportPageRows
+		items, err = page(offset)
+	}
+	if err != nil {
+		c.Writer.Header().Set(HeaderExportTruncated, "true")
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// scanObjects scan rows into the new models of obj one by one.
+func (obj *WebObject) scanObjects(db *gorm.DB, rows *sql.Rows, fn func(vptr any) error) error {
+	for rows.Next() {
+		vptr := reflect.New(obj.modelElem).Interface()
+		if err := db.ScanRows(rows, vptr); err != nil {
+			return err
+		}
+		if err := fn(vptr); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// exportColumns return the columns of model in order of fields, the associations
+// and hidden fields are skipped. viewFields is the column names of view.
+func (obj *WebObject) exportColumns(hiddenFields map[string]struct{}, viewFields []string) []exportColumn {
+	var views map[string]struct{}
+	if len(viewFields) > 0 {
+		views = make(map[string]struct{})
+		for _, v := range viewFields {
+			views[v] = struct{}{}
+		}
+	}
+
+	var columns []exportColumn
+	var walk func(rt reflect.Type, index []int)
+	walk = func(rt reflect.Type, index []int) {
+		for i := 0; i < rt.NumField(); i++ {
+			f := rt.Field(i)
+			fieldIndex := append(append([]int{}, index...), i)
+
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if f.Anonymous && ft.Kind() == reflect.Struct {
+				walk(ft, fieldIndex)
+				continue
+			}
+			if !f.IsExported() || !isExportableType(ft) {
+				continue
+			}
+			if _, ok := hiddenFields[f.Name]; ok {
+				continue
+			}
+
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if views != nil {
+				if _, ok := views[getColumnName(obj.modelElem, f.Name)]; !ok {
+					continue
+				}
+			}
+			columns = append(columns, exportColumn{name: name, index: fieldIndex})
+		}
+	}
+	walk(obj.modelElem, nil)
+	return columns
+}
+
+// isExportableType return false for the associations, maps and slices.
+func isExportableType(rt reflect.Type) bool {
+	if rt == timeType || rt.Implements(valuerType) || reflect.PointerTo(rt).Implements(valuerType) {
+		return true
+	}
+	switch rt.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Array, reflect.Chan, reflect.Func, reflect.Interface:
+		return false
+	case reflect.Slice:
+		return rt.Elem().Kind() == reflect.Uint8
+	}
+	return true
+}
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// exportCells return the cells of vptr, the cell is nil, string, bool, int64, uint64 or float64.
+func exportCells(vptr any, columns []exportColumn) []any {
+	rv := reflect.ValueOf(vptr).Elem()
+	cells := make([]any, len(columns))
+	for i, col := range columns {
+		if v, err := rv.FieldByIndexErr(col.index); err == nil {
+			cells[i] = exportCell(v)
+		}
+	}
+	return cells
+}
+
+// exportCell convert the field value to cell, the time is formatted as RFC3339,
+// the nil pointer and zero time are empty.
+func exportCell(v reflect.Value) any {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return nil
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	valuer, ok := v.Interface().(driver.Valuer)
+	if !ok && v.CanAddr() {
+		valuer, ok = v.Addr().Interface().(driver.Valuer)
+	}
+	if ok {
+		// such as sql.NullString, gorm.DeletedAt
+		val, err := valuer.Value()
+		if err != nil || val == nil {
+			return nil
+		}
+		if rv := reflect.ValueOf(val); rv.Type() != v.Type() {
+			return exportCell(rv)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// formatCell return the text of cell.
+func formatCell(cell any) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(cell)
+}
+
+type exportWriter interface {
+	WriteHeader(columns []exportColumn) error
+	WriteRow(vptr any) error
+	Close() error
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []exportColumn
+	record  []string
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (w *csvWriter) WriteHeader(columns []exportColumn) error {
+	w.columns = columns
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.name
+	}
+	return w.w.Write(header)
+}
+
+func (w *csvWriter) WriteRow(vptr any) error {
+	w.record = w.record[:0]
+	for _, cell := range exportCells(vptr, w.columns) {
+		text := formatCell(cell)
+		if _, ok := cell.(string); ok {
+			text = escapeCSVFormula(text)
+		}
+		w.record = append(w.record, text)
+	}
+	return w.w.Write(w.record)
+}
+
+// escapeCSVFormula prefix the text starting with =, +, -, @, tab or carriage return
+// with ', so it's not evaluated as formula by spreadsheet.
+func escapeCSVFormula(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// xlsxWriter write a minimal workbook with one sheet of inline strings,
+// the sheet is streamed into the zip, so the rows are not buffered.
+type xlsxWriter struct {
+	zw        *zip.Writer
+	sheetName string
+	sheet     io.Writer
+	columns   []exportColumn
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxSheetBegin = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+func newXLSXWriter(w io.Writer, sheetName string) *xlsxWriter {
+	// the max length of sheet name is 31
+	if sheetName == "" {
+		sheetName = "Sheet1"
+	} else if len(sheetName) > 31 {
+		sheetName = sheetName[:31]
+	}
+	return &xlsxWriter{zw: zip.NewWriter(w), sheetName: sheetName}
+}
+
+func (w *xlsxWriter) WriteHeader(columns []exportColumn) error {
+	var name strings.Builder
+	xml.EscapeText(&name, []byte(w.sheetName))
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, name.String())},
+	}
+	for _, part := range parts {
+		f, err := w.zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	sheet, err := w.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	w.sheet = sheet
+	if _, err := io.WriteString(w.sheet, xlsxSheetBegin); err != nil {
+		return err
+	}
+
+	w.columns = columns
+	header := make([]any, len(columns))
+	for i, col := range columns {
+		header[i] = col.name
+	}
+	return w.writeCells(header)
+}
+
+func (w *xlsxWriter) WriteRow(vptr any) error {
+	return w.writeCells(exportCells(vptr, w.columns))
+}
+
+func (w *xlsxWriter) writeCells(cells []any) error {
+	var b strings.Builder
+	b.WriteString("<row>")
+	for _, cell := range cells {
+		switch v := cell.(type) {
+		case nil:
+			b.WriteString("<c/>")
+		case bool:
+			if v {
+				b.WriteString(`<c t="b"><v>1</v></c>`)
+			} else {
+				b.WriteString(`<c t="b"><v>0</v></c>`)
+			}
+		case int64, uint64:
+			fmt.Fprintf(&b, "<c><v>%d</v></c>", v)
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				writeXLSXString(&b, formatCell(v))
+			} else {
+				fmt.Fprintf(&b, "<c><v>%s</v></c>", formatCell(v))
+			}
+		default:
+			writeXLSXString(&b, formatCell(v))
+		}
+	}
+	b.WriteString("</row>")
+	_, err := io.WriteString(w.sheet, b.String())
+	return err
+}
+
+func writeXLSXString(b *strings.Builder, s string) {
+	b.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+	xml.EscapeText(b, []byte(s))
+	b.WriteString("</t></is></c>")
+}
+
+func (w *xlsxWriter) Close() error {
+	if _, err := io.WriteString(w.sheet, xlsxSheetEnd); err != nil {
+		return err
+	}
+	return w.zw.Close()
+}
+
+// ndjsonWriter write the object without hidden fields in JSON, one per line.
+type ndjsonWriter struct {
+	c            *gin.Context
+	obj          *WebObject
+	hiddenFields map[string]struct{}
+}
+
+func (w *ndjsonWriter) WriteRow(vptr any) error {
+	out, err := w.obj.stripFields(vptr, w.hiddenFields)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	_, err = w.c.Writer.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,256 @@
+package gormpher
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type texport struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	CreatedAt time.Time `json:"createdAt"`
+	Name      string    `json:"name"`
+	Enabled   bool      `json:"enabled"`
+	Score     *float64  `json:"score"`
+	Secret    string    `json:"-"`
+	Owner     *tuser    `json:"owner" gorm:"foreignKey:OwnerID"`
+	OwnerID   uint      `json:"ownerId"`
+}
+
+func initExportTest(t *testing.T, obj WebObject) *TestClient {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{}, texport{})
+	db.Create(&tuser{ID: 1, Name: "alice"})
+
+	createdAt := time.Date(2023, 3, 17, 8, 30, 0, 0, time.UTC)
+	score := 9.5
+	var items []texport
+	for i := 0; i < 200; i++ {
+		item := texport{CreatedAt: createdAt, Name: fmt.Sprintf("item-%03d", i), Enabled: i%2 == 0}
+		if i == 0 {
+			item.Score = &score
+			item.OwnerID = 1
+		}
+		items = append(items, item)
+	}
+	db.CreateInBatches(items, 50)
+
+	obj.Name = "export"
+	obj.Model = texport{}
+	obj.GetDB = func(c *gin.Context, isCreate bool) *gorm.DB { return db }
+
+	r := gin.Default()
+	RegisterObjects(r, []WebObject{obj})
+	return NewTestClient(r)
+}
+
+func postExport(c *TestClient, path string, form any, accept string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(form)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	return c.SendReq(path, req)
+}
+
+func TestExportCSV(t *testing.T) {
+	c := initExportTest(t, WebObject{
+		FilterFields:  []string{"Enabled"},
+		OrderFields:   []string{"ID"},
+		MaxExportRows: 180,
+	})
+
+	// ignore the MaxQueryLimit, but limited by MaxExportRows
+	w := postExport(c, "/export", QueryForm{Limit: 10, Orders: []Order{{Name: "id", Op: "asc"}}}, MimeCSV)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), MimeCSV)
+	assert.Equal(t, `attachment; filename="export.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "true", w.Header().Get(HeaderExportTruncated))
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, records, 181)
+	assert.Equal(t, []string{"id", "createdAt", "name", "enabled", "score", "ownerId"}, records[0])
+	assert.Equal(t, []string{"1", "2023-03-17T08:30:00Z", "item-000", "true", "9.5", "1"}, records[1])
+	assert.Equal(t, []string{"2", "2023-03-17T08:30:00Z", "item-001", "false", "", "0"}, records[2])
+
+	// export option with filters
+	w = postExport(c, "/export", map[string]any{
+		"export":  "csv",
+		"filters": []Filter{{Name: "enabled", Op: "=", Value: false}},
+	}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(HeaderExportTruncated))
+	records, err = csv.NewReader(w.Body).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, records, 101)
+
+	w = postExport(c, "/export", map[string]any{"export": "pdf"}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// json as usual
+	w = postExport(c, "/export", QueryForm{}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+}
+
+func TestExportPolicyAndView(t *testing.T) {
+	c := initExportTest(t, WebObject{
+		FieldPolicies: []FieldPolicy{
+			{Field: "CreatedAt", CanRead: func(ctx *gin.Context) bool { return false }},
+		},
+		Views: []QueryView{
+			{
+				Name:   "names",
+				Method: http.MethodPost,
+				Prepare: func(db *gorm.DB, c *gin.Context) (*gorm.DB, *QueryForm, error) {
+					return db, &QueryForm{ViewFields: []string{"ID", "Name", "CreatedAt"}}, nil
+				},
+			},
+		},
+		BeforeRender: func(c *gin.Context, obj any) error {
+			obj.(*texport).Name += "!"
+			return nil
+		},
+	})
+
+	w := postExport(c, "/export/names", QueryForm{}, MimeCSV)
+	assert.Equal(t, http.StatusOK, w.Code)
+	records, err := csv.NewReader(w.Body).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, records, 201)
+	assert.Equal(t, []string{"id", "name"}, records[0])
+	assert.Equal(t, []string{"1", "item-000!"}, records[1])
+}
+
+func TestExportCSVFormula(t *testing.T) {
+	names := map[uint]string{1: "=HYPERLINK(\"x\")", 2: "@SUM(A1)", 3: "+1", 4: "-1", 5: "a=b"}
+	c := initExportTest(t, WebObject{
+		MaxExportRows: 5,
+		BeforeRender: func(c *gin.Context, obj any) error {
+			item := obj.(*texport)
+			item.Name = names[item.ID]
+			return nil
+		},
+	})
+
+	w := postExport(c, "/export", QueryForm{}, MimeCSV)
+	records, err := csv.NewReader(w.Body).ReadAll()
+	assert.Nil(t, err)
+	assert.Len(t, records, 6)
+	assert.Equal(t, "'=HYPERLINK(\"x\")", records[1][2])
+	assert.Equal(t, "'@SUM(A1)", records[2][2])
+	assert.Equal(t, "'+1", records[3][2])
+	assert.Equal(t, "'-1", records[4][2])
+	assert.Equal(t, "a=b", records[5][2])
+}
+
+func TestExportXLSX(t *testing.T) {
+	c := initExportTest(t, WebObject{MaxExportRows: 2})
+
+	w := postExport(c, "/export", map[string]any{"export": "xlsx"}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, MimeXLSX, w.Header().Get("Content-Type"))
+
+	body := w.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	assert.Nil(t, err)
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		assert.Nil(t, err)
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	assert.Contains(t, files, "[Content_Types].xml")
+	assert.Contains(t, files, "_rels/.rels")
+	assert.Contains(t, files, "xl/_rels/workbook.xml.rels")
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="export"`)
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<row><c t="inlineStr"><is><t xml:space="preserve">id</t></is></c>`)
+	assert.Contains(t, sheet, `<row><c><v>1</v></c><c t="inlineStr"><is><t xml:space="preserve">2023-03-17T08:30:00Z</t></is></c><c t="inlineStr"><is><t xml:space="preserve">item-000</t></is></c><c t="b"><v>1</v></c><c><v>9.5</v></c><c><v>1</v></c></row>`)
+	assert.Contains(t, sheet, `<c t="b"><v>0</v></c><c/>`)
+	assert.Contains(t, sheet, `</sheetData></worksheet>`)
+	assert.NotContains(t, sheet, "item-002")
+}
+
+func TestExportNDJSON(t *testing.T) {
+	rendered := 0
+	c := initExportTest(t, WebObject{
+		// not limited for streaming
+		MaxExportRows: 10,
+		BeforeRender: func(c *gin.Context, obj any) error {
+			rendered++
+			obj.(*texport).Name += "!"
+			return nil
+		},
+	})
+
+	w := postExport(c, "/export", QueryForm{}, MimeNDJSON)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, MimeNDJSON, w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+	assert.Empty(t, w.Header().Get(HeaderExportTruncated))
+	assert.Equal(t, 200, rendered)
+
+	lines := bytes.Split(bytes.TrimSpace(w.Body.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 200)
+
+	var item map[string]any
+	err := json.Unmarshal(lines[0], &item)
+	assert.Nil(t, err)
+	assert.Equal(t, "item-000!", item["name"])
+	assert.Equal(t, 9.5, item["score"])
+	assert.NotContains(t, item, "Secret")
+	// the preloads are rendered same as query
+	assert.Equal(t, "alice", item["owner"].(map[string]any)["name"])
+	assert.Nil(t, json.Unmarshal(lines[199], &item))
+	assert.Equal(t, "item-199!", item["name"])
+
+	var result QueryResult[[]map[string]any]
+	assert.Nil(t, c.CallPost("/export", QueryForm{Limit: 1}, &result))
+	item = result.Items[0]
+	assert.Nil(t, json.Unmarshal(lines[0], &result.Items[0]))
+	assert.Equal(t, item, result.Items[0])
+}
+
+func TestExportNDJSONCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rendered := 0
+	c := initExportTest(t, WebObject{
+		BeforeRender: func(c *gin.Context, obj any) error {
+			// the client is disconnected
+			if rendered++; rendered == 10 {
+				cancel()
+			}
+			return nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/export", bytes.NewReader([]byte(`{"export":"ndjson"}`)))
+	req = req.WithContext(ctx)
+	w := c.SendReq("/export", req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Less(t, rendered, 200)
+	assert.Equal(t, HeaderExportTruncated, w.Header().Get("Trailer"))
+	assert.Equal(t, "true", w.Result().Trailer.Get(HeaderExportTruncated))
+}
@@ -0,0 +1,428 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// object is the result of selection set, the fields are encoded in order.
+type object []objectField
+
+type objectField struct {
+	key   string
+	value any
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+type executor struct {
+	schema *schema
+	doc    *document
+	op     *operation
+	vars   map[string]any
+	errors []*Error
+
+	// the variables and fragments used by the operations, for validation
+	usedVars      map[string]bool
+	usedFragments map[string]bool
+}
+
+// Execute run the request, the errors of fields are returned with the data.
+func (gw *Gateway) Execute(c *gin.Context, req Request) Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return Response{Errors: []*Error{err.(*Error)}}
+	}
+
+	ex := &executor{schema: gw.schema, doc: doc}
+	if ex.validate(); len(ex.errors) > 0 {
+		return Response{Errors: ex.errors}
+	}
+	if ex.op, err = doc.operation(req.OperationName); err != nil {
+		return Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	root := gw.schema.root(ex.op.kind)
+	if ex.vars = ex.coerceVariables(req.Variables); len(ex.errors) > 0 {
+		return Response{Errors: ex.errors}
+	}
+
+	// the fields of mutation are resolved serially, so are the fields of query.
+	data := object{}
+	for _, group := range ex.collectFields(root, ex.op.selections) {
+		f := group.fields[0]
+		sf := ex.schema.field(root, f.name)
+		if f.name == "__typename" {
+			data = append(data, objectField{group.key, root.name})
+			continue
+		}
+
+		args, err := ex.coerceArgs(sf, f)
+		var value any
+		if err == nil {
+			value, err = sf.resolve(c, args)
+		}
+		if err != nil {
+			ex.addError(err, []any{group.key})
+			data = append(data, objectField{group.key, nil})
+			continue
+		}
+		data = append(data, objectField{group.key, ex.complete(sf.typ, value, group.selections())})
+	}
+	return Response{Data: data, Errors: ex.errors}
+}
+
+// operation return the operation of name, name is optional for the only one operation.
+func (doc *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, fmt.Errorf("operation name is required")
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %s", name)
+}
+
+func (ex *executor) addError(err error, path []any) {
+	e, ok := err.(*Error)
+	if !ok {
+		e = &Error{Message: err.Error()}
+	}
+	e.Path = path
+	ex.errors = append(ex.errors, e)
+}
+
+// fail add the error of validation, the same errors are added once,
+// such as the errors of fragment spread more than once.
+func (ex *executor) fail(format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	for _, e := range ex.errors {
+		if e.Message == message {
+			return
+		}
+	}
+	ex.errors = append(ex.errors, &Error{Message: message})
+}
+
+func (ex *executor) coerceVariables(values map[string]any) map[string]any {
+	vars := make(map[string]any)
+	for _, v := range ex.op.vars {
+		value, ok := values[v.name]
+		if !ok && v.hasDef {
+			value, ok = v.def, true
+		}
+		if !ok {
+			if v.typ.nonNull {
+				ex.fail("variable $%s is required", v.name)
+			}
+			continue
+		}
+		coerced, err := ex.coerceValue(v.typ, value)
+		if err != nil {
+			ex.fail("invalid variable $%s: %v", v.name, err)
+			continue
+		}
+		vars[v.name] = coerced
+	}
+	return vars
+}
+
+// coerceArgs return the values of arguments, the omitted arguments are not included.
+func (ex *executor) coerceArgs(sf *schemaField, f *field) (map[string]any, error) {
+	args := make(map[string]any)
+	for _, arg := range f.args {
+		if ref, ok := arg.value.(varRef); ok {
+			if _, ok := ex.vars[string(ref)]; !ok {
+				continue
+			}
+		}
+		value, err := ex.coerceValue(sf.arg(arg.name).typ, arg.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument %s: %v", arg.name, err)
+		}
+		args[arg.name] = value
+	}
+	for _, arg := range sf.args {
+		if _, ok := args[arg.name]; !ok && arg.typ.nonNull {
+			return nil, fmt.Errorf("argument %s is required", arg.name)
+		}
+	}
+	return args, nil
+}
+
+// coerceValue convert the literal or variable value to the Go value of type.
+func (ex *executor) coerceValue(t *typeRef, v any) (any, error) {
+	if ref, ok := v.(varRef); ok {
+		v = ex.vars[string(ref)]
+	}
+	if v == nil {
+		if t.nonNull {
+			return nil, fmt.Errorf("expected non-null %s", t)
+		}
+		return nil, nil
+	}
+
+	if t.elem != nil {
+		list, ok := v.([]any)
+		if !ok {
+			list = []any{v}
+		}
+		values := make([]any, len(list))
+		for i, e := range list {
+			value, err := ex.coerceValue(t.elem, e)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+
+	nt := ex.schema.types[t.name]
+	if nt.kind == kindEnum {
+		var name string
+		switch v := v.(type) {
+		case enumValue:
+			name = string(v)
+		case string:
+			name = v
+		}
+		for _, value := range nt.values {
+			if value == name {
+				return name, nil
+			}
+		}
+		return nil, fmt.Errorf("expected %s", t.name)
+	}
+	if nt.kind == kindInput {
+		fields := make(map[string]any)
+		switch v := v.(type) {
+		case objectValue:
+			for _, f := range v {
+				fields[f.name] = f.value
+			}
+		case map[string]any:
+			for k, e := range v {
+				fields[k] = e
+			}
+		default:
+			return nil, fmt.Errorf("expected %s", t.name)
+		}
+		values := make(map[string]any)
+		for k, e := range fields {
+			f, ok := nt.fieldMap[k]
+			if !ok {
+				return nil, fmt.Errorf("unknown field %s of %s", k, t.name)
+			}
+			value, err := ex.coerceValue(f.typ, e)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", k, err)
+			}
+			values[k] = value
+		}
+		for _, f := range nt.fields {
+			if _, ok := values[f.name]; !ok && f.typ.nonNull {
+				return nil, fmt.Errorf("field %s of %s is required", f.name, t.name)
+			}
+		}
+		return values, nil
+	}
+	return ex.coerceScalar(t.name, v)
+}
+
+func (ex *executor) coerceScalar(name string, v any) (any, error) {
+	switch name {
+	case "Int":
+		if n, ok := v.(json.Number); ok && !strings.ContainsAny(string(n), ".eE") {
+			return n, nil
+		}
+	case "Float":
+		if n, ok := v.(json.Number); ok {
+			return n, nil
+		}
+	case "String":
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case "Boolean":
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case "ID":
+		switch v := v.(type) {
+		case string:
+			return v, nil
+		case json.Number:
+			if !strings.ContainsAny(string(v), ".eE") {
+				return string(v), nil
+			}
+		}
+	case "JSON":
+		return ex.literal(v), nil
+	}
+	return nil, fmt.Errorf("expected %s", name)
+}
+
+// literal convert the value of JSON scalar to Go value.
+func (ex *executor) literal(v any) any {
+	switch v := v.(type) {
+	case varRef:
+		return ex.vars[string(v)]
+	case enumValue:
+		return string(v)
+	case []any:
+		values := make([]any, len(v))
+		for i, e := range v {
+			values[i] = ex.literal(e)
+		}
+		return values
+	case objectValue:
+		values := make(map[string]any)
+		for _, f := range v {
+			values[f.name] = ex.literal(f.value)
+		}
+		return values
+	}
+	return v
+}
+
+// fieldGroup is the fields of same response key
+type fieldGroup struct {
+	key    string
+	fields []*field
+}
+
+func (g *fieldGroup) selections() []*selection {
+	var selections []*selection
+	for _, f := range g.fields {
+		selections = append(selections, f.selections...)
+	}
+	return selections
+}
+
+// collectFields return the fields of selections in order, with the fragments and directives.
+func (ex *executor) collectFields(t *schemaType, selections []*selection) []*fieldGroup {
+	var groups []*fieldGroup
+	index := make(map[string]*fieldGroup)
+
+	// the fragment spread more than once is collected once
+	visited := make(map[string]bool)
+	var collect func(selections []*selection)
+	collect = func(selections []*selection) {
+		for _, s := range selections {
+			if !ex.included(s.directives) {
+				continue
+			}
+			switch {
+			case s.spread != "":
+				if !visited[s.spread] {
+					visited[s.spread] = true
+					collect(ex.doc.fragments[s.spread].selections)
+				}
+			case s.inline != nil:
+				collect(s.inline.selections)
+			default:
+				key := s.field.responseKey()
+				g, ok := index[key]
+				if !ok {
+					g = &fieldGroup{key: key}
+					index[key] = g
+					groups = append(groups, g)
+				}
+				g.fields = append(g.fields, s.field)
+			}
+		}
+	}
+	collect(selections)
+	return groups
+}
+
+// included check the @skip and @include directives.
+func (ex *executor) included(directives []*directive) bool {
+	for _, d := range directives {
+		for _, arg := range d.args {
+			if arg.name != "if" {
+				continue
+			}
+			v, _ := ex.literal(arg.value).(bool)
+			if (d.name == "skip" && v) || (d.name == "include" && !v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// complete select the fields of value (decoded JSON), according to the type.
+func (ex *executor) complete(t *typeRef, value any, selections []*selection) any {
+	if value == nil {
+		return nil
+	}
+	if t.elem != nil {
+		list, ok := value.([]any)
+		if !ok {
+			return nil
+		}
+		values := make([]any, len(list))
+		for i, v := range list {
+			values[i] = ex.complete(t.elem, v, selections)
+		}
+		return values
+	}
+
+	nt := ex.schema.types[t.name]
+	if nt.kind != kindObject {
+		return value
+	}
+	result := object{}
+	for _, group := range ex.collectFields(nt, selections) {
+		f := group.fields[0]
+		if f.name == "__typename" {
+			result = append(result, objectField{group.key, nt.name})
+			continue
+		}
+		sf := ex.schema.field(nt, f.name)
+		var v any
+		switch value := value.(type) {
+		case map[string]any:
+			v = value[f.name]
+		case lazyObject:
+			args, err := ex.coerceArgs(sf, f)
+			if err != nil {
+				ex.addError(err, nil)
+				break
+			}
+			v = value(f.name, args)
+		default:
+			return nil
+		}
+		result = append(result, objectField{group.key, ex.complete(sf.typ, v, group.selections())})
+	}
+	return result
+}
@@ -0,0 +1,386 @@
+// Package graphql serve a GraphQL schema generated from the registered WebObjects.
+//
+// Each object is a type of the model, with the fields of Query and Mutation:
+//
+//	user(id: ID!): User                                        // GET
+//	userList(pos, limit, keyword, filters, orders): UserList  // QUERY
+//	createUser(input: UserInput!): User                       // CREATE
+//	updateUser(id: ID!, input: UserUpdateInput!): Boolean     // EDIT, EditFields only
+//	deleteUser(id: ID!): Boolean                              // DELETE
+//
+// The fields are resolved by the actions of objects, such as WebObject.Get and WebObject.Query,
+// with the context of GraphQL request, so the whitelists, hooks, policies and tenants work
+// as the REST API. The rate limits and metrics of REST routes are not applied to the fields.
+// The introspection of __schema and __type is served for the tools such as GraphiQL,
+// and Gateway.Schema return the schema definition language. The documents are validated
+// by the rules of specification before executed, the invalid documents are responded with 400.
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/restsend/gormpher"
+)
+
+const defaultAllowMethods = gormpher.GET | gormpher.CREATE | gormpher.EDIT | gormpher.DELETE | gormpher.QUERY | gormpher.BATCH
+
+// Gateway is the gin handler of GraphQL, such as:
+//
+//	gormpher.RegisterObjects(r, objs)
+//	gw, _ := graphql.NewGateway(objs)
+//	r.POST("/graphql", gw.Handle)
+type Gateway struct {
+	schema *schema
+}
+
+// Request is the GraphQL request of POST body or GET query.
+type Request struct {
+	Query         string         `json:"query" form:"query"`
+	OperationName string         `json:"operationName" form:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Response is the GraphQL response, Data is nil when the request is invalid.
+type Response struct {
+	Data   any      `json:"data,omitempty"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// NewGateway build the schema of objs, the fields are resolved by the actions of objs.
+// The objects must be registered or built before, so the names are filled.
+func NewGateway(objs []gormpher.WebObject) (*Gateway, error) {
+	gw := &Gateway{schema: newSchema()}
+
+	types := make([]*schemaType, len(objs))
+	for i := range objs {
+		obj := &objs[i]
+		if obj.Name == "" {
+			return nil, errors.New("object without name, register it before")
+		}
+		t, err := gw.schema.reserveType(modelType(obj), typeName(obj.Name))
+		if err != nil {
+			return nil, err
+		}
+		types[i] = t
+	}
+
+	for i := range objs {
+		if err := gw.schema.fillType(types[i], modelType(&objs[i])); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range objs {
+		if err := gw.addObject(&objs[i], types[i]); err != nil {
+			return nil, err
+		}
+	}
+	return gw, nil
+}
+
+func modelType(obj *gormpher.WebObject) reflect.Type {
+	rt := reflect.TypeOf(obj.Model)
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	return rt
+}
+
+// addObject add the fields of Query and Mutation for obj.
+func (gw *Gateway) addObject(obj *gormpher.WebObject, t *schemaType) error {
+	allowMethods := obj.AllowMethods
+	if allowMethods == 0 {
+		allowMethods = defaultAllowMethods
+	}
+	name := lowerFirst(t.name)
+
+	addField := func(root *schemaType, name string, typ *typeRef) (*schemaField, error) {
+		if _, ok := root.fieldMap[name]; ok {
+			return nil, fmt.Errorf("duplicate field %s of %s", name, root.name)
+		}
+		return root.addField(name, typ), nil
+	}
+	addType := func(kind int, name string) (*schemaType, error) {
+		if _, ok := gw.schema.types[name]; ok {
+			return nil, fmt.Errorf("duplicate type %s", name)
+		}
+		return gw.schema.addType(kind, name), nil
+	}
+
+	if allowMethods&gormpher.GET != 0 {
+		f, err := addField(gw.schema.query, name, named(t.name, false))
+		if err != nil {
+			return err
+		}
+		f.addArg("id", named("ID", true))
+		f.resolve = func(c *gin.Context, args map[string]any) (any, error) {
+			return resolveValue(obj.Get(c, fmt.Sprint(args["id"])))
+		}
+	}
+
+	if allowMethods&gormpher.QUERY != 0 {
+		list, err := addType(kindObject, t.name+"List")
+		if err != nil {
+			return err
+		}
+		list.addField("total", named("Int", true))
+		list.addField("pos", named("Int", true))
+		list.addField("limit", named("Int", true))
+		list.addField("keyword", named("String", false))
+		list.addField("items", listOf(named(t.name, true), true))
+
+		f, err := addField(gw.schema.query, name+"List", named(list.name, false))
+		if err != nil {
+			return err
+		}
+		f.addArg("pos", named("Int", false))
+		f.addArg("limit", named("Int", false))
+		f.addArg("keyword", named("String", false))
+		f.addArg("filters", listOf(named("QueryFilter", true), false))
+		f.addArg("orders", listOf(named("QueryOrder", true), false))
+		f.resolve = func(c *gin.Context, args map[string]any) (any, error) {
+			return query(c, obj, args)
+		}
+	}
+
+	if allowMethods&gormpher.CREATE != 0 {
+		input, err := addType(kindInput, t.name+"Input")
+		if err != nil {
+			return err
+		}
+		gw.schema.fillInput(input, t, nil)
+
+		f, err := addField(gw.schema.mutation, "create"+t.name, named(t.name, false))
+		if err != nil {
+			return err
+		}
+		f.addArg("input", named(input.name, true))
+		f.resolve = func(c *gin.Context, args map[string]any) (any, error) {
+			vals, _ := jsonValue(args["input"]).(map[string]any)
+			return resolveValue(obj.Create(c, vals))
+		}
+	}
+
+	if allowMethods&gormpher.EDIT != 0 && len(obj.EditFields) > 0 {
+		rt := modelType(obj)
+		var editNames []string
+		for _, name := range obj.EditFields {
+			if f, ok := rt.FieldByName(name); ok {
+				editNames = append(editNames, jsonName(f))
+			}
+		}
+
+		input, err := addType(kindInput, t.name+"UpdateInput")
+		if err != nil {
+			return err
+		}
+		gw.schema.fillInput(input, t, editNames)
+
+		f, err := addField(gw.schema.mutation, "update"+t.name, named("Boolean", false))
+		if err != nil {
+			return err
+		}
+		f.addArg("id", named("ID", true))
+		f.addArg("input", named(input.name, true))
+		f.resolve = func(c *gin.Context, args map[string]any) (any, error) {
+			vals, _ := jsonValue(args["input"]).(map[string]any)
+			if err := obj.Update(c, fmt.Sprint(args["id"]), vals); err != nil {
+				return nil, resolveError(err)
+			}
+			return true, nil
+		}
+	}
+
+	if allowMethods&gormpher.DELETE != 0 {
+		f, err := addField(gw.schema.mutation, "delete"+t.name, named("Boolean", false))
+		if err != nil {
+			return err
+		}
+		f.addArg("id", named("ID", true))
+		f.resolve = func(c *gin.Context, args map[string]any) (any, error) {
+			if err := obj.Delete(c, fmt.Sprint(args["id"]), nil); err != nil {
+				return nil, resolveError(err)
+			}
+			return true, nil
+		}
+	}
+	return nil
+}
+
+func jsonName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// Schema return the schema definition language of gateway.
+func (gw *Gateway) Schema() string {
+	return gw.schema.SDL()
+}
+
+// Handle serve the GraphQL request of POST (application/json or application/graphql)
+// and GET, the mutation is not allowed by GET.
+func (gw *Gateway) Handle(c *gin.Context) {
+	var req Request
+	switch {
+	case c.Request.Method == http.MethodGet:
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if vars := c.Query("variables"); vars != "" {
+			if err := decodeJSON(strings.NewReader(vars), &req.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, Response{Errors: []*Error{{Message: "invalid variables"}}})
+				return
+			}
+		}
+	case c.ContentType() == "application/graphql":
+		body, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{Errors: []*Error{{Message: err.Error()}}})
+			return
+		}
+		req.Query = string(body)
+	default:
+		if err := decodeJSON(c.Request.Body, &req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{Errors: []*Error{{Message: "invalid request"}}})
+			return
+		}
+	}
+
+	if c.Request.Method == http.MethodGet && isMutation(req) {
+		c.JSON(http.StatusMethodNotAllowed, Response{Errors: []*Error{{Message: "mutation is not allowed by GET"}}})
+		return
+	}
+
+	resp := gw.Execute(c, req)
+	if resp.Data == nil {
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func isMutation(req Request) bool {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return false
+	}
+	op, err := doc.operation(req.OperationName)
+	return err == nil && op.kind == "mutation"
+}
+
+func decodeJSON(r io.Reader, v any) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// query resolve the list field by the query of obj.
+func query(c *gin.Context, obj *gormpher.WebObject, args map[string]any) (any, error) {
+	var form gormpher.QueryForm
+	if v, ok := args["pos"].(json.Number); ok {
+		pos, _ := v.Int64()
+		form.Pos = int(pos)
+	}
+	if v, ok := args["limit"].(json.Number); ok {
+		limit, _ := v.Int64()
+		form.Limit = int(limit)
+	}
+	form.Keyword, _ = args["keyword"].(string)
+
+	filters, _ := args["filters"].([]any)
+	for _, v := range filters {
+		m := v.(map[string]any)
+		filter := gormpher.Filter{Op: "=", Value: jsonValue(m["value"])}
+		filter.Name, _ = m["name"].(string)
+		if op, ok := m["op"].(string); ok {
+			filter.Op = op
+		}
+		form.Filters = append(form.Filters, filter)
+	}
+	orders, _ := args["orders"].([]any)
+	for _, v := range orders {
+		m := v.(map[string]any)
+		var order gormpher.Order
+		order.Name, _ = m["name"].(string)
+		order.Op, _ = m["op"].(string)
+		form.Orders = append(form.Orders, order)
+	}
+
+	r, err := resolveValue(obj.Query(c, form))
+	if err != nil {
+		return nil, err
+	}
+	result, ok := r.(map[string]any)
+	if !ok {
+		return nil, errors.New("invalid query result")
+	}
+	// the zero values are omitted by QueryResult
+	for _, k := range []string{"total", "pos", "limit"} {
+		if _, ok := result[k]; !ok {
+			result[k] = json.Number("0")
+		}
+	}
+	if result["items"] == nil {
+		result["items"] = []any{}
+	}
+	return result, nil
+}
+
+// resolveValue return the JSON value of the result of action, the fields are
+// selected from the JSON value, so the json tags and marshalers are respected.
+func resolveValue(v any, err error) (any, error) {
+	if err != nil {
+		return nil, resolveError(err)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := decodeJSON(bytes.NewReader(data), &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// resolveError return the error of action with the HTTP status code in extensions.
+func resolveError(err error) error {
+	var e *gormpher.ActionError
+	if errors.As(err, &e) {
+		return &Error{Message: e.Err.Error(), Extensions: map[string]any{"status": e.Code}}
+	}
+	return err
+}
+
+// jsonValue convert the numbers of value to float64, same as the JSON decoded
+// by encoding/json, the values of actions are checked by the kinds.
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case []any:
+		values := make([]any, len(v))
+		for i, e := range v {
+			values[i] = jsonValue(e)
+		}
+		return values
+	case map[string]any:
+		values := make(map[string]any, len(v))
+		for k, e := range v {
+			values[k] = jsonValue(e)
+		}
+		return values
+	}
+	return v
+}
@@ -0,0 +1,280 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/restsend/gormpher"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type tcompany struct {
+	ID   uint   `json:"id" gorm:"primarykey"`
+	Name string `json:"name"`
+}
+
+type tuser struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Name      string    `json:"name"`
+	Age       int       `json:"age"`
+	Enabled   bool      `json:"enabled"`
+	Password  string    `json:"-"`
+	CompanyID uint      `json:"companyId"`
+	Company   *tcompany `json:"company" gorm:"foreignKey:CompanyID"`
+}
+
+func initGatewayTest(t *testing.T) (*gin.Engine, *Gateway, *gorm.DB) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tcompany{}, tuser{})
+
+	db.Create(&tcompany{ID: 1, Name: "acme"})
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 9, CompanyID: 1})
+	db.Create(&tuser{ID: 2, Name: "bob", Age: 10, Enabled: true, CompanyID: 1})
+	db.Create(&tuser{ID: 3, Name: "clash", Age: 11})
+
+	r := gin.Default()
+	objs := []gormpher.WebObject{
+		{
+			Name:         "user",
+			Model:        tuser{},
+			GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			EditFields:   []string{"Name", "Enabled"},
+			FilterFields: []string{"Name", "Age", "Enabled"},
+			OrderFields:  []string{"ID", "Age"},
+			SearchFields: []string{"Name"},
+			BeforeCreate: func(ctx *gin.Context, vptr any, vals map[string]any) error {
+				if vptr.(*tuser).Name == "root" {
+					return errors.New("reserved name")
+				}
+				return nil
+			},
+		},
+		{
+			Name:         "company",
+			Model:        &tcompany{},
+			GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			AllowMethods: gormpher.GET | gormpher.QUERY,
+		},
+	}
+	gormpher.RegisterObjects(r, objs)
+
+	gw, err := NewGateway(objs)
+	assert.Nil(t, err)
+	r.POST("/graphql", gw.Handle)
+	r.GET("/graphql", gw.Handle)
+	return r, gw, db
+}
+
+func sendGraphQL(r http.Handler, query string, vars map[string]any) (int, map[string]any) {
+	body, _ := json.Marshal(Request{Query: query, Variables: vars})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var res map[string]any
+	json.Unmarshal(w.Body.Bytes(), &res)
+	return w.Code, res
+}
+
+func TestGatewaySchema(t *testing.T) {
+	_, gw, _ := initGatewayTest(t)
+	sdl := gw.Schema()
+
+	assert.Contains(t, sdl, "type Query {\n  user(id: ID!): User\n  userList(pos: Int, limit: Int, keyword: String, filters: [QueryFilter!], orders: [QueryOrder!]): UserList\n  company(id: ID!): Company\n")
+	assert.Contains(t, sdl, "type Mutation {\n  createUser(input: UserInput!): User\n  updateUser(id: ID!, input: UserUpdateInput!): Boolean\n  deleteUser(id: ID!): Boolean\n}")
+	assert.Contains(t, sdl, "type User {\n  id: Int\n  name: String\n  age: Int\n  enabled: Boolean\n  companyId: Int\n  company: Company\n}")
+	assert.Contains(t, sdl, "input UserUpdateInput {\n  name: String\n  enabled: Boolean\n}")
+	assert.Contains(t, sdl, "input UserInput {\n  id: Int\n  name: String\n  age: Int\n  enabled: Boolean\n  companyId: Int\n}")
+	assert.Contains(t, sdl, "type UserList {\n  total: Int!\n  pos: Int!\n  limit: Int!\n  keyword: String\n  items: [User!]!\n}")
+	assert.NotContains(t, sdl, "createCompany")
+	assert.NotContains(t, sdl, "Password")
+
+	// the object is not registered
+	_, err := NewGateway([]gormpher.WebObject{{Model: tuser{}}})
+	assert.NotNil(t, err)
+	_, err = NewGateway([]gormpher.WebObject{{Name: "user", Model: tuser{}}, {Name: "user", Model: tcompany{}}})
+	assert.NotNil(t, err)
+}
+
+func TestGatewayQuery(t *testing.T) {
+	r, _, _ := initGatewayTest(t)
+
+	code, res := sendGraphQL(r, `query Users($age: Int!, $limit: Int = 1) {
+		list: userList(filters: [{name: "age", op: ">=", value: $age}], orders: [{name: "age", op: "desc"}], limit: $limit) {
+			total
+			limit
+			items { ...userFields company { name } }
+		}
+		bob: user(id: 2) { __typename name company { id } }
+		acme: company(id: "1") { name }
+	}
+	fragment userFields on User { id name password: age }`, map[string]any{"age": 10})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Nil(t, res["errors"])
+
+	data := res["data"].(map[string]any)
+	list := data["list"].(map[string]any)
+	assert.Equal(t, float64(2), list["total"])
+	assert.Equal(t, float64(1), list["limit"])
+	assert.Equal(t, []any{map[string]any{"id": float64(3), "name": "clash", "password": float64(11), "company": nil}}, list["items"])
+	assert.Equal(t, map[string]any{"__typename": "User", "name": "bob", "company": map[string]any{"id": float64(1)}}, data["bob"])
+	assert.Equal(t, map[string]any{"name": "acme"}, data["acme"])
+
+	// keyword, skip and include
+	code, res = sendGraphQL(r, `query ($skip: Boolean!) {
+		userList(keyword: "li") { total items { name age @skip(if: $skip) enabled @include(if: $skip) } }
+	}`, map[string]any{"skip": true})
+	assert.Equal(t, http.StatusOK, code)
+	list = res["data"].(map[string]any)["userList"].(map[string]any)
+	assert.Equal(t, float64(1), list["total"])
+	assert.Equal(t, []any{map[string]any{"name": "alice", "enabled": false}}, list["items"])
+
+	// the errors of fields
+	code, res = sendGraphQL(r, `{ user(id: 100) { name } company(id: 1) { name } }`, nil)
+	assert.Equal(t, http.StatusOK, code)
+	data = res["data"].(map[string]any)
+	assert.Nil(t, data["user"])
+	assert.Equal(t, "acme", data["company"].(map[string]any)["name"])
+	errs := res["errors"].([]any)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "not found", errs[0].(map[string]any)["message"])
+	assert.Equal(t, []any{"user"}, errs[0].(map[string]any)["path"])
+	assert.Equal(t, float64(404), errs[0].(map[string]any)["extensions"].(map[string]any)["status"])
+
+	// GET
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(`{ userList { total } }`), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"userList":{"total":3}}}`, w.Body.String())
+}
+
+func TestGatewayMutation(t *testing.T) {
+	r, _, db := initGatewayTest(t)
+
+	code, res := sendGraphQL(r, `mutation ($input: UserInput!) {
+		created: createUser(input: $input) { id name age }
+		reserved: createUser(input: {name: "root"}) { id }
+	}`, map[string]any{"input": map[string]any{"name": "dave", "age": 20}})
+	assert.Equal(t, http.StatusOK, code)
+	data := res["data"].(map[string]any)
+	assert.Equal(t, map[string]any{"id": float64(4), "name": "dave", "age": float64(20)}, data["created"])
+	assert.Nil(t, data["reserved"])
+	assert.Equal(t, "reserved name", res["errors"].([]any)[0].(map[string]any)["message"])
+
+	code, res = sendGraphQL(r, `mutation {
+		updateUser(id: 4, input: {name: "dave2", enabled: true})
+		deleteUser(id: "3")
+	}`, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Nil(t, res["errors"])
+	assert.Equal(t, map[string]any{"updateUser": true, "deleteUser": true}, res["data"])
+
+	var user tuser
+	db.First(&user, 4)
+	assert.Equal(t, "dave2", user.Name)
+	assert.True(t, user.Enabled)
+	assert.Error(t, db.First(&tuser{}, 3).Error)
+
+	// age is not edit field
+	code, res = sendGraphQL(r, `mutation { updateUser(id: 4, input: {age: 30}) }`, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "invalid argument input of field updateUser: unknown field age of UserUpdateInput", res["errors"].([]any)[0].(map[string]any)["message"])
+
+	// the variable is validated when the request is executed
+	code, res = sendGraphQL(r, `mutation($input: UserUpdateInput!) { updateUser(id: 4, input: $input) }`, map[string]any{"input": map[string]any{"age": 30}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "invalid variable $input: unknown field age of UserUpdateInput", res["errors"].([]any)[0].(map[string]any)["message"])
+
+	// mutation is not allowed by GET
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(`mutation { deleteUser(id: 1) }`), nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Nil(t, db.First(&tuser{}, 1).Error)
+}
+
+func TestGatewayValidation(t *testing.T) {
+	r, _, _ := initGatewayTest(t)
+
+	for query, message := range map[string]string{
+		`{ user(id: 1) { unknown } }`:                             "cannot query field unknown on type User",
+		`{ user { name } }`:                                       "argument id of field user is required",
+		`{ user(id: 1, name: "x") { name } }`:                     "unknown argument name of field user",
+		`{ user(id: 1) }`:                                         "field user of type User must have a selection",
+		`{ user(id: 1) { name { id } } }`:                         "field name of type String must not have a selection",
+		`{ user(id: $id) { name } }`:                              "undefined variable $id",
+		`query ($id: ID!) { user(id: $id) { name } }`:             "variable $id is required",
+		`{ user(id: 1) { ...f } }`:                                "unknown fragment f",
+		`{ user(id: 1) { ...f } } fragment f on Company { name }`: "fragment f on Company cannot be spread on User",
+		`{ user(id: 1) { name @cached } }`:                        "unknown directive @cached",
+		`mutation { createCompany(input: {}) { id } }`:            "cannot query field createCompany on type Mutation",
+		`{ user(id: 1) { name }`:                                  "syntax error: expected \"}\" at 1:23",
+	} {
+		code, res := sendGraphQL(r, query, nil)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+		assert.Nil(t, res["data"], query)
+		assert.Equal(t, message, res["errors"].([]any)[0].(map[string]any)["message"], query)
+	}
+}
+
+func TestGatewayContext(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{})
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 9})
+	db.Create(&tuser{ID: 2, Name: "bob", Age: 10})
+
+	// the objects are resolved by actions, without the REST routes
+	objs := []gormpher.WebObject{{
+		Name:  "user",
+		Model: tuser{},
+		GetDB: func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+		Authorizer: gormpher.AuthorizerFunc(func(ctx *gin.Context, obj *gormpher.WebObject, action int, vptr any) error {
+			if u, ok := vptr.(*tuser); ok && u.Name != ctx.GetString("user") {
+				return errors.New("not owner")
+			}
+			return nil
+		}),
+		FieldPolicies: []gormpher.FieldPolicy{
+			{Field: "Age", CanRead: func(ctx *gin.Context) bool { return ctx.GetString("user") == "bob" }},
+		},
+	}}
+	assert.Nil(t, objs[0].Build())
+	gw, err := NewGateway(objs)
+	assert.Nil(t, err)
+
+	r := gin.New()
+	r.POST("/graphql", func(c *gin.Context) {
+		c.Set("user", c.GetHeader("X-User"))
+	}, gw.Handle)
+
+	send := func(user, query string) map[string]any {
+		body, _ := json.Marshal(Request{Query: query})
+		req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var res map[string]any
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return res
+	}
+
+	res := send("alice", `{ user(id: 1) { name age } other: user(id: 2) { name } }`)
+	assert.Equal(t, map[string]any{"user": map[string]any{"name": "alice", "age": nil}, "other": nil}, res["data"])
+	errs := res["errors"].([]any)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "not owner", errs[0].(map[string]any)["message"])
+	assert.Equal(t, float64(http.StatusForbidden), errs[0].(map[string]any)["extensions"].(map[string]any)["status"])
+
+	res = send("bob", `{ user(id: 2) { name age } userList { items { name age } } }`)
+	assert.Nil(t, res["errors"])
+	assert.Equal(t, map[string]any{"name": "bob", "age": float64(10)}, res["data"].(map[string]any)["user"])
+	assert.Len(t, res["data"].(map[string]any)["userList"].(map[string]any)["items"], 2)
+}
@@ -0,0 +1,270 @@
+package graphql
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// lazyObject is the object value resolved field by field, such as the
+// introspection types which reference each other.
+type lazyObject func(name string, args map[string]any) any
+
+var typeKinds = map[int]string{
+	kindScalar: "SCALAR",
+	kindObject: "OBJECT",
+	kindInput:  "INPUT_OBJECT",
+	kindEnum:   "ENUM",
+}
+
+// addIntrospection add the introspection types and the meta fields, see
+// https://spec.graphql.org/October2021/#sec-Schema-Introspection
+func (s *schema) addIntrospection() {
+	nonNullList := func(name string) *typeRef {
+		return listOf(named(name, true), true)
+	}
+	list := func(name string) *typeRef {
+		return listOf(named(name, true), false)
+	}
+	includeDeprecated := func(f *schemaField) {
+		f.addArg("includeDeprecated", named("Boolean", false)).def = "false"
+	}
+
+	kind := s.addType(kindEnum, "__TypeKind")
+	kind.values = []string{"SCALAR", "OBJECT", "INTERFACE", "UNION", "ENUM", "INPUT_OBJECT", "LIST", "NON_NULL"}
+
+	location := s.addType(kindEnum, "__DirectiveLocation")
+	location.values = []string{"QUERY", "MUTATION", "SUBSCRIPTION", "FIELD", "FRAGMENT_DEFINITION",
+		"FRAGMENT_SPREAD", "INLINE_FRAGMENT", "VARIABLE_DEFINITION", "SCHEMA", "SCALAR", "OBJECT",
+		"FIELD_DEFINITION", "ARGUMENT_DEFINITION", "INTERFACE", "UNION", "ENUM", "ENUM_VALUE",
+		"INPUT_OBJECT", "INPUT_FIELD_DEFINITION"}
+
+	schemaType := s.addType(kindObject, "__Schema")
+	schemaType.addField("description", named("String", false))
+	schemaType.addField("types", nonNullList("__Type"))
+	schemaType.addField("queryType", named("__Type", true))
+	schemaType.addField("mutationType", named("__Type", false))
+	schemaType.addField("subscriptionType", named("__Type", false))
+	schemaType.addField("directives", nonNullList("__Directive"))
+
+	typeType := s.addType(kindObject, "__Type")
+	typeType.addField("kind", named("__TypeKind", true))
+	typeType.addField("name", named("String", false))
+	typeType.addField("description", named("String", false))
+	typeType.addField("specifiedByURL", named("String", false))
+	includeDeprecated(typeType.addField("fields", list("__Field")))
+	typeType.addField("interfaces", list("__Type"))
+	typeType.addField("possibleTypes", list("__Type"))
+	includeDeprecated(typeType.addField("enumValues", list("__EnumValue")))
+	includeDeprecated(typeType.addField("inputFields", list("__InputValue")))
+	typeType.addField("ofType", named("__Type", false))
+	typeType.addField("isOneOf", named("Boolean", false))
+
+	field := s.addType(kindObject, "__Field")
+	field.addField("name", named("String", true))
+	field.addField("description", named("String", false))
+	includeDeprecated(field.addField("args", nonNullList("__InputValue")))
+	field.addField("type", named("__Type", true))
+	field.addField("isDeprecated", named("Boolean", true))
+	field.addField("deprecationReason", named("String", false))
+
+	input := s.addType(kindObject, "__InputValue")
+	input.addField("name", named("String", true))
+	input.addField("description", named("String", false))
+	input.addField("type", named("__Type", true))
+	input.addField("defaultValue", named("String", false))
+	input.addField("isDeprecated", named("Boolean", true))
+	input.addField("deprecationReason", named("String", false))
+
+	enum := s.addType(kindObject, "__EnumValue")
+	enum.addField("name", named("String", true))
+	enum.addField("description", named("String", false))
+	enum.addField("isDeprecated", named("Boolean", true))
+	enum.addField("deprecationReason", named("String", false))
+
+	directive := s.addType(kindObject, "__Directive")
+	directive.addField("name", named("String", true))
+	directive.addField("description", named("String", false))
+	directive.addField("locations", nonNullList("__DirectiveLocation"))
+	includeDeprecated(directive.addField("args", nonNullList("__InputValue")))
+	directive.addField("isRepeatable", named("Boolean", true))
+
+	s.metaFields = map[string]*schemaField{
+		"__typename": {name: "__typename", typ: named("String", true)},
+		"__schema": {name: "__schema", typ: named("__Schema", true), resolve: func(c *gin.Context, args map[string]any) (any, error) {
+			return s.introSchema(), nil
+		}},
+		"__type": {name: "__type", typ: named("__Type", false), resolve: func(c *gin.Context, args map[string]any) (any, error) {
+			t, ok := s.types[args["name"].(string)]
+			if !ok {
+				return nil, nil
+			}
+			return s.introNamed(t), nil
+		}},
+	}
+	s.metaFields["__type"].addArg("name", named("String", true))
+}
+
+func (s *schema) introSchema() lazyObject {
+	return func(name string, args map[string]any) any {
+		switch name {
+		case "types":
+			var types []any
+			for _, name := range s.typeNames() {
+				types = append(types, s.introNamed(s.types[name]))
+			}
+			return types
+		case "queryType":
+			return s.introNamed(s.query)
+		case "mutationType":
+			if len(s.mutation.fields) == 0 {
+				return nil
+			}
+			return s.introNamed(s.mutation)
+		case "directives":
+			var directives []any
+			for _, d := range s.directives {
+				directives = append(directives, s.introDirective(d))
+			}
+			return directives
+		}
+		return nil
+	}
+}
+
+// introType return the __Type of type reference, the wrapping types are NON_NULL and LIST.
+func (s *schema) introType(t *typeRef) lazyObject {
+	var kind string
+	var ofType lazyObject
+	switch {
+	case t.nonNull:
+		inner := *t
+		inner.nonNull = false
+		kind, ofType = "NON_NULL", s.introType(&inner)
+	case t.elem != nil:
+		kind, ofType = "LIST", s.introType(t.elem)
+	default:
+		return s.introNamed(s.types[t.name])
+	}
+	return func(name string, args map[string]any) any {
+		switch name {
+		case "kind":
+			return kind
+		case "ofType":
+			return ofType
+		}
+		return nil
+	}
+}
+
+// introNamed return the __Type of named type.
+func (s *schema) introNamed(t *schemaType) lazyObject {
+	return func(name string, args map[string]any) any {
+		switch name {
+		case "kind":
+			return typeKinds[t.kind]
+		case "name":
+			return t.name
+		case "fields":
+			if t.kind != kindObject {
+				return nil
+			}
+			fields := []any{}
+			for _, f := range t.fields {
+				fields = append(fields, s.introField(f))
+			}
+			return fields
+		case "interfaces":
+			if t.kind != kindObject {
+				return nil
+			}
+			return []any{}
+		case "enumValues":
+			if t.kind != kindEnum {
+				return nil
+			}
+			values := []any{}
+			for _, v := range t.values {
+				values = append(values, introEnumValue(v))
+			}
+			return values
+		case "inputFields":
+			if t.kind != kindInput {
+				return nil
+			}
+			return s.introInputValues(t.fields)
+		case "isOneOf":
+			if t.kind != kindInput {
+				return nil
+			}
+			return false
+		}
+		return nil
+	}
+}
+
+func (s *schema) introField(f *schemaField) lazyObject {
+	return func(name string, args map[string]any) any {
+		switch name {
+		case "name":
+			return f.name
+		case "args":
+			return s.introInputValues(f.args)
+		case "type":
+			return s.introType(f.typ)
+		case "isDeprecated":
+			return false
+		}
+		return nil
+	}
+}
+
+func (s *schema) introInputValues(fields []*schemaField) []any {
+	values := []any{}
+	for _, f := range fields {
+		f := f
+		values = append(values, lazyObject(func(name string, args map[string]any) any {
+			switch name {
+			case "name":
+				return f.name
+			case "type":
+				return s.introType(f.typ)
+			case "defaultValue":
+				if f.def == "" {
+					return nil
+				}
+				return f.def
+			case "isDeprecated":
+				return false
+			}
+			return nil
+		}))
+	}
+	return values
+}
+
+func introEnumValue(value string) lazyObject {
+	return func(name string, args map[string]any) any {
+		switch name {
+		case "name":
+			return value
+		case "isDeprecated":
+			return false
+		}
+		return nil
+	}
+}
+
+func (s *schema) introDirective(d *schemaField) lazyObject {
+	return func(name string, args map[string]any) any {
+		switch name {
+		case "name":
+			return d.name
+		case "locations":
+			return []any{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"}
+		case "args":
+			return s.introInputValues(d.args)
+		case "isRepeatable":
+			return false
+		}
+		return nil
+	}
+}
@@ -0,0 +1,185 @@
+package graphql
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// introspectionQuery is the query of graphql-js getIntrospectionQuery with all options.
+const introspectionQuery = `
+query IntrospectionQuery {
+  __schema {
+    description
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+    directives {
+      name
+      description
+      isRepeatable
+      locations
+      args(includeDeprecated: true) { ...InputValue }
+    }
+  }
+}
+
+fragment FullType on __Type {
+  kind
+  name
+  description
+  specifiedByURL
+  isOneOf
+  fields(includeDeprecated: true) {
+    name
+    description
+    args(includeDeprecated: true) { ...InputValue }
+    type { ...TypeRef }
+    isDeprecated
+    deprecationReason
+  }
+  inputFields(includeDeprecated: true) { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) {
+    name
+    description
+    isDeprecated
+    deprecationReason
+  }
+  possibleTypes { ...TypeRef }
+}
+
+fragment InputValue on __InputValue {
+  name
+  description
+  type { ...TypeRef }
+  defaultValue
+  isDeprecated
+  deprecationReason
+}
+
+fragment TypeRef on __Type {
+  kind
+  name
+  ofType {
+    kind
+    name
+    ofType {
+      kind
+      name
+      ofType {
+        kind
+        name
+      }
+    }
+  }
+}`
+
+func TestIntrospection(t *testing.T) {
+	r, _, _ := initGatewayTest(t)
+
+	code, res := sendGraphQL(r, introspectionQuery, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Nil(t, res["errors"])
+
+	schema := res["data"].(map[string]any)["__schema"].(map[string]any)
+	assert.Equal(t, map[string]any{"name": "Query"}, schema["queryType"])
+	assert.Equal(t, map[string]any{"name": "Mutation"}, schema["mutationType"])
+	assert.Nil(t, schema["subscriptionType"])
+
+	types := make(map[string]map[string]any)
+	for _, v := range schema["types"].([]any) {
+		types[v.(map[string]any)["name"].(string)] = v.(map[string]any)
+	}
+	for _, name := range []string{"Int", "String", "JSON", "User", "UserList", "UserInput", "Company", "QueryFilter", "__Schema", "__Type", "__TypeKind"} {
+		assert.Contains(t, types, name)
+	}
+	assert.Equal(t, "SCALAR", types["JSON"]["kind"])
+	assert.Equal(t, "INPUT_OBJECT", types["UserInput"]["kind"])
+	assert.Equal(t, false, types["UserInput"]["isOneOf"])
+	assert.Nil(t, types["UserInput"]["fields"])
+	assert.Equal(t, "ENUM", types["__TypeKind"]["kind"])
+	assert.Contains(t, types["__TypeKind"]["enumValues"], map[string]any{"name": "NON_NULL", "description": nil, "isDeprecated": false, "deprecationReason": nil})
+
+	fields := make(map[string]any)
+	for _, v := range types["UserList"]["fields"].([]any) {
+		fields[v.(map[string]any)["name"].(string)] = v.(map[string]any)["type"]
+	}
+	// [User!]!
+	assert.Equal(t, map[string]any{"kind": "NON_NULL", "name": nil, "ofType": map[string]any{
+		"kind": "LIST", "name": nil, "ofType": map[string]any{
+			"kind": "NON_NULL", "name": nil, "ofType": map[string]any{"kind": "OBJECT", "name": "User"},
+		},
+	}}, fields["items"])
+
+	// the arguments of Query fields
+	var userList map[string]any
+	for _, v := range types["Query"]["fields"].([]any) {
+		if v.(map[string]any)["name"] == "userList" {
+			userList = v.(map[string]any)
+		}
+	}
+	args := userList["args"].([]any)
+	assert.Len(t, args, 5)
+	assert.Equal(t, "filters", args[3].(map[string]any)["name"])
+	assert.Equal(t, "LIST", args[3].(map[string]any)["type"].(map[string]any)["kind"])
+
+	directives := schema["directives"].([]any)
+	assert.Len(t, directives, 2)
+	assert.Equal(t, map[string]any{
+		"name":         "skip",
+		"description":  nil,
+		"isRepeatable": false,
+		"locations":    []any{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		"args": []any{map[string]any{
+			"name": "if", "description": nil, "defaultValue": nil, "isDeprecated": false, "deprecationReason": nil,
+			"type": map[string]any{"kind": "NON_NULL", "name": nil, "ofType": map[string]any{"kind": "SCALAR", "name": "Boolean", "ofType": nil}},
+		}},
+	}, directives[0])
+
+	// __type and __typename
+	code, res = sendGraphQL(r, `{
+		company: __type(name: "Company") { __typename kind name fields { name type { name } } }
+		unknown: __type(name: "Unknown") { name }
+		__typename
+	}`, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Nil(t, res["errors"])
+	assert.Equal(t, map[string]any{
+		"company": map[string]any{"__typename": "__Type", "kind": "OBJECT", "name": "Company", "fields": []any{
+			map[string]any{"name": "id", "type": map[string]any{"name": "Int"}},
+			map[string]any{"name": "name", "type": map[string]any{"name": "String"}},
+		}},
+		"unknown":    nil,
+		"__typename": "Query",
+	}, res["data"])
+
+	// the default value of includeDeprecated
+	code, res = sendGraphQL(r, `{ __type(name: "__Type") { fields { name args { name defaultValue } } } }`, nil)
+	assert.Equal(t, http.StatusOK, code)
+	for _, v := range res["data"].(map[string]any)["__type"].(map[string]any)["fields"].([]any) {
+		if v.(map[string]any)["name"] == "fields" {
+			assert.Equal(t, []any{map[string]any{"name": "includeDeprecated", "defaultValue": "false"}}, v.(map[string]any)["args"])
+		}
+	}
+
+	for query, message := range map[string]string{
+		`mutation { __schema { types { name } } }`:          "cannot query field __schema on type Mutation",
+		`{ user(id: 1) { __type(name: "User") { name } } }`: "cannot query field __type on type User",
+		`{ __type { name } }`:                               "argument name of field __type is required",
+		`{ __schema { types } }`:                            "field types of type __Type must have a selection",
+		`{ __typename(x: 1) }`:                              "unknown argument x of field __typename",
+	} {
+		code, res := sendGraphQL(r, query, nil)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+		assert.Equal(t, message, res["errors"].([]any)[0].(map[string]any)["message"], query)
+	}
+}
+
+func TestIntrospectionSchema(t *testing.T) {
+	_, gw, _ := initGatewayTest(t)
+	// the introspection types are not in the schema definition language
+	assert.NotContains(t, gw.Schema(), "__")
+}
@@ -0,0 +1,536 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// the executable subset of GraphQL document: operations, fragments, variables and directives.
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string // "query" or "mutation"
+	name       string
+	vars       []*varDef
+	directives []*directive
+	selections []*selection
+}
+
+type varDef struct {
+	name   string
+	typ    *typeRef
+	def    any
+	hasDef bool
+}
+
+type typeRef struct {
+	name    string
+	elem    *typeRef // for list
+	nonNull bool
+}
+
+type fragment struct {
+	name       string
+	on         string
+	directives []*directive
+	selections []*selection
+}
+
+// selection is one of field, fragment spread or inline fragment
+type selection struct {
+	field      *field
+	spread     string
+	inline     *fragment
+	directives []*directive
+}
+
+type field struct {
+	alias      string
+	name       string
+	args       []*argument
+	selections []*selection
+}
+
+type argument struct {
+	name  string
+	value any
+}
+
+type directive struct {
+	name string
+	args []*argument
+}
+
+// the value of literal is one of nil, bool, string, json.Number, []any,
+// objectValue, varRef and enumValue.
+type (
+	varRef      string
+	enumValue   string
+	objectValue []*argument
+)
+
+func (t *typeRef) String() string {
+	var s string
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	} else {
+		s = t.name
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// Error is the error of GraphQL response.
+type Error struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind  int
+	value string
+	pos   int
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+func parse(src string) (doc *document, err error) {
+	p := &parser{src: src}
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			doc, err = nil, e
+		}
+	}()
+	p.next()
+	doc = p.parseDocument()
+	return doc, nil
+}
+
+func (p *parser) fail(format string, args ...any) {
+	line, col := 1, 1
+	for _, r := range p.src[:p.tok.pos] {
+		if r == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	panic(&Error{Message: fmt.Sprintf("syntax error: %s at %d:%d", fmt.Sprintf(format, args...), line, col)})
+}
+
+// next read the next token, the commas, spaces and comments are ignored.
+func (p *parser) next() {
+	for p.pos < len(p.src) {
+		ch := p.src[p.pos]
+		if ch == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' && p.src[p.pos] != '\r' {
+				p.pos++
+			}
+			continue
+		}
+		if ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == ',' {
+			p.pos++
+			continue
+		}
+		if strings.HasPrefix(p.src[p.pos:], "\ufeff") {
+			p.pos += len("\ufeff")
+			continue
+		}
+		break
+	}
+
+	start := p.pos
+	p.tok = token{pos: start}
+	if p.pos >= len(p.src) {
+		p.tok.kind = tokEOF
+		return
+	}
+
+	ch := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		p.tok.kind, p.tok.value = tokPunct, "..."
+	case strings.IndexByte("!$&()=:@[]{}|", ch) >= 0:
+		p.pos++
+		p.tok.kind, p.tok.value = tokPunct, string(ch)
+	case ch == '_' || isLetter(ch):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isLetter(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		p.tok.kind, p.tok.value = tokName, p.src[start:p.pos]
+	case ch == '-' || isDigit(ch):
+		p.readNumber()
+	case ch == '"':
+		p.readString()
+	default:
+		p.fail("unexpected character %q", ch)
+	}
+}
+
+func (p *parser) readNumber() {
+	start := p.pos
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	digits := func() int {
+		n := 0
+		for p.pos < len(p.src) && isDigit(p.src[p.pos]) {
+			p.pos++
+			n++
+		}
+		return n
+	}
+	if digits() == 0 {
+		p.fail("invalid number")
+	}
+	kind := tokInt
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		p.pos++
+		if digits() == 0 {
+			p.fail("invalid number")
+		}
+		kind = tokFloat
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		if digits() == 0 {
+			p.fail("invalid number")
+		}
+		kind = tokFloat
+	}
+	p.tok.kind, p.tok.value = kind, p.src[start:p.pos]
+}
+
+func (p *parser) readString() {
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		end := strings.Index(p.src[p.pos+3:], `"""`)
+		if end < 0 {
+			p.fail("unterminated string")
+		}
+		s := p.src[p.pos+3 : p.pos+3+end]
+		p.pos += end + 6
+		p.tok.kind, p.tok.value = tokString, strings.TrimSpace(s)
+		return
+	}
+
+	var sb strings.Builder
+	p.pos++
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' || p.src[p.pos] == '\r' {
+			p.fail("unterminated string")
+		}
+		ch := p.src[p.pos]
+		if ch == '"' {
+			p.pos++
+			break
+		}
+		if ch != '\\' {
+			r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+			sb.WriteRune(r)
+			p.pos += size
+			continue
+		}
+		if p.pos+1 >= len(p.src) {
+			p.fail("unterminated string")
+		}
+		switch esc := p.src[p.pos+1]; esc {
+		case '"', '\\', '/':
+			sb.WriteByte(esc)
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'u':
+			if p.pos+6 > len(p.src) {
+				p.fail("invalid escape")
+			}
+			r, err := strconv.ParseUint(p.src[p.pos+2:p.pos+6], 16, 32)
+			if err != nil {
+				p.fail("invalid escape")
+			}
+			sb.WriteRune(rune(r))
+			p.pos += 4
+		default:
+			p.fail("invalid escape \\%c", esc)
+		}
+		p.pos += 2
+	}
+	p.tok.kind, p.tok.value = tokString, sb.String()
+}
+
+func isLetter(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokPunct && p.tok.value == punct
+}
+
+func (p *parser) skip(punct string) bool {
+	if p.peek(punct) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(punct string) {
+	if !p.skip(punct) {
+		p.fail("expected %q", punct)
+	}
+}
+
+func (p *parser) name() string {
+	if p.tok.kind != tokName {
+		p.fail("expected name")
+	}
+	name := p.tok.value
+	p.next()
+	return name
+}
+
+func (p *parser) parseDocument() *document {
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokEOF {
+		if p.peek("{") {
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: p.parseSelections()})
+			continue
+		}
+		if p.tok.kind != tokName {
+			p.fail("expected definition")
+		}
+		switch p.tok.value {
+		case "query", "mutation":
+			doc.operations = append(doc.operations, p.parseOperation())
+		case "fragment":
+			p.next()
+			f := &fragment{name: p.name()}
+			if f.name == "on" {
+				p.fail("invalid fragment name")
+			}
+			if p.name() != "on" {
+				p.fail(`expected "on"`)
+			}
+			f.on = p.name()
+			f.directives = p.parseDirectives()
+			f.selections = p.parseSelections()
+			if _, ok := doc.fragments[f.name]; ok {
+				p.fail("duplicate fragment %s", f.name)
+			}
+			doc.fragments[f.name] = f
+		default:
+			p.fail("unsupported definition %s", p.tok.value)
+		}
+	}
+	if len(doc.operations) == 0 {
+		p.fail("no operation")
+	}
+	return doc
+}
+
+func (p *parser) parseOperation() *operation {
+	op := &operation{kind: p.name()}
+	if p.tok.kind == tokName {
+		op.name = p.name()
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			p.expect("$")
+			v := &varDef{name: p.name()}
+			p.expect(":")
+			v.typ = p.parseType()
+			if p.skip("=") {
+				v.def, v.hasDef = p.parseValue(true), true
+			}
+			op.vars = append(op.vars, v)
+		}
+	}
+	op.directives = p.parseDirectives()
+	op.selections = p.parseSelections()
+	return op
+}
+
+func (p *parser) parseType() *typeRef {
+	t := &typeRef{}
+	if p.skip("[") {
+		t.elem = p.parseType()
+		p.expect("]")
+	} else {
+		t.name = p.name()
+	}
+	t.nonNull = p.skip("!")
+	return t
+}
+
+func (p *parser) parseSelections() []*selection {
+	p.expect("{")
+	var selections []*selection
+	for !p.skip("}") {
+		if p.tok.kind == tokEOF {
+			p.fail("expected %q", "}")
+		}
+		selections = append(selections, p.parseSelection())
+	}
+	if len(selections) == 0 {
+		p.fail("empty selection")
+	}
+	return selections
+}
+
+func (p *parser) parseSelection() *selection {
+	if p.skip("...") {
+		if p.tok.kind == tokName && p.tok.value != "on" {
+			s := &selection{spread: p.name()}
+			s.directives = p.parseDirectives()
+			return s
+		}
+		f := &fragment{}
+		if p.tok.kind == tokName {
+			p.next()
+			f.on = p.name()
+		}
+		s := &selection{inline: f}
+		s.directives = p.parseDirectives()
+		f.selections = p.parseSelections()
+		return s
+	}
+
+	f := &field{name: p.name()}
+	if p.skip(":") {
+		f.alias, f.name = f.name, p.name()
+	}
+	f.args = p.parseArguments(false)
+	s := &selection{field: f}
+	s.directives = p.parseDirectives()
+	if p.peek("{") {
+		f.selections = p.parseSelections()
+	}
+	return s
+}
+
+func (p *parser) parseArguments(constant bool) []*argument {
+	var args []*argument
+	if p.skip("(") {
+		for !p.skip(")") {
+			arg := &argument{name: p.name()}
+			p.expect(":")
+			arg.value = p.parseValue(constant)
+			args = append(args, arg)
+		}
+	}
+	return args
+}
+
+func (p *parser) parseDirectives() []*directive {
+	var directives []*directive
+	for p.skip("@") {
+		d := &directive{name: p.name()}
+		d.args = p.parseArguments(false)
+		directives = append(directives, d)
+	}
+	return directives
+}
+
+func (p *parser) parseValue(constant bool) any {
+	tok := p.tok
+	switch tok.kind {
+	case tokPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				p.fail("unexpected variable")
+			}
+			p.next()
+			return varRef(p.name())
+		case "[":
+			p.next()
+			list := []any{}
+			for !p.skip("]") {
+				list = append(list, p.parseValue(constant))
+			}
+			return list
+		case "{":
+			p.next()
+			obj := objectValue{}
+			for !p.skip("}") {
+				arg := &argument{name: p.name()}
+				p.expect(":")
+				arg.value = p.parseValue(constant)
+				obj = append(obj, arg)
+			}
+			return obj
+		}
+	case tokInt, tokFloat:
+		p.next()
+		return json.Number(tok.value)
+	case tokString:
+		p.next()
+		return tok.value
+	case tokName:
+		p.next()
+		switch tok.value {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return enumValue(tok.value)
+	}
+	p.fail("unexpected %q", tok.value)
+	return nil
+}
@@ -0,0 +1,74 @@
+package graphql
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	doc, err := parse(`
+	# comment
+	query Users($limit: Int = 10, $names: [String!]!) {
+		list: userList(limit: $limit, filters: [{name: "name", op: "in", value: $names}]) {
+			total
+			items { ...userFields @include(if: true) }
+		}
+		user(id: "1") { ... on User { name } }
+	}
+
+	fragment userFields on User {
+		id, name
+	}`)
+	assert.Nil(t, err)
+	assert.Len(t, doc.operations, 1)
+
+	op := doc.operations[0]
+	assert.Equal(t, "query", op.kind)
+	assert.Equal(t, "Users", op.name)
+	assert.Len(t, op.vars, 2)
+	assert.Equal(t, json.Number("10"), op.vars[0].def)
+	assert.Equal(t, "[String!]!", op.vars[1].typ.String())
+
+	list := op.selections[0].field
+	assert.Equal(t, "list", list.responseKey())
+	assert.Equal(t, "userList", list.name)
+	assert.Equal(t, varRef("limit"), list.args[0].value)
+	filter := list.args[1].value.([]any)[0].(objectValue)
+	assert.Equal(t, "in", filter[1].value)
+	assert.Equal(t, varRef("names"), filter[2].value)
+
+	items := list.selections[1]
+	assert.Equal(t, "userFields", items.field.selections[0].spread)
+	assert.Equal(t, "include", items.field.selections[0].directives[0].name)
+
+	user := op.selections[1].field
+	assert.Equal(t, "User", user.selections[0].inline.on)
+	assert.Len(t, doc.fragments["userFields"].selections, 2)
+
+	doc, err = parse(`{ a(s: "x\"中\n", f: -1.5e3, e: ENUM, n: null, b: """ block """) }`)
+	assert.Nil(t, err)
+	args := doc.operations[0].selections[0].field.args
+	assert.Equal(t, "x\"中\n", args[0].value)
+	assert.Equal(t, json.Number("-1.5e3"), args[1].value)
+	assert.Equal(t, enumValue("ENUM"), args[2].value)
+	assert.Nil(t, args[3].value)
+	assert.Equal(t, "block", args[4].value)
+
+	for _, src := range []string{
+		``,
+		`{ }`,
+		`{ user(id: 1 }`,
+		`{ a(s: "unterminated) }`,
+		`subscription { a }`,
+		`query ($a: Int = $b) { a }`,
+		`{ a } fragment f on T { a } fragment f on T { b }`,
+	} {
+		_, err := parse(src)
+		assert.NotNil(t, err, src)
+	}
+
+	_, err = parse("{\n  a(b: %) }")
+	assert.Equal(t, `syntax error: unexpected character '%' at 2:8`, err.Error())
+}
@@ -0,0 +1,377 @@
+package graphql
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Type kind
+const (
+	kindScalar = iota
+	kindObject
+	kindInput
+	kindEnum
+)
+
+var builtinScalars = []string{"Int", "Float", "String", "Boolean", "ID", "JSON"}
+
+type schemaType struct {
+	kind     int
+	name     string
+	fields   []*schemaField
+	fieldMap map[string]*schemaField
+	values   []string // for enum
+}
+
+type resolveFunc func(c *gin.Context, args map[string]any) (any, error)
+
+type schemaField struct {
+	name    string
+	typ     *typeRef
+	args    []*schemaField
+	def     string      // the default value of argument, such as "false"
+	resolve resolveFunc // for the fields of Query and Mutation
+}
+
+type schema struct {
+	types    map[string]*schemaType
+	query    *schemaType
+	mutation *schemaType
+
+	// the meta fields of introspection and the directives of executor,
+	// the locations of directives are FIELD, FRAGMENT_SPREAD and INLINE_FRAGMENT.
+	metaFields map[string]*schemaField
+	directives []*schemaField
+
+	// the generated object types of Go types
+	goTypes map[reflect.Type]string
+}
+
+func newSchema() *schema {
+	s := &schema{
+		types:   make(map[string]*schemaType),
+		goTypes: make(map[reflect.Type]string),
+	}
+	for _, name := range builtinScalars {
+		s.types[name] = &schemaType{kind: kindScalar, name: name}
+	}
+	s.query = s.addType(kindObject, "Query")
+	s.mutation = s.addType(kindObject, "Mutation")
+
+	filter := s.addType(kindInput, "QueryFilter")
+	filter.addField("name", named("String", true))
+	filter.addField("op", named("String", false))
+	filter.addField("value", named("JSON", false))
+
+	order := s.addType(kindInput, "QueryOrder")
+	order.addField("name", named("String", true))
+	order.addField("op", named("String", false))
+
+	for _, name := range []string{"skip", "include"} {
+		d := &schemaField{name: name}
+		d.addArg("if", named("Boolean", true))
+		s.directives = append(s.directives, d)
+	}
+	s.addIntrospection()
+	return s
+}
+
+// field return the field of type t, with the meta fields __typename of all
+// object types, __schema and __type of Query.
+func (s *schema) field(t *schemaType, name string) *schemaField {
+	if f, ok := s.metaFields[name]; ok && (name == "__typename" || t == s.query) {
+		return f
+	}
+	return t.fieldMap[name]
+}
+
+// root return the root type of operation, Query or Mutation.
+func (s *schema) root(kind string) *schemaType {
+	if kind == "mutation" {
+		return s.mutation
+	}
+	return s.query
+}
+
+func (s *schema) directive(name string) *schemaField {
+	for _, d := range s.directives {
+		if d.name == name {
+			return d
+		}
+	}
+	return nil
+}
+
+// typeNames return the names of types in order, the empty types are excluded, such as
+// Mutation of the read only objects.
+func (s *schema) typeNames() []string {
+	var names []string
+	for name, t := range s.types {
+		if (t.kind == kindObject || t.kind == kindInput) && len(t.fields) == 0 {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *schema) addType(kind int, name string) *schemaType {
+	t := &schemaType{kind: kind, name: name, fieldMap: make(map[string]*schemaField)}
+	s.types[name] = t
+	return t
+}
+
+func (t *schemaType) addField(name string, typ *typeRef) *schemaField {
+	f := &schemaField{name: name, typ: typ}
+	t.fields = append(t.fields, f)
+	t.fieldMap[name] = f
+	return f
+}
+
+func (f *schemaField) addArg(name string, typ *typeRef) *schemaField {
+	arg := &schemaField{name: name, typ: typ}
+	f.args = append(f.args, arg)
+	return arg
+}
+
+func (f *schemaField) arg(name string) *schemaField {
+	for _, arg := range f.args {
+		if arg.name == name {
+			return arg
+		}
+	}
+	return nil
+}
+
+func named(name string, nonNull bool) *typeRef {
+	return &typeRef{name: name, nonNull: nonNull}
+}
+
+func listOf(elem *typeRef, nonNull bool) *typeRef {
+	return &typeRef{elem: elem, nonNull: nonNull}
+}
+
+// namedType return the type name of list element.
+func (t *typeRef) namedType() string {
+	for t.elem != nil {
+		t = t.elem
+	}
+	return t.name
+}
+
+// typeName convert the object name to GraphQL type name, such as "user_profile" => "UserProfile".
+func typeName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var sb strings.Builder
+	for _, part := range parts {
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return sb.String()
+}
+
+// lowerFirst return the field name of type name, such as "UserProfile" => "userProfile".
+func lowerFirst(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// modelField is the json field of model
+type modelField struct {
+	name string
+	typ  reflect.Type
+}
+
+// modelFields return the json fields of struct, the embedded structs are squashed as encoding/json.
+func modelFields(rt reflect.Type) []modelField {
+	var fields []modelField
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		jsonTag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.Anonymous && jsonTag == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, modelFields(ft)...)
+				continue
+			}
+		}
+		if !f.IsExported() || jsonTag == "-" {
+			continue
+		}
+		if jsonTag == "" {
+			jsonTag = f.Name
+		}
+		fields = append(fields, modelField{name: jsonTag, typ: f.Type})
+	}
+	return fields
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	valuerType    = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// isCustomJSON check the type is encoded by itself, such as sql.NullString, gorm.DeletedAt.
+func isCustomJSON(rt reflect.Type) bool {
+	return rt.Implements(valuerType) || rt.Implements(marshalerType) ||
+		reflect.PtrTo(rt).Implements(marshalerType)
+}
+
+// fieldType return the output type of Go type, the structs are generated as object types.
+func (s *schema) fieldType(rt reflect.Type) (*typeRef, error) {
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	switch {
+	case rt == timeType:
+		return named("String", false), nil
+	case isCustomJSON(rt):
+		return named("JSON", false), nil
+	}
+
+	switch rt.Kind() {
+	case reflect.Bool:
+		return named("Boolean", false), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return named("Int", false), nil
+	case reflect.Float32, reflect.Float64:
+		return named("Float", false), nil
+	case reflect.String:
+		return named("String", false), nil
+	case reflect.Slice, reflect.Array:
+		if rt.Elem().Kind() == reflect.Uint8 {
+			return named("String", false), nil // base64 of []byte
+		}
+		elem, err := s.fieldType(rt.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return listOf(elem, false), nil
+	case reflect.Struct:
+		if rt.Name() == "" {
+			return named("JSON", false), nil
+		}
+		name, err := s.objectType(rt)
+		if err != nil {
+			return nil, err
+		}
+		return named(name, false), nil
+	}
+	return named("JSON", false), nil
+}
+
+// objectType return the object type of struct, it's generated with the Go type name when not exists.
+func (s *schema) objectType(rt reflect.Type) (string, error) {
+	if name, ok := s.goTypes[rt]; ok {
+		return name, nil
+	}
+	t, err := s.reserveType(rt, typeName(rt.Name()))
+	if err != nil {
+		return "", err
+	}
+	return t.name, s.fillType(t, rt)
+}
+
+// reserveType add the empty object type of struct, so the struct is referenced
+// before the fields are filled, such as the models of objects.
+func (s *schema) reserveType(rt reflect.Type, name string) (*schemaType, error) {
+	if _, ok := s.types[name]; ok {
+		return nil, fmt.Errorf("duplicate type %s", name)
+	}
+	s.goTypes[rt] = name
+	return s.addType(kindObject, name), nil
+}
+
+func (s *schema) fillType(t *schemaType, rt reflect.Type) error {
+	for _, f := range modelFields(rt) {
+		typ, err := s.fieldType(f.typ)
+		if err != nil {
+			return err
+		}
+		t.addField(f.name, typ)
+	}
+	return nil
+}
+
+// fillInput add the scalar fields of object type to input type,
+// only the names are included when not nil.
+func (s *schema) fillInput(t *schemaType, object *schemaType, names []string) {
+	for _, f := range object.fields {
+		if names != nil && !contains(names, f.name) {
+			continue
+		}
+		if s.types[f.typ.namedType()].kind != kindScalar {
+			continue
+		}
+		t.addField(f.name, f.typ)
+	}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SDL return the schema in GraphQL schema definition language.
+func (s *schema) SDL() string {
+	var sb strings.Builder
+	sb.WriteString("scalar JSON\n")
+
+	writeType := func(t *schemaType) {
+		if len(t.fields) == 0 {
+			return
+		}
+		keyword := "type"
+		if t.kind == kindInput {
+			keyword = "input"
+		}
+		fmt.Fprintf(&sb, "\n%s %s {\n", keyword, t.name)
+		for _, f := range t.fields {
+			sb.WriteString("  " + f.name)
+			if len(f.args) > 0 {
+				var args []string
+				for _, arg := range f.args {
+					args = append(args, arg.name+": "+arg.typ.String())
+				}
+				sb.WriteString("(" + strings.Join(args, ", ") + ")")
+			}
+			sb.WriteString(": " + f.typ.String() + "\n")
+		}
+		sb.WriteString("}\n")
+	}
+
+	writeType(s.query)
+	writeType(s.mutation)
+
+	// the introspection types are not included
+	for _, name := range s.typeNames() {
+		t := s.types[name]
+		if t.kind == kindObject || t.kind == kindInput {
+			if t != s.query && t != s.mutation && !strings.HasPrefix(name, "__") {
+				writeType(t)
+			}
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,393 @@
+package graphql
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// the validation of document, see https://spec.graphql.org/October2021/#sec-Validation
+// The rules of subscriptions, interfaces and unions are not included.
+
+// validate check the operations and fragments of document against the schema.
+func (ex *executor) validate() {
+	names := make(map[string]bool)
+	ex.usedFragments = make(map[string]bool)
+	for _, op := range ex.doc.operations {
+		if op.name == "" && len(ex.doc.operations) > 1 {
+			ex.fail("anonymous operation must be the only operation")
+		}
+		if op.name != "" && names[op.name] {
+			ex.fail("duplicate operation %s", op.name)
+		}
+		names[op.name] = true
+		ex.validateOperation(op)
+	}
+
+	var fragments []string
+	for name := range ex.doc.fragments {
+		fragments = append(fragments, name)
+	}
+	sort.Strings(fragments)
+	for _, name := range fragments {
+		frag := ex.doc.fragments[name]
+		ex.validateDirectives(frag.directives, "fragment definition", nil)
+		if !ex.usedFragments[name] {
+			ex.fail("fragment %s is not used", name)
+		}
+	}
+
+	// the fields are merged after the fragments and fields are valid
+	if len(ex.errors) == 0 {
+		for _, op := range ex.doc.operations {
+			ex.validateMerge(ex.schema.root(op.kind), op.selections)
+		}
+	}
+}
+
+func (ex *executor) validateOperation(op *operation) {
+	root := ex.schema.root(op.kind)
+	if len(root.fields) == 0 {
+		ex.fail("schema does not support %s", op.kind)
+		return
+	}
+
+	vars := make(map[string]*varDef)
+	for _, v := range op.vars {
+		if _, ok := vars[v.name]; ok {
+			ex.fail("duplicate variable $%s", v.name)
+		}
+		if t, ok := ex.schema.types[v.typ.namedType()]; !ok || t.kind == kindObject {
+			ex.fail("invalid type %s of variable $%s", v.typ, v.name)
+		}
+		vars[v.name] = v
+	}
+
+	ex.usedVars = make(map[string]bool)
+	ex.validateDirectives(op.directives, op.kind, vars)
+	ex.validateSelections(root, op.selections, vars, map[string]bool{})
+	for _, v := range op.vars {
+		if !ex.usedVars[v.name] {
+			ex.fail("variable $%s is not used", v.name)
+		}
+	}
+}
+
+func (ex *executor) validateSelections(t *schemaType, selections []*selection, vars map[string]*varDef, visiting map[string]bool) {
+	for _, s := range selections {
+		ex.validateDirectives(s.directives, "", vars)
+
+		switch {
+		case s.spread != "":
+			frag, ok := ex.doc.fragments[s.spread]
+			if !ok {
+				ex.fail("unknown fragment %s", s.spread)
+				continue
+			}
+			ex.usedFragments[frag.name] = true
+			if !ex.validateCondition(frag.name, frag.on) {
+				continue
+			}
+			if frag.on != t.name {
+				ex.fail("fragment %s on %s cannot be spread on %s", frag.name, frag.on, t.name)
+				continue
+			}
+			if visiting[frag.name] {
+				ex.fail("fragment %s is cyclic", frag.name)
+				continue
+			}
+			visiting[frag.name] = true
+			ex.validateSelections(t, frag.selections, vars, visiting)
+			delete(visiting, frag.name)
+		case s.inline != nil:
+			if s.inline.on != "" {
+				if !ex.validateCondition("", s.inline.on) {
+					continue
+				}
+				if s.inline.on != t.name {
+					ex.fail("fragment on %s cannot be spread on %s", s.inline.on, t.name)
+					continue
+				}
+			}
+			ex.validateSelections(t, s.inline.selections, vars, visiting)
+		default:
+			ex.validateField(t, s.field, vars, visiting)
+		}
+	}
+}
+
+// validateCondition check the type condition of fragment is an object type.
+func (ex *executor) validateCondition(name, on string) bool {
+	t, ok := ex.schema.types[on]
+	if !ok {
+		ex.fail("unknown type %s", on)
+		return false
+	}
+	if t.kind != kindObject {
+		if name == "" {
+			ex.fail("fragment cannot condition on non composite type %s", on)
+		} else {
+			ex.fail("fragment %s cannot condition on non composite type %s", name, on)
+		}
+		return false
+	}
+	return true
+}
+
+func (ex *executor) validateField(t *schemaType, f *field, vars map[string]*varDef, visiting map[string]bool) {
+	sf := ex.schema.field(t, f.name)
+	if sf == nil {
+		ex.fail("cannot query field %s on type %s", f.name, t.name)
+		return
+	}
+
+	ex.validateArgs(sf, "field "+f.name, f.args, vars)
+
+	ft := ex.schema.types[sf.typ.namedType()]
+	if ft.kind == kindObject {
+		if len(f.selections) == 0 {
+			ex.fail("field %s of type %s must have a selection", f.name, ft.name)
+			return
+		}
+		ex.validateSelections(ft, f.selections, vars, visiting)
+	} else if len(f.selections) > 0 {
+		ex.fail("field %s of type %s must not have a selection", f.name, ft.name)
+	}
+}
+
+// validateDirectives check the directives and the arguments of directives, location is
+// not empty for the operations and fragment definitions, where @skip and @include are
+// not allowed.
+func (ex *executor) validateDirectives(directives []*directive, location string, vars map[string]*varDef) {
+	used := make(map[string]bool)
+	for _, d := range directives {
+		sd := ex.schema.directive(d.name)
+		if sd == nil {
+			ex.fail("unknown directive @%s", d.name)
+			continue
+		}
+		if location != "" {
+			ex.fail("directive @%s is not allowed on %s", d.name, location)
+			continue
+		}
+		if used[d.name] {
+			ex.fail("directive @%s is used more than once", d.name)
+		}
+		used[d.name] = true
+		ex.validateArgs(sd, "directive @"+d.name, d.args, vars)
+	}
+}
+
+// validateArgs check the arguments are defined and unique, the required arguments are
+// provided, and the values are valid for the types of arguments.
+func (ex *executor) validateArgs(sf *schemaField, of string, args []*argument, vars map[string]*varDef) {
+	names := make(map[string]bool)
+	for _, arg := range args {
+		if names[arg.name] {
+			ex.fail("duplicate argument %s of %s", arg.name, of)
+		}
+		names[arg.name] = true
+
+		def := sf.arg(arg.name)
+		if def == nil {
+			ex.fail("unknown argument %s of %s", arg.name, of)
+			continue
+		}
+		if err := ex.validateValue(def.typ, def.def != "", arg.value, vars); err != nil {
+			ex.fail("invalid argument %s of %s: %v", arg.name, of, err)
+		}
+	}
+	for _, arg := range sf.args {
+		if arg.typ.nonNull && arg.def == "" && !names[arg.name] {
+			ex.fail("argument %s of %s is required", arg.name, of)
+		}
+	}
+}
+
+// validateValue check the literal value is valid for t, and the variables of value are
+// defined with the compatible types. hasDef is true when the location has a default value.
+func (ex *executor) validateValue(t *typeRef, hasDef bool, v any, vars map[string]*varDef) error {
+	if ref, ok := v.(varRef); ok {
+		ex.usedVars[string(ref)] = true
+		vd, ok := vars[string(ref)]
+		if !ok {
+			ex.fail("undefined variable $%s", ref)
+			return nil
+		}
+		if !typeAllowed(vd.typ, vd.hasDef && vd.def != nil || hasDef, t) {
+			return fmt.Errorf("variable $%s of type %s cannot be used as %s", ref, vd.typ, t)
+		}
+		return nil
+	}
+	if v == nil {
+		if t.nonNull {
+			return fmt.Errorf("expected non-null %s", t)
+		}
+		return nil
+	}
+
+	if t.elem != nil {
+		list, ok := v.([]any)
+		if !ok {
+			list = []any{v}
+		}
+		for _, e := range list {
+			if err := ex.validateValue(t.elem, false, e, vars); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	nt := ex.schema.types[t.name]
+	switch {
+	case nt.kind == kindInput:
+		fields, ok := v.(objectValue)
+		if !ok {
+			return fmt.Errorf("expected %s", t.name)
+		}
+		names := make(map[string]bool)
+		for _, f := range fields {
+			if names[f.name] {
+				return fmt.Errorf("duplicate field %s of %s", f.name, t.name)
+			}
+			names[f.name] = true
+			sf, ok := nt.fieldMap[f.name]
+			if !ok {
+				return fmt.Errorf("unknown field %s of %s", f.name, t.name)
+			}
+			if err := ex.validateValue(sf.typ, false, f.value, vars); err != nil {
+				return fmt.Errorf("%s: %v", f.name, err)
+			}
+		}
+		for _, f := range nt.fields {
+			if f.typ.nonNull && !names[f.name] {
+				return fmt.Errorf("field %s of %s is required", f.name, t.name)
+			}
+		}
+		return nil
+	case t.name == "JSON":
+		// the variables in JSON literal are used without the types
+		var walk func(v any)
+		walk = func(v any) {
+			switch v := v.(type) {
+			case varRef:
+				ex.validateValue(&typeRef{name: "JSON"}, false, v, vars)
+			case []any:
+				for _, e := range v {
+					walk(e)
+				}
+			case objectValue:
+				for _, f := range v {
+					walk(f.value)
+				}
+			}
+		}
+		walk(v)
+		return nil
+	}
+	_, err := ex.coerceValue(t, v)
+	return err
+}
+
+// typeAllowed check the variable of type vt can be used at the location of type t,
+// the nullable variable is allowed for the non-null location with a default value.
+func typeAllowed(vt *typeRef, hasDef bool, t *typeRef) bool {
+	if t.nonNull && !vt.nonNull {
+		if !hasDef {
+			return false
+		}
+		inner := *t
+		inner.nonNull = false
+		t = &inner
+	}
+	return typeCompatible(vt, t)
+}
+
+func typeCompatible(vt, t *typeRef) bool {
+	if t.nonNull && !vt.nonNull {
+		return false
+	}
+	if t.elem != nil {
+		return vt.elem != nil && typeCompatible(vt.elem, t.elem)
+	}
+	if vt.elem != nil {
+		return false
+	}
+	// JSON accepts the variables of any type
+	return vt.name == t.name || t.name == "JSON"
+}
+
+// validateMerge check the fields of same response key can be merged, they are the same
+// field with the same arguments, and so are the sub-selections of them.
+func (ex *executor) validateMerge(t *schemaType, selections []*selection) {
+	var groups []*fieldGroup
+	index := make(map[string]*fieldGroup)
+	visited := make(map[string]bool)
+
+	var collect func(selections []*selection)
+	collect = func(selections []*selection) {
+		for _, s := range selections {
+			switch {
+			case s.spread != "":
+				if !visited[s.spread] {
+					visited[s.spread] = true
+					collect(ex.doc.fragments[s.spread].selections)
+				}
+			case s.inline != nil:
+				collect(s.inline.selections)
+			default:
+				key := s.field.responseKey()
+				g, ok := index[key]
+				if !ok {
+					g = &fieldGroup{key: key}
+					index[key] = g
+					groups = append(groups, g)
+				}
+				g.fields = append(g.fields, s.field)
+			}
+		}
+	}
+	collect(selections)
+
+	for _, g := range groups {
+		first := g.fields[0]
+		merged := true
+		for _, f := range g.fields[1:] {
+			if f.name != first.name {
+				ex.fail("fields %s conflict because %s and %s are different fields", g.key, first.name, f.name)
+				merged = false
+				break
+			}
+			if !sameArgs(first.args, f.args) {
+				ex.fail("fields %s conflict because they have different arguments", g.key)
+				merged = false
+				break
+			}
+		}
+		if !merged {
+			continue
+		}
+		sf := ex.schema.field(t, first.name)
+		if ft := ex.schema.types[sf.typ.namedType()]; ft.kind == kindObject {
+			ex.validateMerge(ft, g.selections())
+		}
+	}
+}
+
+func sameArgs(a, b []*argument) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	values := make(map[string]any)
+	for _, arg := range a {
+		values[arg.name] = arg.value
+	}
+	for _, arg := range b {
+		v, ok := values[arg.name]
+		if !ok || !reflect.DeepEqual(v, arg.value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package graphql
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateDocument(t *testing.T) {
+	r, _, _ := initGatewayTest(t)
+
+	for query, message := range map[string]string{
+		// operations
+		`{ user(id: 1) { name } } query q { user(id: 2) { name } }`:                     "anonymous operation must be the only operation",
+		`query q { user(id: 1) { name } } query q { user(id: 2) { name } }`:             "duplicate operation q",
+		`query @skip(if: true) { user(id: 1) { name } }`:                                "directive @skip is not allowed on query",
+		`query ($id: ID!, $age: Int) { user(id: $id) { name } }`:                        "variable $age is not used",
+		`query ($id: ID!, $id: ID!) { user(id: $id) { name } }`:                         "duplicate variable $id",
+		`query ($u: User) { user(id: 1) { name } }`:                                     "invalid type User of variable $u",
+		`{ user(id: 1, id: 2) { name } }`:                                               "duplicate argument id of field user",
+		`{ user(id: 1) { name @skip(if: true) @skip(if: false) } }`:                     "directive @skip is used more than once",
+		`{ user(id: 1) { name @include } }`:                                             "argument if of directive @include is required",
+		`{ user(id: null) { name } }`:                                                   "invalid argument id of field user: expected non-null ID!",
+		`{ userList(limit: "10") { total } }`:                                           "invalid argument limit of field userList: expected Int",
+		`{ userList(filters: [{op: "="}]) { total } }`:                                  "invalid argument filters of field userList: field name of QueryFilter is required",
+		`{ userList(orders: {name: "id", name: "age"}) { total } }`:                     "invalid argument orders of field userList: duplicate field name of QueryOrder",
+		`query ($limit: String) { userList(limit: $limit) { total } }`:                  "invalid argument limit of field userList: variable $limit of type String cannot be used as Int",
+		`query ($id: ID) { user(id: $id) { name } }`:                                    "invalid argument id of field user: variable $id of type ID cannot be used as ID!",
+		`query ($names: [String]) { userList(filters: $names) { total } }`:              "invalid argument filters of field userList: variable $names of type [String] cannot be used as [QueryFilter!]",
+		`query ($f: QueryFilter) { userList(filters: [$f]) { total } }`:                 "invalid argument filters of field userList: variable $f of type QueryFilter cannot be used as QueryFilter!",
+		`query ($v: Int) { userList(filters: [{name: "age", value: [$w]}]) { total } }`: "undefined variable $w",
+
+		// fragments
+		`{ user(id: 1) { name } } fragment f on User { name }`:                             "fragment f is not used",
+		`{ user(id: 1) { ...f } } fragment f on Unknown { name }`:                          "unknown type Unknown",
+		`{ user(id: 1) { ... on Unknown { name } } }`:                                      "unknown type Unknown",
+		`{ user(id: 1) { ...f } } fragment f on String { name }`:                           "fragment f cannot condition on non composite type String",
+		`{ user(id: 1) { ... on QueryFilter { name } } }`:                                  "fragment cannot condition on non composite type QueryFilter",
+		`{ user(id: 1) { ...f } } fragment f on User { ...g } fragment g on User { ...f }`: "fragment f is cyclic",
+		`{ user(id: 1) { ...f } } fragment f on User @skip(if: true) { name }`:             "directive @skip is not allowed on fragment definition",
+
+		// fields merging
+		`{ user(id: 1) { n: name n: age } }`:                                                    "fields n conflict because name and age are different fields",
+		`{ user(id: 1) { name } user(id: 2) { name } }`:                                         "fields user conflict because they have different arguments",
+		`{ user(id: 1) { ...f company { name: id } } } fragment f on User { company { name } }`: "fields name conflict because name and id are different fields",
+	} {
+		code, res := sendGraphQL(r, query, nil)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+		assert.Nil(t, res["data"], query)
+		if assert.NotNil(t, res["errors"], query) {
+			assert.Equal(t, message, res["errors"].([]any)[0].(map[string]any)["message"], query)
+		}
+	}
+
+	// the errors are reported once
+	code, res := sendGraphQL(r, `{ a: user(id: 1) { ...f } b: user(id: 2) { ...f } } fragment f on User { unknown }`, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Len(t, res["errors"], 1)
+}
+
+func TestValidateValid(t *testing.T) {
+	r, _, _ := initGatewayTest(t)
+
+	for query, vars := range map[string]map[string]any{
+		// the same fields are merged, and the fragment spread twice is collected once
+		`{ user(id: 1) { name ...f ...f company { id } } } fragment f on User { name company { name } }`: nil,
+		// the nullable variable with default value is used as non-null
+		`query ($id: ID = 1) { user(id: $id) { name } }`: nil,
+		// the variables of JSON are not typed
+		`query ($age: Int) { userList(filters: [{name: "age", op: "=", value: $age}]) { total } }`: {"age": 9},
+	} {
+		code, res := sendGraphQL(r, query, vars)
+		assert.Equal(t, http.StatusOK, code, query)
+		assert.Nil(t, res["errors"], query)
+	}
+}
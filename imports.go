@@ -0,0 +1,456 @@
+package gormpher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mitchellh/mapstructure"
+	"gorm.io/gorm"
+)
+
+// Import format
+const (
+	ImportCSV    = "csv"
+	ImportNDJSON = "ndjson"
+)
+
+// Import mode
+const (
+	ImportCreate = "create" // create every row
+	ImportUpsert = "upsert" // update the row when the primary key exists, otherwise create
+)
+
+const (
+	DefaultMaxImportRows = 10000
+	DefaultMaxImportSize = 32 << 20
+)
+
+const maxImportLineSize = 1024 * 1024
+
+var (
+	errImportFormat   = errors.New("invalid import format")
+	errImportMode     = errors.New("invalid import mode")
+	errImportTooLarge = errors.New("import too large")
+)
+
+// ImportError is the error of a line, the line of CSV header is 1.
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportResult is the report of import, nothing is written when Errors is not empty.
+type ImportResult struct {
+	Total   int           `json:"total"`
+	Created int           `json:"created"`
+	Updated int           `json:"updated"`
+	DryRun  bool          `json:"dryRun,omitempty"`
+	Errors  []ImportError `json:"errors,omitempty"`
+}
+
+type importRow struct {
+	line    int
+	vals    map[string]any // json name => value
+	action  int            // CREATE or EDIT
+	val     any            // the decoded object for create, the reloaded object for edit
+	old     any            // the loaded object for edit
+	updates map[string]any // field name => value for edit
+}
+
+// handleImportObjects import the rows of CSV or NDJSON upload, the query options:
+//
+//	format: "csv" or "ndjson", detected by file extension or Content-Type when empty
+//	mode: "create" (default) or "upsert"
+//	dryRun: validate the rows without writing
+//
+// The upload is the body or the "file" of multipart form, up to MaxImportSize and
+// MaxImportRows. The rows are written in one transaction, 422 with the per-line
+// errors is returned when any row fails.
+func handleImportObjects(c *gin.Context, obj *WebObject) {
+	mode := c.DefaultQuery("mode", ImportCreate)
+	if mode != ImportCreate && mode != ImportUpsert {
+		handleError(c, http.StatusBadRequest, errImportMode)
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.Query("dryRun"))
+
+	if !obj.authorize(c, IMPORT, nil) {
+		return
+	}
+
+	db, err := obj.getDB(c, EDIT)
+	if err != nil {
+		handleError(c, http.StatusForbidden, err)
+		return
+	}
+
+	var tenant any
+	if obj.TenantField != "" {
+		if tenant, err = obj.getTenant(c); err != nil {
+			handleError(c, http.StatusForbidden, err)
+			return
+		}
+	}
+
+	maxSize := obj.MaxImportSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxImportSize
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize)
+
+	r, format, err := getImportReader(c)
+	if err != nil {
+		handleImportReadError(c, err)
+		return
+	}
+	defer r.Close()
+
+	maxRows := obj.MaxImportRows
+	if maxRows <= 0 {
+		maxRows = DefaultMaxImportRows
+	}
+	var rows []importRow
+	var lineErrors []ImportError
+	if format == ImportCSV {
+		rows, err = obj.readCSVRows(r, maxRows)
+	} else {
+		rows, lineErrors, err = readNDJSONRows(r, maxRows)
+	}
+	if err != nil {
+		handleImportReadError(c, err)
+		return
+	}
+
+	result := ImportResult{
+		Total:  len(rows) + len(lineErrors),
+		DryRun: dryRun,
+		Errors: lineErrors,
+	}
+	for i := range rows {
+		row := &rows[i]
+		if err := obj.prepareImport(c, db, row, mode, tenant, format == ImportCSV); err != nil {
+			result.Errors = append(result.Errors, ImportError{Line: row.line, Error: err.Error()})
+			continue
+		}
+		if row.action == CREATE {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		result.Created, result.Updated = 0, 0
+		sort.Slice(result.Errors, func(i, j int) bool {
+			return result.Errors[i].Line < result.Errors[j].Line
+		})
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	// every row is written in a savepoint, so all the failed rows are reported.
+	err = obj.openDB(c, IMPORT).Transaction(func(tx *gorm.DB) error {
+		for i := range rows {
+			row := &rows[i]
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				return obj.writeImport(c, tx, row)
+			})
+			if err != nil {
+				result.Errors = append(result.Errors, ImportError{Line: row.line, Error: err.Error()})
+			}
+		}
+		if len(result.Errors) > 0 {
+			return errors.New("import failed")
+		}
+		return nil
+	})
+	if err != nil {
+		if len(result.Errors) == 0 {
+			handleError(c, http.StatusInternalServerError, err)
+			return
+		}
+		result.Created, result.Updated = 0, 0
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+
+	obj.invalidateCache()
+	for i := range rows {
+		obj.notify(c, rows[i].action, rows[i].old, rows[i].val)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// handleImportReadError abort with 413 when the upload is over limits, otherwise 400.
+func handleImportReadError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || errors.Is(err, errImportTooLarge) {
+		handleError(c, http.StatusRequestEntityTooLarge, errImportTooLarge)
+		return
+	}
+	handleError(c, http.StatusBadRequest, err)
+}
+
+// getImportReader return the upload and format.
+func getImportReader(c *gin.Context) (io.ReadCloser, string, error) {
+	format := strings.ToLower(c.Query("format"))
+	contentType := c.ContentType()
+	r := c.Request.Body
+
+	if contentType == "multipart/form-data" {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", err
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		r = f
+		switch strings.ToLower(path.Ext(fh.Filename)) {
+		case ".csv":
+			contentType = MimeCSV
+		case ".ndjson", ".jsonl":
+			contentType = MimeNDJSON
+		default:
+			contentType = fh.Header.Get("Content-Type")
+		}
+	}
+
+	if format == "" {
+		switch {
+		case strings.HasPrefix(contentType, MimeCSV):
+			format = ImportCSV
+		case strings.HasPrefix(contentType, MimeNDJSON),
+			strings.HasPrefix(contentType, "application/jsonl"):
+			format = ImportNDJSON
+		}
+	}
+	if format != ImportCSV && format != ImportNDJSON {
+		r.Close()
+		return nil, "", errImportFormat
+	}
+	return r, format, nil
+}
+
+// readCSVRows read the rows of CSV up to maxRows, the header is the json names of columns.
+// The empty cells are skipped, so the fields keep zero value.
+func (obj *WebObject) readCSVRows(r io.Reader, maxRows int) ([]importRow, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range header {
+		name = strings.TrimPrefix(strings.TrimSpace(name), "\ufeff") // BOM of Excel
+		if _, ok := obj.jsonToFields[name]; !ok {
+			return nil, errors.New("unknown column " + name)
+		}
+		header[i] = name
+	}
+
+	var rows []importRow
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) >= maxRows {
+			return nil, errImportTooLarge
+		}
+		line, _ := cr.FieldPos(0)
+		vals := make(map[string]any)
+		for i, v := range record {
+			if v != "" {
+				vals[header[i]] = v
+			}
+		}
+		rows = append(rows, importRow{line: line, vals: vals})
+	}
+	return rows, nil
+}
+
+// readNDJSONRows read the rows of NDJSON up to maxRows, the invalid lines are returned as errors.
+func readNDJSONRows(r io.Reader, maxRows int) ([]importRow, []ImportError, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLineSize)
+
+	var rows []importRow
+	var lineErrors []ImportError
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if len(rows)+len(lineErrors) >= maxRows {
+			return nil, nil, errImportTooLarge
+		}
+		var vals map[string]any
+		if err := json.Unmarshal(data, &vals); err != nil {
+			lineErrors = append(lineErrors, ImportError{Line: line, Error: err.Error()})
+			continue
+		}
+		rows = append(rows, importRow{line: line, vals: vals})
+	}
+	return rows, lineErrors, scanner.Err()
+}
+
+// prepareImport check and decode the row, the existing object is loaded for upsert.
+// weak is for the string values of CSV.
+func (obj *WebObject) prepareImport(c *gin.Context, db *gorm.DB, row *importRow, mode string, tenant any, weak bool) error {
+	if err := obj.checkWritable(c, row.vals); err != nil {
+		return err
+	}
+	if obj.TenantField != "" {
+		if err := obj.checkTenant(row.vals, tenant); err != nil {
+			return err
+		}
+	}
+
+	row.action = CREATE
+	if key, ok := row.vals[obj.jsonPKName]; ok && mode == ImportUpsert {
+		f, _ := obj.modelElem.FieldByName(obj.jsonToFields[obj.jsonPKName])
+		rv, err := convertValue(key, f.Type)
+		if err != nil {
+			return err
+		}
+		old := reflect.New(obj.modelElem).Interface()
+		err = db.Where(obj.gormPKName, rv.Interface()).Take(old).Error
+		if err == nil {
+			row.action = EDIT
+			row.old = old
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	val := reflect.New(obj.modelElem).Interface()
+	if err := obj.decodeImport(row.vals, val, weak); err != nil {
+		return err
+	}
+
+	if row.action == CREATE {
+		if obj.TenantField != "" {
+			obj.setTenant(val, tenant)
+		}
+		if obj.Authorizer != nil {
+			if err := obj.Authorizer.Authorize(c, obj, CREATE, val); err != nil {
+				return err
+			}
+		}
+		if obj.BeforeCreate != nil {
+			if err := obj.beforeCreate(c, val, row.vals); err != nil {
+				return err
+			}
+		}
+		row.val = val
+		return nil
+	}
+
+	// update the edit fields only, as handleUpdateObject
+	editFields := make(map[string]struct{})
+	for _, k := range obj.EditFields {
+		editFields[k] = struct{}{}
+	}
+	rv := reflect.ValueOf(val).Elem()
+	row.updates = make(map[string]any)
+	for k := range row.vals {
+		if k == obj.jsonPKName ||
+			(obj.VersionField != "" && k == obj.versionJsonName) ||
+			(obj.TenantField != "" && k == obj.tenantJsonName) {
+			continue
+		}
+		fname := obj.jsonToFields[k]
+		if _, ok := editFields[fname]; !ok {
+			continue
+		}
+		row.updates[fname] = rv.FieldByName(fname).Interface()
+	}
+	if len(row.updates) == 0 {
+		return errors.New("not changed")
+	}
+
+	if obj.Authorizer != nil {
+		if err := obj.Authorizer.Authorize(c, obj, EDIT, row.old); err != nil {
+			return err
+		}
+	}
+	if obj.BeforeUpdate != nil {
+		if err := obj.beforeUpdate(c, row.old, row.vals); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeImport decode the vals (json name key) into vptr, with the time layouts of create.
+func (obj *WebObject) decodeImport(vals map[string]any, vptr any, weak bool) error {
+	fieldVals := make(map[string]any, len(vals))
+	for k, v := range vals {
+		if fname, ok := obj.jsonToFields[k]; ok {
+			fieldVals[fname] = v
+		}
+	}
+	config := mapstructure.DecoderConfig{
+		DecodeHook:       decodeTime,
+		WeaklyTypedInput: weak,
+		Squash:           true,
+		Result:           vptr,
+	}
+	decoder, err := mapstructure.NewDecoder(&config)
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(fieldVals)
+}
+
+// writeImport create or update the row in tx.
+func (obj *WebObject) writeImport(c *gin.Context, tx *gorm.DB, row *importRow) error {
+	if row.action == CREATE {
+		if err := tx.Create(row.val).Error; err != nil {
+			return err
+		}
+		return obj.recordChanges(c, tx, CREATE, nil, row.val)
+	}
+
+	key := obj.getKey(row.old)
+	model := reflect.New(obj.modelElem).Interface()
+	q := tx.Model(model).Where(obj.gormPKName, key)
+	if obj.VersionField != "" {
+		q = obj.withVersion(q, row.old)
+		obj.nextVersion(row.updates)
+	}
+	result := q.Updates(row.updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if obj.VersionField != "" && result.RowsAffected == 0 {
+		return errVersionMismatch
+	}
+	if err := tx.First(model, obj.gormPKName, key).Error; err != nil {
+		return err
+	}
+	row.val = model
+	return obj.recordChanges(c, tx, EDIT, row.old, model)
+}
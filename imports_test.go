@@ -0,0 +1,220 @@
+package gormpher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func initImportTest(t *testing.T) (*TestClient, *gorm.DB) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{}, texport{})
+
+	r := gin.Default()
+	RegisterObjects(r, []WebObject{
+		{
+			Name:         "item",
+			Model:        texport{},
+			AllowMethods: GET | CREATE | QUERY | IMPORT,
+			EditFields:   []string{"Name", "Enabled", "Score"},
+			GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			BeforeCreate: func(ctx *gin.Context, vptr any, vals map[string]any) error {
+				if vptr.(*texport).Name == "root" {
+					return errors.New("reserved name")
+				}
+				return nil
+			},
+		},
+	})
+	return NewTestClient(r), db
+}
+
+func postImport(c *TestClient, path, contentType string, body []byte) (int, ImportResult) {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	w := c.SendReq(path, req)
+
+	var result ImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	return w.Code, result
+}
+
+func TestImportCSV(t *testing.T) {
+	c, db := initImportTest(t)
+
+	body := []byte("\ufeffname,enabled,score,createdAt\n" +
+		"alice,true,9.5,2023-03-17 08:30:00\n" +
+		"\"bob, jr\",false,,2023-03-17\n")
+
+	code, result := postImport(c, "/item/import?dryRun=true", MimeCSV, body)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ImportResult{Total: 2, Created: 2, DryRun: true}, result)
+
+	var count int64
+	db.Model(&texport{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	code, result = postImport(c, "/item/import", MimeCSV, body)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ImportResult{Total: 2, Created: 2}, result)
+
+	var items []texport
+	db.Order("id").Find(&items)
+	assert.Len(t, items, 2)
+	assert.Equal(t, "alice", items[0].Name)
+	assert.True(t, items[0].Enabled)
+	assert.Equal(t, 9.5, *items[0].Score)
+	assert.Equal(t, time.Date(2023, 3, 17, 8, 30, 0, 0, time.UTC), items[0].CreatedAt.UTC())
+	assert.Equal(t, "bob, jr", items[1].Name)
+	assert.False(t, items[1].Enabled)
+	assert.Nil(t, items[1].Score)
+
+	// unknown column
+	code, _ = postImport(c, "/item/import", MimeCSV, []byte("name,unknown\nclash,1\n"))
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// unknown format
+	code, _ = postImport(c, "/item/import", "text/plain", body)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestImportErrors(t *testing.T) {
+	c, db := initImportTest(t)
+
+	body := []byte("name,score\n" +
+		"alice,1\n" +
+		"root,2\n" +
+		"bob,abc\n")
+	code, result := postImport(c, "/item/import", MimeCSV, body)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, 3, result.Total)
+	assert.Equal(t, 0, result.Created)
+	assert.Len(t, result.Errors, 2)
+	assert.Equal(t, 3, result.Errors[0].Line)
+	assert.Equal(t, "reserved name", result.Errors[0].Error)
+	assert.Equal(t, 4, result.Errors[1].Line)
+
+	// the write fails on the duplicated id, the first row is rolled back
+	body = []byte(`{"id":10,"name":"alice"}` + "\n" +
+		`{"id":10,"name":"bob"}` + "\n")
+	code, result = postImport(c, "/item/import", MimeNDJSON, body)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, 2, result.Errors[0].Line)
+
+	var count int64
+	db.Model(&texport{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestImportUpsert(t *testing.T) {
+	c, db := initImportTest(t)
+	db.Create(&texport{ID: 1, Name: "alice", Enabled: true})
+
+	body := []byte(`{"id":1,"name":"alice2","enabled":false,"ownerId":9}` + "\n" +
+		"\n" +
+		`{"id":2,"name":"bob","createdAt":"2023-03-17T08:30"}` + "\n")
+
+	// create only
+	code, result := postImport(c, "/item/import?format=ndjson", "application/json", body)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, 1, result.Errors[0].Line)
+
+	code, result = postImport(c, "/item/import?mode=upsert", MimeNDJSON, body)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ImportResult{Total: 2, Created: 1, Updated: 1}, result)
+
+	var item texport
+	db.First(&item, 1)
+	assert.Equal(t, "alice2", item.Name)
+	assert.False(t, item.Enabled)
+	assert.Equal(t, uint(0), item.OwnerID) // not edit field
+
+	item = texport{}
+	db.First(&item, 2)
+	assert.Equal(t, "bob", item.Name)
+	assert.Equal(t, 2023, item.CreatedAt.Year())
+
+	// invalid json line
+	code, result = postImport(c, "/item/import?mode=upsert", MimeNDJSON, []byte("{\"id\":1}\n{bad\n"))
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, 2, result.Total)
+	assert.Len(t, result.Errors, 2)
+	assert.Equal(t, "not changed", result.Errors[0].Error)
+	assert.Equal(t, 2, result.Errors[1].Line)
+
+	code, _ = postImport(c, "/item/import?mode=replace", MimeNDJSON, body)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestImportMultipart(t *testing.T) {
+	c, db := initImportTest(t)
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, _ := mw.CreateFormFile("file", "items.csv")
+	fw.Write([]byte("name\nalice\nbob\n"))
+	mw.Close()
+
+	code, result := postImport(c, "/item/import", mw.FormDataContentType(), buf.Bytes())
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, result.Created)
+
+	var count int64
+	db.Model(&texport{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestImportLimits(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(texport{})
+
+	r := gin.New()
+	RegisterObjects(r, []WebObject{
+		{
+			Name:          "item",
+			Model:         texport{},
+			AllowMethods:  IMPORT,
+			EditFields:    []string{"Name"},
+			GetDB:         func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			MaxImportRows: 2,
+			MaxImportSize: 64,
+		},
+	})
+	c := NewTestClient(r)
+
+	code, result := postImport(c, "/item/import", MimeCSV, []byte("name\nalice\nbob\n"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, result.Created)
+
+	code, _ = postImport(c, "/item/import", MimeCSV, []byte("name\nalice\nbob\nclash\n"))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+	code, _ = postImport(c, "/item/import", MimeNDJSON, []byte("{\"name\":\"a\"}\n{bad\n{\"name\":\"b\"}\n"))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+
+	code, _ = postImport(c, "/item/import", MimeCSV, []byte("name\n"+strings.Repeat("a", 100)+"\n"))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, _ := mw.CreateFormFile("file", "items.csv")
+	fw.Write([]byte("name\n" + strings.Repeat("a", 100) + "\n"))
+	mw.Close()
+	code, _ = postImport(c, "/item/import", mw.FormDataContentType(), buf.Bytes())
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+
+	var count int64
+	db.Model(&texport{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
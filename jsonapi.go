@@ -0,0 +1,438 @@
+package gormpher
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/schema"
+)
+
+const MimeJSONAPI = "application/vnd.api+json"
+
+const jsonAPIKey = "_gormpher_jsonapi"
+
+// the conflicts of resource object and endpoint, respond 409
+var (
+	errJSONAPIType = errors.New("invalid resource type")
+	errJSONAPIID   = errors.New("resource id does not match the key")
+)
+
+// JSONAPIResource is the resource object of JSON:API.
+type JSONAPIResource struct {
+	Type          string                         `json:"type"`
+	ID            string                         `json:"id"`
+	Attributes    map[string]any                 `json:"attributes,omitempty"`
+	Relationships map[string]JSONAPIRelationship `json:"relationships,omitempty"`
+	Links         map[string]string              `json:"links,omitempty"`
+}
+
+// JSONAPIRelationship is the relationship of resource, Data is
+// JSONAPIIdentifier, []JSONAPIIdentifier or nil.
+type JSONAPIRelationship struct {
+	Data any `json:"data"`
+}
+
+type JSONAPIIdentifier struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// JSONAPIDocument is the top level document of JSON:API,
+// Data is JSONAPIResource or []JSONAPIResource.
+type JSONAPIDocument struct {
+	Data     any               `json:"data,omitempty"`
+	Included []JSONAPIResource `json:"included,omitempty"`
+	Meta     map[string]any    `json:"meta,omitempty"`
+	Links    map[string]string `json:"links,omitempty"`
+	Errors   []JSONAPIError    `json:"errors,omitempty"`
+}
+
+type JSONAPIError struct {
+	Status string `json:"status"`
+	Title  string `json:"title"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// negotiateJSONAPI switch the request to JSON:API mode, when the request
+// accepts or sends application/vnd.api+json.
+func negotiateJSONAPI(c *gin.Context) {
+	if strings.Contains(c.GetHeader("Accept"), MimeJSONAPI) || c.ContentType() == MimeJSONAPI {
+		c.Set(jsonAPIKey, true)
+		c.Header("Content-Type", MimeJSONAPI)
+	}
+}
+
+func isJSONAPI(c *gin.Context) bool {
+	return c.GetBool(jsonAPIKey)
+}
+
+// handleJSONAPIError respond the error document.
+func handleJSONAPIError(c *gin.Context, code int, err any) {
+	var detail string
+	switch err := err.(type) {
+	case error:
+		detail = err.Error()
+		c.Error(err)
+	case string:
+		detail = err
+		c.Error(errors.New(err))
+	default:
+		detail = fmt.Sprintf("unknown error: %v", err)
+	}
+	c.AbortWithStatusJSON(code, JSONAPIDocument{
+		Errors: []JSONAPIError{{Status: strconv.Itoa(code), Title: http.StatusText(code), Detail: detail}},
+	})
+}
+
+// renderOK respond true, or 204 No Content in JSON:API mode.
+func renderOK(c *gin.Context) {
+	if isJSONAPI(c) {
+		c.Writer.Header().Del("Content-Type")
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, true)
+}
+
+// bindVals bind the json body, the attributes and id of JSON:API document are flattened.
+// The id of document must match the key of request, see handleBindError.
+func (obj *WebObject) bindVals(c *gin.Context) (map[string]any, error) {
+	var vals map[string]any
+	if !isJSONAPI(c) {
+		err := c.BindJSON(&vals)
+		return vals, err
+	}
+
+	var doc struct {
+		Data struct {
+			Type       string         `json:"type"`
+			ID         any            `json:"id"`
+			Attributes map[string]any `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := c.BindJSON(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Data.Type != obj.jsonAPIType(obj.modelElem) {
+		return nil, errJSONAPIType
+	}
+	vals = doc.Data.Attributes
+	if vals == nil {
+		vals = make(map[string]any)
+	}
+	if key := c.Param("key"); key != "" {
+		if doc.Data.ID == nil {
+			return nil, errors.New("resource id is required")
+		}
+		if fmt.Sprintf("%v", doc.Data.ID) != key {
+			return nil, errJSONAPIID
+		}
+	}
+	if doc.Data.ID != nil {
+		vals[obj.jsonPKName] = doc.Data.ID
+	}
+	return vals, nil
+}
+
+// handleBindError respond the error of bindVals, 409 for the conflicts of
+// resource type and id, otherwise 400.
+func handleBindError(c *gin.Context, err error) {
+	if errors.Is(err, errJSONAPIType) || errors.Is(err, errJSONAPIID) {
+		handleError(c, http.StatusConflict, err)
+		return
+	}
+	handleError(c, http.StatusBadRequest, err)
+}
+
+// getIncludes return the relationships of include parameter, the json names of preloads.
+func (obj *WebObject) getIncludes(c *gin.Context) (map[string]struct{}, error) {
+	includes := make(map[string]struct{})
+	include := c.Query("include")
+	if include == "" {
+		return includes, nil
+	}
+
+	relationships := make(map[string]struct{})
+	for _, field := range obj.preloads {
+		relationships[obj.getJsonName(field)] = struct{}{}
+	}
+	for _, name := range strings.Split(include, ",") {
+		if _, ok := relationships[name]; !ok {
+			return nil, fmt.Errorf("invalid include %s", name)
+		}
+		includes[name] = struct{}{}
+	}
+	return includes, nil
+}
+
+// jsonAPIDocument return the document of single object.
+func (obj *WebObject) jsonAPIDocument(c *gin.Context, vptr any, includes map[string]struct{}) (*JSONAPIDocument, error) {
+	b := jsonAPIBuilder{
+		obj:          obj,
+		basePath:     jsonAPIBasePath(c, c.Param("key") != ""),
+		includes:     includes,
+		hiddenFields: obj.unreadableFields(c),
+	}
+	res, err := b.resource(vptr)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONAPIDocument{
+		Data:     res,
+		Included: b.included,
+		Links:    map[string]string{"self": c.Request.URL.RequestURI()},
+	}, nil
+}
+
+// jsonAPIQueryDocument return the document of query or view, with pagination meta and links.
+// The hidden fields are not rendered.
+func (obj *WebObject) jsonAPIQueryDocument(c *gin.Context, view *QueryView, r QueryResult[any], form *QueryForm, includes, hiddenFields map[string]struct{}) (*JSONAPIDocument, error) {
+	b := jsonAPIBuilder{
+		obj:          obj,
+		basePath:     jsonAPIBasePath(c, view != nil),
+		includes:     includes,
+		hiddenFields: hiddenFields,
+	}
+
+	data := []JSONAPIResource{}
+	items := reflect.ValueOf(r.Items)
+	if items.Kind() == reflect.Slice {
+		for i := 0; i < items.Len(); i++ {
+			res, err := b.resource(items.Index(i).Addr().Interface())
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, res)
+		}
+	}
+
+	return &JSONAPIDocument{
+		Data:     data,
+		Included: b.included,
+		Meta:     map[string]any{"total": r.Total, "pos": r.Pos, "limit": r.Limit},
+		Links:    jsonAPIPageLinks(c, form, r.Total),
+	}, nil
+}
+
+// jsonAPIBasePath return the path of object mounted in router, such as "/api/item",
+// the last segment of request path is trimmed for the key and view routes.
+func jsonAPIBasePath(c *gin.Context, trim bool) string {
+	p := path.Clean(c.Request.URL.Path)
+	if trim {
+		p = path.Dir(p)
+	}
+	return p
+}
+
+// jsonAPIPageLinks return the self, first, prev, next and last links of query.
+func jsonAPIPageLinks(c *gin.Context, form *QueryForm, total int) map[string]string {
+	links := map[string]string{"self": c.Request.URL.RequestURI()}
+	if c.Request.Method != http.MethodGet || form.Limit <= 0 {
+		return links
+	}
+
+	link := func(pos int) string {
+		query := c.Request.URL.Query()
+		if form.Pagination {
+			query.Set("page[number]", strconv.Itoa(pos))
+			query.Set("page[size]", strconv.Itoa(form.Limit))
+		} else {
+			query.Set("page[offset]", strconv.Itoa(pos))
+			query.Set("page[limit]", strconv.Itoa(form.Limit))
+		}
+		u := url.URL{Path: c.Request.URL.Path, RawQuery: query.Encode()}
+		return u.String()
+	}
+
+	if form.Pagination {
+		last := (total + form.Limit - 1) / form.Limit
+		if last < 1 {
+			last = 1
+		}
+		links["first"] = link(1)
+		links["last"] = link(last)
+		if form.Pos > 1 {
+			links["prev"] = link(form.Pos - 1)
+		}
+		if form.Pos < last {
+			links["next"] = link(form.Pos + 1)
+		}
+	} else {
+		last := 0
+		if total > 0 {
+			last = (total - 1) / form.Limit * form.Limit
+		}
+		links["first"] = link(0)
+		links["last"] = link(last)
+		if form.Pos > 0 {
+			prev := form.Pos - form.Limit
+			if prev < 0 {
+				prev = 0
+			}
+			links["prev"] = link(prev)
+		}
+		if form.Pos+form.Limit < total {
+			links["next"] = link(form.Pos + form.Limit)
+		}
+	}
+	return links
+}
+
+// jsonAPIBuilder build the resources, the included resources are collected without duplicates.
+type jsonAPIBuilder struct {
+	obj          *WebObject
+	basePath     string // for the self links of resources
+	includes     map[string]struct{}
+	hiddenFields map[string]struct{}
+	included     []JSONAPIResource
+	seen         map[JSONAPIIdentifier]struct{}
+}
+
+// resource return the resource of vptr, the preloads are relationships.
+func (b *jsonAPIBuilder) resource(vptr any) (JSONAPIResource, error) {
+	obj := b.obj
+	out, err := obj.stripFields(vptr, b.hiddenFields)
+	if err != nil {
+		return JSONAPIResource{}, err
+	}
+	attributes, err := toJSONMap(out)
+	if err != nil {
+		return JSONAPIResource{}, err
+	}
+
+	res := JSONAPIResource{
+		Type:       obj.jsonAPIType(obj.modelElem),
+		ID:         obj.getKey(vptr),
+		Attributes: attributes,
+		Links:      map[string]string{"self": path.Join(b.basePath, url.PathEscape(obj.getKey(vptr)))},
+	}
+	delete(attributes, obj.jsonPKName)
+
+	rv := reflect.ValueOf(vptr).Elem()
+	for _, field := range obj.preloads {
+		if _, ok := b.hiddenFields[field]; ok {
+			continue
+		}
+		name := obj.getJsonName(field)
+		delete(attributes, name)
+
+		rel, err := b.relationship(name, rv.FieldByName(field))
+		if err != nil {
+			return JSONAPIResource{}, err
+		}
+		if res.Relationships == nil {
+			res.Relationships = make(map[string]JSONAPIRelationship)
+		}
+		res.Relationships[name] = rel
+	}
+	return res, nil
+}
+
+// relationship return the linkage of preloaded field, and include the related resources.
+func (b *jsonAPIBuilder) relationship(name string, fv reflect.Value) (JSONAPIRelationship, error) {
+	_, include := b.includes[name]
+
+	if fv.Kind() == reflect.Slice {
+		ids := []JSONAPIIdentifier{}
+		for i := 0; i < fv.Len(); i++ {
+			id, err := b.related(fv.Index(i), include)
+			if err != nil {
+				return JSONAPIRelationship{}, err
+			}
+			if id != nil {
+				ids = append(ids, *id)
+			}
+		}
+		return JSONAPIRelationship{Data: ids}, nil
+	}
+
+	id, err := b.related(fv, include)
+	if err != nil || id == nil {
+		return JSONAPIRelationship{}, err
+	}
+	return JSONAPIRelationship{Data: *id}, nil
+}
+
+// related return the identifier of related object, nil for nil pointer or zero primary key.
+func (b *jsonAPIBuilder) related(v reflect.Value, include bool) (*JSONAPIIdentifier, error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	pkName, pkValue := primaryKeyOf(v)
+	if !pkValue.IsValid() || pkValue.IsZero() {
+		return nil, nil
+	}
+	id := JSONAPIIdentifier{
+		Type: b.obj.jsonAPIType(v.Type()),
+		ID:   fmt.Sprintf("%v", pkValue.Interface()),
+	}
+	if !include {
+		return &id, nil
+	}
+
+	if b.seen == nil {
+		b.seen = make(map[JSONAPIIdentifier]struct{})
+	}
+	if _, ok := b.seen[id]; ok {
+		return &id, nil
+	}
+	b.seen[id] = struct{}{}
+
+	attributes, err := toJSONMap(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := v.Type().FieldByName(pkName); ok {
+		delete(attributes, strings.Split(f.Tag.Get("json"), ",")[0])
+		delete(attributes, pkName)
+	}
+	b.included = append(b.included, JSONAPIResource{Type: id.Type, ID: id.ID, Attributes: attributes})
+	return &id, nil
+}
+
+// jsonAPIType return the resource type of model, the Name of the object registered
+// with the model, or the singular table name when the model has no object, such as "user_profile".
+func (obj *WebObject) jsonAPIType(rt reflect.Type) string {
+	if name, ok := obj.jsonAPITypes[rt]; ok {
+		return name
+	}
+	return schema.NamingStrategy{SingularTable: true}.TableName(rt.Name())
+}
+
+// primaryKeyOf return the primary key field of struct, the field with
+// gorm primaryKey tag or named ID.
+func primaryKeyOf(v reflect.Value) (string, reflect.Value) {
+	rt := v.Type()
+	var idName string
+	var idValue reflect.Value
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if name, fv := primaryKeyOf(v.Field(i)); fv.IsValid() {
+				return name, fv
+			}
+			continue
+		}
+		if strings.Contains(strings.ToLower(f.Tag.Get("gorm")), "primarykey") {
+			return f.Name, v.Field(i)
+		}
+		if f.Name == "ID" {
+			idName, idValue = f.Name, v.Field(i)
+		}
+	}
+	return idName, idValue
+}
@@ -0,0 +1,213 @@
+package gormpher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func initJSONAPITest(t *testing.T) (*TestClient, *gorm.DB) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{}, texport{})
+
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 10})
+	for i := 1; i <= 5; i++ {
+		db.Create(&texport{Name: fmt.Sprintf("item-%d", i), OwnerID: 1, Enabled: i%2 == 1})
+	}
+
+	r := gin.Default()
+	RegisterObjects(r, []WebObject{
+		{
+			Name:         "item",
+			Model:        texport{},
+			JSONAPI:      true,
+			EditFields:   []string{"Name", "Enabled"},
+			FilterFields: []string{"Name", "Enabled"},
+			OrderFields:  []string{"ID"},
+			GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+		},
+		{
+			Name:         "user",
+			Model:        tuser{},
+			JSONAPI:      true,
+			AllowMethods: GET,
+			GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+		},
+	})
+	return NewTestClient(r), db
+}
+
+func sendJSONAPI(c *TestClient, method, path string, body any) (*httptest.ResponseRecorder, JSONAPIDocument) {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Accept", MimeJSONAPI)
+	if body != nil {
+		req.Header.Set("Content-Type", MimeJSONAPI)
+	}
+	w := c.SendReq(path, req)
+
+	var doc JSONAPIDocument
+	json.Unmarshal(w.Body.Bytes(), &doc)
+	return w, doc
+}
+
+func TestJSONAPIGet(t *testing.T) {
+	c, _ := initJSONAPITest(t)
+
+	w, doc := sendJSONAPI(c, http.MethodGet, "/item/1?include=owner", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, MimeJSONAPI, w.Header().Get("Content-Type"))
+
+	res := doc.Data.(map[string]any)
+	assert.Equal(t, "item", res["type"])
+	assert.Equal(t, "1", res["id"])
+	attributes := res["attributes"].(map[string]any)
+	assert.Equal(t, "item-1", attributes["name"])
+	assert.NotContains(t, attributes, "id")
+	assert.NotContains(t, attributes, "owner")
+	owner := res["relationships"].(map[string]any)["owner"].(map[string]any)["data"]
+	assert.Equal(t, map[string]any{"type": "user", "id": "1"}, owner)
+
+	assert.Len(t, doc.Included, 1)
+	assert.Equal(t, "user", doc.Included[0].Type)
+	assert.Equal(t, "alice", doc.Included[0].Attributes["name"])
+
+	// the same type of the related and primary resources
+	_, doc = sendJSONAPI(c, http.MethodGet, "/user/1", nil)
+	assert.Equal(t, "user", doc.Data.(map[string]any)["type"])
+
+	// invalid include
+	w, doc = sendJSONAPI(c, http.MethodGet, "/item/1?include=unknown", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "400", doc.Errors[0].Status)
+
+	w, doc = sendJSONAPI(c, http.MethodGet, "/item/100", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "not found", doc.Errors[0].Detail)
+
+	// plain json without Accept
+	var item texport
+	err := c.CallGet("/item/1", nil, &item)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", item.Owner.Name)
+}
+
+func TestJSONAPIQuery(t *testing.T) {
+	c, _ := initJSONAPITest(t)
+
+	w, doc := sendJSONAPI(c, http.MethodGet, "/item?filter[enabled]=true&sort=-id&page[limit]=2&include=owner", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	data := doc.Data.([]any)
+	assert.Len(t, data, 2)
+	assert.Equal(t, "5", data[0].(map[string]any)["id"])
+	assert.Equal(t, "3", data[1].(map[string]any)["id"])
+	assert.Len(t, doc.Included, 1)
+	assert.Equal(t, float64(3), doc.Meta["total"])
+	assert.Equal(t, "/item?filter%5Benabled%5D=true&include=owner&page%5Blimit%5D=2&page%5Boffset%5D=2&sort=-id", doc.Links["next"])
+	assert.Equal(t, "/item?filter%5Benabled%5D=true&include=owner&page%5Blimit%5D=2&page%5Boffset%5D=2&sort=-id", doc.Links["last"])
+	assert.NotContains(t, doc.Links, "prev")
+
+	w, doc = sendJSONAPI(c, http.MethodGet, "/item?filter[name][in]=item-1,item-2&page[number]=2&page[size]=1", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	data = doc.Data.([]any)
+	assert.Len(t, data, 1)
+	assert.Equal(t, "2", data[0].(map[string]any)["id"])
+	assert.Contains(t, doc.Links["prev"], "page%5Bnumber%5D=1")
+	assert.NotContains(t, doc.Links, "next")
+
+	w, _ = sendJSONAPI(c, http.MethodGet, "/item?page[limit]=abc", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// the POST query works as before
+	var res QueryResult[[]texport]
+	err := c.CallPost("/item", QueryForm{}, &res)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, res.Total)
+}
+
+func TestJSONAPIWrite(t *testing.T) {
+	c, db := initJSONAPITest(t)
+
+	w, doc := sendJSONAPI(c, http.MethodPut, "/item", map[string]any{
+		"data": map[string]any{"type": "item", "attributes": map[string]any{"name": "created", "enabled": true}},
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/item/6", w.Header().Get("Location"))
+	assert.Equal(t, "6", doc.Data.(map[string]any)["id"])
+
+	w, _ = sendJSONAPI(c, http.MethodPut, "/item", map[string]any{
+		"data": map[string]any{"type": "user", "attributes": map[string]any{"name": "created"}},
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// the id of document must match the key
+	w, _ = sendJSONAPI(c, http.MethodPatch, "/item/6", map[string]any{
+		"data": map[string]any{"type": "item", "id": "5", "attributes": map[string]any{"name": "updated"}},
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w, _ = sendJSONAPI(c, http.MethodPatch, "/item/6", map[string]any{
+		"data": map[string]any{"type": "item", "attributes": map[string]any{"name": "updated"}},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, _ = sendJSONAPI(c, http.MethodPatch, "/item/6", map[string]any{
+		"data": map[string]any{"type": "item", "id": "6", "attributes": map[string]any{"name": "updated"}},
+	})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	var item texport
+	db.First(&item, 6)
+	assert.Equal(t, "updated", item.Name)
+
+	w, _ = sendJSONAPI(c, http.MethodDelete, "/item/6", nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestJSONAPILinks(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{})
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 10})
+
+	r := gin.New()
+	err := RegisterObjects(r.Group("api"), []WebObject{
+		{
+			Name:    "user",
+			Group:   "v1",
+			Model:   tuser{},
+			JSONAPI: true,
+			GetDB:   func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			Views:   []QueryView{{Name: "all", Method: http.MethodGet}},
+		},
+	})
+	assert.Nil(t, err)
+	c := NewTestClient(r)
+
+	// the self links are the mounted paths
+	_, doc := sendJSONAPI(c, http.MethodGet, "/api/v1/user/1", nil)
+	assert.Equal(t, "/api/v1/user/1", doc.Data.(map[string]any)["links"].(map[string]any)["self"])
+	for _, p := range []string{"/api/v1/user", "/api/v1/user/all"} {
+		_, doc = sendJSONAPI(c, http.MethodGet, p, nil)
+		data := doc.Data.([]any)
+		assert.Len(t, data, 1)
+		assert.Equal(t, "/api/v1/user/1", data[0].(map[string]any)["links"].(map[string]any)["self"])
+	}
+
+	w, doc := sendJSONAPI(c, http.MethodPut, "/api/v1/user", map[string]any{
+		"data": map[string]any{"type": "user", "attributes": map[string]any{"name": "bob"}},
+	})
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v1/user/2", doc.Data.(map[string]any)["links"].(map[string]any)["self"])
+	assert.Equal(t, "/api/v1/user/2", w.Header().Get("Location"))
+}
@@ -0,0 +1,157 @@
+package gormpher
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const logKey = "gormpher:log"
+
+// logState is the logging context of request, stored in gin.Context and the db settings.
+type logState struct {
+	logger    *slog.Logger
+	threshold time.Duration
+	vars      bool // log the bound values of queries
+	object    string
+	action    string
+	view      string
+	filters   []string // the compiled filters of query
+}
+
+// logRequest log the action of route with Logger, the errors of 5xx are logged as error.
+func (obj *WebObject) logRequest(action int, view string, h gin.HandlerFunc) gin.HandlerFunc {
+	if obj.Logger == nil {
+		return h
+	}
+	return func(c *gin.Context) {
+		state := &logState{
+			logger:    obj.Logger,
+			threshold: obj.SlowQueryThreshold,
+			vars:      obj.LogQueryVars,
+			object:    obj.Name,
+			action:    ActionName(action),
+			view:      view,
+		}
+		c.Set(logKey, state)
+		start := time.Now()
+		h(c)
+
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("object", state.object),
+			slog.String("action", state.action),
+		}
+		if view != "" {
+			attrs = append(attrs, slog.String("view", view))
+		}
+		if key := c.Param("key"); key != "" {
+			attrs = append(attrs, slog.String("key", key))
+		}
+		attrs = append(attrs, slog.Int("status", status), slog.Duration("duration", time.Since(start)))
+
+		level := slog.LevelInfo
+		if e := c.Errors.Last(); e != nil {
+			attrs = append(attrs, slog.String("error", e.Error()))
+		}
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		obj.Logger.LogAttrs(c.Request.Context(), level, "gormpher "+state.action, attrs...)
+	}
+}
+
+// logFilters set the compiled filters of query, reported with the slow queries.
+func logFilters(c *gin.Context, filters []Filter) {
+	state, ok := c.Value(logKey).(*logState)
+	if !ok {
+		return
+	}
+	state.filters = make([]string, 0, len(filters))
+	for _, f := range filters {
+		q := f.GetQuery()
+		if q == "" {
+			continue
+		}
+		if state.vars {
+			q = strings.Replace(q, "?", fmt.Sprintf("%v", f.Value), 1)
+		}
+		state.filters = append(state.filters, q)
+	}
+}
+
+// registered callbacks of gorm.Config
+var logCallbacks sync.Map
+
+// logDB report the queries of db above SlowQueryThreshold, the callbacks of db
+// are registered by RegisterCallbacks.
+func logDB(c *gin.Context, db *gorm.DB) *gorm.DB {
+	state, ok := c.Value(logKey).(*logState)
+	if !ok || state.threshold <= 0 {
+		return db
+	}
+	// new session, so the logged db can be reused by multiple statements
+	return db.Set(logKey, state).Session(&gorm.Session{})
+}
+
+func registerLogCallbacks(db *gorm.DB) {
+	const startKey = "gormpher:log_start"
+	before := func(db *gorm.DB) {
+		if _, ok := db.Get(logKey); ok {
+			db.InstanceSet(startKey, time.Now())
+		}
+	}
+	after := func(db *gorm.DB) {
+		v, ok := db.Get(logKey)
+		if !ok {
+			return
+		}
+		start, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		state := v.(*logState)
+		elapsed := time.Since(start.(time.Time))
+		if elapsed < state.threshold {
+			return
+		}
+		attrs := []slog.Attr{
+			slog.String("object", state.object),
+			slog.String("action", state.action),
+		}
+		if state.view != "" {
+			attrs = append(attrs, slog.String("view", state.view))
+		}
+		sql := db.Statement.SQL.String()
+		if state.vars {
+			sql = db.Dialector.Explain(sql, db.Statement.Vars...)
+		}
+		attrs = append(attrs,
+			slog.String("sql", sql),
+			slog.Any("filters", state.filters),
+			slog.Int64("rows", db.RowsAffected),
+			slog.Duration("duration", elapsed),
+		)
+		if db.Error != nil {
+			attrs = append(attrs, slog.String("error", db.Error.Error()))
+		}
+		state.logger.LogAttrs(db.Statement.Context, slog.LevelWarn, "gormpher slow query", attrs...)
+	}
+
+	callback := db.Callback()
+	callback.Query().Before("gorm:query").Register("gormpher:log_before_query", before)
+	callback.Query().After("gorm:after_query").Register("gormpher:log_after_query", after)
+	callback.Row().Before("gorm:row").Register("gormpher:log_before_row", before)
+	callback.Row().After("gorm:row").Register("gormpher:log_after_row", after)
+	callback.Create().Before("gorm:create").Register("gormpher:log_before_create", before)
+	callback.Create().After("gorm:after_create").Register("gormpher:log_after_create", after)
+	callback.Update().Before("gorm:update").Register("gormpher:log_before_update", before)
+	callback.Update().After("gorm:after_update").Register("gormpher:log_after_update", after)
+	callback.Delete().Before("gorm:delete").Register("gormpher:log_before_delete", before)
+	callback.Delete().After("gorm:after_delete").Register("gormpher:log_after_delete", after)
+}
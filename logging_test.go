@@ -0,0 +1,125 @@
+package gormpher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// logRecords return the records of JSON logs.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var record map[string]any
+		assert.Nil(t, dec.Decode(&record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogging(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{})
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 9})
+	db.Create(&tuser{ID: 2, Name: "bob", Age: 10})
+	RegisterCallbacks(db)
+
+	var buf bytes.Buffer
+	r := gin.Default()
+	err := RegisterObjects(r, []WebObject{
+		{
+			Name:               "user",
+			Model:              tuser{},
+			FilterFields:       []string{"Age"},
+			GetDB:              func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			Logger:             slog.New(slog.NewJSONHandler(&buf, nil)),
+			SlowQueryThreshold: time.Nanosecond,
+			BeforeRender: func(ctx *gin.Context, vptr any) error {
+				if vptr.(*tuser).ID == 2 {
+					return errors.New("render fail")
+				}
+				return nil
+			},
+		},
+	})
+	assert.Nil(t, err)
+	client := NewTestClient(r)
+
+	w := client.Get("/user/1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	records := logRecords(t, &buf)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "WARN", records[0]["level"])
+	assert.Equal(t, "gormpher slow query", records[0]["msg"])
+	assert.Equal(t, "user", records[0]["object"])
+	// the values are not logged by default
+	assert.Contains(t, records[0]["sql"], "SELECT * FROM `tusers` WHERE `id` = ?")
+	assert.Equal(t, float64(1), records[0]["rows"])
+
+	assert.Equal(t, "INFO", records[1]["level"])
+	assert.Equal(t, "gormpher get", records[1]["msg"])
+	assert.Equal(t, "user", records[1]["object"])
+	assert.Equal(t, "get", records[1]["action"])
+	assert.Equal(t, "1", records[1]["key"])
+	assert.Equal(t, float64(http.StatusOK), records[1]["status"])
+	assert.Contains(t, records[1], "duration")
+
+	w = client.Post("/user", []byte(`{"filters":[{"name":"age","op":">=","value":9}]}`))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	records = logRecords(t, &buf)
+	assert.Len(t, records, 3) // count, find and query
+	assert.Equal(t, []any{"`age` >= ?"}, records[0]["filters"])
+	assert.Contains(t, records[1]["sql"], "LIMIT")
+	assert.Equal(t, "ERROR", records[2]["level"])
+	assert.Equal(t, "gormpher query", records[2]["msg"])
+	assert.Equal(t, float64(http.StatusInternalServerError), records[2]["status"])
+	assert.Equal(t, "render fail", records[2]["error"])
+}
+
+func TestLoggingQueryVars(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{})
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 9})
+	RegisterCallbacks(db)
+
+	var buf bytes.Buffer
+	r := gin.New()
+	err := RegisterObjects(r, []WebObject{
+		{
+			Name:               "user",
+			Model:              tuser{},
+			EditFields:         []string{"Name"},
+			FilterFields:       []string{"Age"},
+			GetDB:              func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			Logger:             slog.New(slog.NewJSONHandler(&buf, nil)),
+			SlowQueryThreshold: time.Nanosecond,
+			LogQueryVars:       true,
+		},
+	})
+	assert.Nil(t, err)
+	client := NewTestClient(r)
+
+	w := client.Post("/user", []byte(`{"filters":[{"name":"age","op":">=","value":9}]}`))
+	assert.Equal(t, http.StatusOK, w.Code)
+	records := logRecords(t, &buf)
+	assert.Equal(t, []any{"`age` >= 9"}, records[0]["filters"])
+	assert.Contains(t, records[0]["sql"], "`age` >= 9")
+
+	var ok bool
+	assert.Nil(t, client.CallPatch("/user/1", map[string]any{"name": "secret"}, &ok))
+	var sqls []any
+	for _, record := range logRecords(t, &buf) {
+		sqls = append(sqls, record["sql"])
+	}
+	assert.Contains(t, sqls, "UPDATE `tusers` SET `name`=\"secret\" WHERE `id` = \"1\"")
+}
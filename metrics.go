@@ -0,0 +1,275 @@
+package gormpher
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MetricsRecorder record the metrics of WebObject routes, the labels are the object name,
+// action name and view name (empty for the non-view routes). It's implemented by Metrics,
+// or an adapter of the other registry such as prometheus/client_golang.
+type MetricsRecorder interface {
+	ObserveRequest(object, action, view string, status int, duration time.Duration)
+	ObserveRows(object, action, view string, rows int)
+	ObserveQuery(object, action, view string, duration time.Duration)
+}
+
+var (
+	DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	DefaultRowsBuckets     = []float64{0, 1, 10, 50, 100, 500, 1000, 10000}
+)
+
+const metricsKey = "gormpher:metrics"
+
+// metricsLabels is the labels of request, stored in gin.Context and the db settings.
+type metricsLabels struct {
+	recorder MetricsRecorder
+	object   string
+	action   string
+	view     string
+}
+
+// Metrics is the MetricsRecorder in memory, served in Prometheus text format:
+//
+//	metrics := NewMetrics("gormpher")
+//	r.GET("/metrics", gin.WrapH(metrics))
+type Metrics struct {
+	Namespace       string
+	DurationBuckets []float64 // the buckets of request and query durations in seconds
+	RowsBuckets     []float64 // the buckets of rows returned
+
+	mu            sync.Mutex
+	requests      map[string]float64 // labels => count
+	durations     map[string]*histogram
+	rows          map[string]*histogram
+	queryDuration map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64 // the count of each bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// NewMetrics return the Metrics with the default buckets.
+func NewMetrics(namespace string) *Metrics {
+	return &Metrics{
+		Namespace:       namespace,
+		DurationBuckets: DefaultDurationBuckets,
+		RowsBuckets:     DefaultRowsBuckets,
+		requests:        make(map[string]float64),
+		durations:       make(map[string]*histogram),
+		rows:            make(map[string]*histogram),
+		queryDuration:   make(map[string]*histogram),
+	}
+}
+
+func (m *Metrics) ObserveRequest(object, action, view string, status int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[formatLabels("object", object, "action", action, "view", view, "code", strconv.Itoa(status))]++
+	observe(m.durations, formatLabels("object", object, "action", action, "view", view), m.DurationBuckets, duration.Seconds())
+}
+
+func (m *Metrics) ObserveRows(object, action, view string, rows int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	observe(m.rows, formatLabels("object", object, "action", action, "view", view), m.RowsBuckets, float64(rows))
+}
+
+func (m *Metrics) ObserveQuery(object, action, view string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	observe(m.queryDuration, formatLabels("object", object, "action", action, "view", view), m.DurationBuckets, duration.Seconds())
+}
+
+func observe(vec map[string]*histogram, labels string, buckets []float64, v float64) {
+	h, ok := vec[labels]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(buckets))}
+		vec[labels] = h
+	}
+	for i, le := range buckets {
+		if v <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// formatLabels return the labels of Prometheus text format, such as `object="user",action="get"`.
+func formatLabels(kvs ...string) string {
+	var sb strings.Builder
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	for i := 0; i+1 < len(kvs); i += 2 {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, `%s="%s"`, kvs[i], replacer.Replace(kvs[i+1]))
+	}
+	return sb.String()
+}
+
+// ServeHTTP write the metrics in Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(m.String()))
+}
+
+func (m *Metrics) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name := func(s string) string {
+		if m.Namespace == "" {
+			return s
+		}
+		return m.Namespace + "_" + s
+	}
+
+	var sb strings.Builder
+	requests := name("requests_total")
+	fmt.Fprintf(&sb, "# HELP %s The requests of WebObject routes.\n# TYPE %s counter\n", requests, requests)
+	for _, labels := range sortedKeys(m.requests) {
+		fmt.Fprintf(&sb, "%s{%s} %s\n", requests, labels, formatFloat(m.requests[labels]))
+	}
+
+	writeHistogram(&sb, name("request_duration_seconds"), "The durations of WebObject requests.", m.durations, m.DurationBuckets)
+	writeHistogram(&sb, name("rows_returned"), "The rows returned by get and query.", m.rows, m.RowsBuckets)
+	writeHistogram(&sb, name("db_query_duration_seconds"), "The durations of database queries.", m.queryDuration, m.DurationBuckets)
+	return sb.String()
+}
+
+func writeHistogram(sb *strings.Builder, name, help string, vec map[string]*histogram, buckets []float64) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for _, labels := range sortedKeys(vec) {
+		h := vec[labels]
+		var cumulative uint64
+		for i, le := range buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(sb, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(le), cumulative)
+		}
+		fmt.Fprintf(sb, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+		fmt.Fprintf(sb, "%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+		fmt.Fprintf(sb, "%s_count{%s} %d\n", name, labels, h.count)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// instrument record the request of route with Metrics.
+func (obj *WebObject) instrument(action int, view string, h gin.HandlerFunc) gin.HandlerFunc {
+	if obj.Metrics == nil {
+		return h
+	}
+	return func(c *gin.Context) {
+		labels := &metricsLabels{
+			recorder: obj.Metrics,
+			object:   obj.Name,
+			action:   ActionName(action),
+			view:     view,
+		}
+		c.Set(metricsKey, labels)
+		start := time.Now()
+		h(c)
+		obj.Metrics.ObserveRequest(labels.object, labels.action, labels.view, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// observeRows record the rows returned of request in metrics and span.
+func (obj *WebObject) observeRows(c *gin.Context, rows int) {
+	setSpanAttributes(c, Attribute{AttrRows, rows})
+	if labels, ok := c.Value(metricsKey).(*metricsLabels); ok {
+		labels.recorder.ObserveRows(labels.object, labels.action, labels.view, rows)
+	}
+}
+
+// registered callbacks of gorm.Config
+var metricsCallbacks sync.Map
+
+// RegisterCallbacks register the gorm callbacks of metrics, tracing and slow query
+// log on dbs once. The registration of gorm callbacks is not concurrency-safe, so it
+// should be called at startup, before the dbs are used by requests, such as:
+//
+//	db, _ := gorm.Open(...)
+//	gormpher.RegisterCallbacks(db)
+//
+// The queries of the dbs not registered are not recorded, traced or logged.
+func RegisterCallbacks(dbs ...*gorm.DB) {
+	for _, db := range dbs {
+		if _, loaded := metricsCallbacks.LoadOrStore(db.Config, true); !loaded {
+			registerMetricsCallbacks(db)
+		}
+		if _, loaded := traceCallbacks.LoadOrStore(db.Config, true); !loaded {
+			registerTraceCallbacks(db)
+		}
+		if _, loaded := logCallbacks.LoadOrStore(db.Config, true); !loaded {
+			registerLogCallbacks(db)
+		}
+	}
+}
+
+// instrumentDB record the durations of queries of db with the labels of request,
+// the callbacks of db are registered by RegisterCallbacks.
+func instrumentDB(c *gin.Context, db *gorm.DB) *gorm.DB {
+	labels, ok := c.Value(metricsKey).(*metricsLabels)
+	if !ok {
+		return db
+	}
+	// new session, so the instrumented db can be reused by multiple statements
+	return db.Set(metricsKey, labels).Session(&gorm.Session{})
+}
+
+func registerMetricsCallbacks(db *gorm.DB) {
+	const startKey = "gormpher:metrics_start"
+	before := func(db *gorm.DB) {
+		if _, ok := db.Get(metricsKey); ok {
+			db.InstanceSet(startKey, time.Now())
+		}
+	}
+	after := func(db *gorm.DB) {
+		v, ok := db.Get(metricsKey)
+		if !ok {
+			return
+		}
+		start, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		labels := v.(*metricsLabels)
+		labels.recorder.ObserveQuery(labels.object, labels.action, labels.view, time.Since(start.(time.Time)))
+	}
+
+	callback := db.Callback()
+	callback.Query().Before("gorm:query").Register("gormpher:metrics_before_query", before)
+	callback.Query().After("gorm:after_query").Register("gormpher:metrics_after_query", after)
+	callback.Row().Before("gorm:row").Register("gormpher:metrics_before_row", before)
+	callback.Row().After("gorm:row").Register("gormpher:metrics_after_row", after)
+	callback.Create().Before("gorm:create").Register("gormpher:metrics_before_create", before)
+	callback.Create().After("gorm:after_create").Register("gormpher:metrics_after_create", after)
+	callback.Update().Before("gorm:update").Register("gormpher:metrics_before_update", before)
+	callback.Update().After("gorm:after_update").Register("gormpher:metrics_after_update", after)
+	callback.Delete().Before("gorm:delete").Register("gormpher:metrics_before_delete", before)
+	callback.Delete().After("gorm:after_delete").Register("gormpher:metrics_after_delete", after)
+}
@@ -0,0 +1,126 @@
+package gormpher
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMetrics(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{})
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 9})
+	db.Create(&tuser{ID: 2, Name: "bob", Age: 10})
+	// registered once at startup
+	RegisterCallbacks(db)
+	RegisterCallbacks(db)
+
+	metrics := NewMetrics("gormpher")
+	r := gin.Default()
+	r.GET("/metrics", gin.WrapH(metrics))
+	RegisterObjects(r.Group("api"), []WebObject{
+		{
+			Name:    "user",
+			Model:   tuser{},
+			GetDB:   func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			Metrics: metrics,
+			Views: []QueryView{
+				{Name: "adults", Method: http.MethodGet},
+			},
+		},
+	})
+
+	client := NewTestClient(r)
+	w := client.Get("/api/user/1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = client.Get("/api/user/100")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = client.Post("/api/user", []byte("{}"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = client.Get("/api/user/adults")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = client.Get("/metrics")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain; version=0.0.4")
+	body := w.Body.String()
+	assert.Contains(t, body, "# TYPE gormpher_requests_total counter\n")
+	assert.Contains(t, body, `gormpher_requests_total{object="user",action="get",view="",code="200"} 1`)
+	assert.Contains(t, body, `gormpher_requests_total{object="user",action="get",view="",code="404"} 1`)
+	assert.Contains(t, body, `gormpher_requests_total{object="user",action="query",view="",code="200"} 1`)
+	assert.Contains(t, body, `gormpher_requests_total{object="user",action="view",view="adults",code="200"} 1`)
+	assert.Contains(t, body, `gormpher_request_duration_seconds_count{object="user",action="get",view=""} 2`)
+	assert.Contains(t, body, `gormpher_rows_returned_bucket{object="user",action="get",view="",le="1"} 1`)
+	assert.Contains(t, body, `gormpher_rows_returned_bucket{object="user",action="query",view="",le="1"} 0`)
+	assert.Contains(t, body, `gormpher_rows_returned_sum{object="user",action="query",view=""} 2`)
+	assert.Contains(t, body, `gormpher_db_query_duration_seconds_count{object="user",action="get",view=""} 2`)
+	assert.Contains(t, body, `gormpher_db_query_duration_seconds_count{object="user",action="view",view="adults"}`)
+
+	// the queries out of requests are not recorded
+	var count int64
+	db.Model(&tuser{}).Count(&count)
+	assert.Equal(t, body, metrics.String())
+}
+
+func TestMetricsConcurrent(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	db.AutoMigrate(tuser{})
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 9})
+	RegisterCallbacks(db)
+
+	metrics := NewMetrics("")
+	getDB := func(c *gin.Context, isCreate bool) *gorm.DB { return db }
+	r := gin.New()
+	err := RegisterObjects(r, []WebObject{
+		{Name: "user", Model: tuser{}, GetDB: getDB, Metrics: metrics},
+		{Name: "plain", Model: tuser{}, GetDB: getDB},
+	})
+	assert.Nil(t, err)
+
+	// the callbacks are not registered by requests, run with -race
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, http.StatusOK, NewTestClient(r).Get("/plain/1").Code)
+		}()
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, http.StatusOK, NewTestClient(r).Get("/user/1").Code)
+		}()
+	}
+	wg.Wait()
+	assert.Contains(t, metrics.String(), `db_query_duration_seconds_count{object="user",action="get",view=""} 10`)
+}
+
+func TestMetricsFormat(t *testing.T) {
+	m := NewMetrics("")
+	m.DurationBuckets = []float64{0.1, 1}
+	m.ObserveRequest("a\"b", "get", "", 200, 500*time.Millisecond)
+	m.ObserveRequest("a\"b", "get", "", 200, 2*time.Second)
+
+	assert.Equal(t, `# HELP requests_total The requests of WebObject routes.
+# TYPE requests_total counter
+requests_total{object="a\"b",action="get",view="",code="200"} 2
+# HELP request_duration_seconds The durations of WebObject requests.
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{object="a\"b",action="get",view="",le="0.1"} 0
+request_duration_seconds_bucket{object="a\"b",action="get",view="",le="1"} 1
+request_duration_seconds_bucket{object="a\"b",action="get",view="",le="+Inf"} 2
+request_duration_seconds_sum{object="a\"b",action="get",view=""} 2.5
+request_duration_seconds_count{object="a\"b",action="get",view=""} 2
+# HELP rows_returned The rows returned by get and query.
+# TYPE rows_returned histogram
+# HELP db_query_duration_seconds The durations of database queries.
+# TYPE db_query_duration_seconds histogram
+`, m.String())
+}
@@ -2,12 +2,16 @@ package gormpher
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"path"
 	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -29,9 +33,38 @@ const (
 	DELETE = 1 << 4
 	QUERY  = 1 << 5
 	BATCH  = 1 << 6
+	VIEW   = 1 << 7 // for Authorizer and Scope only, views are always registered
+	IMPORT = 1 << 8 // not allowed by default
 )
 
+// ActionName return the name of action, such as "get", "create".
+func ActionName(action int) string {
+	switch action {
+	case GET:
+		return "get"
+	case CREATE:
+		return "create"
+	case EDIT:
+		return "edit"
+	case DELETE:
+		return "delete"
+	case QUERY:
+		return "query"
+	case BATCH:
+		return "batch"
+	case VIEW:
+		return "view"
+	case IMPORT:
+		return "import"
+	}
+	return "unknown"
+}
+
 type GetDB func(c *gin.Context, isCreate bool) *gorm.DB // designed for group
+
+// SelectDB return the db of action and obj, such as ReplicaRouter.SelectDB
+// which routes the reads to replicas.
+type SelectDB func(c *gin.Context, obj *WebObject, action int) *gorm.DB
 type PrepareQuery func(db *gorm.DB, c *gin.Context) (*gorm.DB, *QueryForm, error)
 
 type (
@@ -45,6 +78,10 @@ type QueryView struct {
 	Name    string
 	Method  string
 	Prepare PrepareQuery
+
+	// Cache-Control header of the view, such as "public, max-age=60",
+	// use WebObject.CacheControl when empty.
+	CacheControl string
 }
 
 type WebObject struct {
@@ -52,6 +89,8 @@ type WebObject struct {
 	Group string
 	Name  string
 	GetDB GetDB
+	// SelectDB is used instead of GetDB when set, for the routing of actions.
+	SelectDB SelectDB
 
 	// config
 	// Pagination   bool
@@ -64,6 +103,85 @@ type WebObject struct {
 	SearchFields []string
 	Views        []QueryView
 
+	// for optimistic concurrency, the struct field name of version column,
+	// such as "Version" (integer) or "UpdatedAt" (time.Time).
+	// When set, update and delete require If-Match header or version value,
+	// and batch delete requires the pairs of key and version, such as [{"id":1,"version":3}].
+	VersionField string
+
+	// Cache-Control header for get and query, such as "private, max-age=10".
+	CacheControl string
+
+	// for JSON:API, the request which accepts or sends application/vnd.api+json
+	// is served with JSON:API documents, the preloads are relationships.
+	// The collection is queried by GET {name}?filter[name]=alice&sort=-id&page[limit]=10&include=...
+	JSONAPI bool
+
+	// the max rows of CSV and XLSX export, default 10000. The query is exported
+	// as CSV, XLSX or streaming NDJSON with "export" option or Accept header,
+	// NDJSON streams all rows in pages with the preloads, same as the query.
+	MaxExportRows int
+
+	// the max rows (default 10000) and bytes (default 32MB) of import upload,
+	// the import over limits is rejected with 413.
+	MaxImportRows int
+	MaxImportSize int64
+
+	// the guardrails of query cost, such as the max filters and statement timeout.
+	QueryLimits QueryLimits
+
+	// for read-through cache of get and query, the cached values are invalidated by
+	// the writes of object, and expired after CacheTTL (default 1 minute), such as the
+	// stale rows of lagging replicas. CacheKey return the variation of cache key, such as
+	// the user id, it's required with Cache, as the rows may depend on the request by
+	// GetDB, Scope, Authorizer or the Prepare of views. Return "" for the shared rows.
+	Cache    Cache
+	CacheTTL time.Duration
+	CacheKey func(c *gin.Context) string
+
+	// for access control
+	Authorizer    Authorizer
+	Scope         ScopeFunc
+	FieldPolicies []FieldPolicy
+
+	// for multi-tenancy, the struct field name of tenant column, such as "TenantID".
+	// Every read and write is constrained by the tenant from TenantResolver,
+	// and the tenant column is set on create and can't be changed by client.
+	TenantField    string
+	TenantResolver TenantResolver
+
+	// for audit log, GetActor return the actor of request, such as user id.
+	Audit    AuditRecorder
+	GetActor func(ctx *gin.Context) string
+
+	// for change events, stream the writes with Server-Sent Events at GET {name}/events,
+	// and publish the writes in transaction to Publisher, such as Outbox.
+	// Every event is authorized as GET of the row, Stream can't be used with Scope.
+	Stream    *EventStream
+	Publisher EventPublisher
+
+	// for HTTP callbacks of changes, the deliveries are written in transaction
+	// and delivered by WebhookDispatcher.
+	Webhooks []Webhook
+
+	// for instrumentation, record the requests, rows returned and durations of
+	// queries labelled by object, action and view, such as Metrics.
+	// The db of queries should be registered by RegisterCallbacks at startup,
+	// same for Tracer and SlowQueryThreshold.
+	Metrics MetricsRecorder
+	// for tracing, start the spans of actions, GetDB, hooks and queries.
+	Tracer Tracer
+	// for logging, log the actions with Logger, and the queries above
+	// SlowQueryThreshold with the compiled filters. Zero threshold disables it.
+	// The SQL and filters are logged with placeholders, LogQueryVars logs the
+	// bound values instead, which may include the secrets and personal data.
+	Logger             *slog.Logger
+	SlowQueryThreshold time.Duration
+	LogQueryVars       bool
+
+	// for rate limiting, the requests over limit are rejected with 429.
+	RateLimiter *RateLimiter
+
 	// hooks
 	BeforeCreate BeforeCreateFunc
 	BeforeUpdate BeforeUpdateFunc
@@ -81,6 +199,14 @@ type WebObject struct {
 	// Map json tag to field kind. such as:
 	// UUID string `json:"id"` => {"id": string}
 	jsonToKinds map[string]reflect.Kind
+
+	versionColumn   string
+	versionJsonName string
+	tenantColumn    string
+	tenantJsonName  string
+
+	// the JSON:API resource types of the models of registered objects
+	jsonAPITypes map[reflect.Type]string
 }
 
 type Filter struct {
@@ -101,8 +227,10 @@ type QueryForm struct {
 	Keyword      string   `json:"keyword,omitempty"`
 	Filters      []Filter `json:"filters,omitempty"`
 	Orders       []Order  `json:"orders,omitempty"`
-	ViewFields   []string `json:"-"` // for view
-	searchFields []string `json:"-"` // for keyword
+	Export       string   `json:"export,omitempty"`
+	Fields       []string `json:"fields,omitempty"` // the json names to render
+	ViewFields   []string `json:"-"`                // for view
+	searchFields []string `json:"-"`                // for keyword
 }
 
 type QueryResult[T any] struct {
@@ -145,6 +273,39 @@ func (f *Filter) GetQuery() string {
 	return fmt.Sprintf("`%s` %s ?", f.Name, op)
 }
 
+// parseFilterValue parse the string value of filter by the kind of field,
+// such as "true" for bool, the value is kept when failed.
+func parseFilterValue(v any, kind reflect.Kind) any {
+	switch v := v.(type) {
+	case []string:
+		vals := make([]any, len(v))
+		for i, s := range v {
+			vals[i] = parseFilterValue(s, kind)
+		}
+		return vals
+	case string:
+		var val any
+		var err error
+		switch {
+		case kind == reflect.Bool:
+			val, err = strconv.ParseBool(v)
+		case isIntegerKind(kind) && kind >= reflect.Uint:
+			val, err = strconv.ParseUint(v, 10, 64)
+		case isIntegerKind(kind):
+			val, err = strconv.ParseInt(v, 10, 64)
+		case kind == reflect.Float32 || kind == reflect.Float64:
+			val, err = strconv.ParseFloat(v, 64)
+		default:
+			return v
+		}
+		if err != nil {
+			return v
+		}
+		return val
+	}
+	return v
+}
+
 // GetQuery return the combined order SQL statement.
 // such as "id DESC".
 func (o *Order) GetQuery() string {
@@ -160,81 +321,230 @@ func (obj *WebObject) RegisterObject(r gin.IRoutes) error {
 	if err := obj.Build(); err != nil {
 		return err
 	}
+	routes, err := obj.routes()
+	if err != nil {
+		return err
+	}
+	for _, route := range routes {
+		r.Handle(route.method, route.path, route.handler)
+	}
+	return nil
+}
 
+// objectRoute is the route of object.
+type objectRoute struct {
+	method  string
+	path    string
+	handler gin.HandlerFunc
+}
+
+// routes return the routes of built obj, the views are checked to not conflict with the other routes.
+func (obj *WebObject) routes() ([]objectRoute, error) {
+	var routes []objectRoute
 	p := filepath.Join(obj.Group, obj.Name)
 	allowMethods := obj.AllowMethods
 	if allowMethods == 0 {
 		allowMethods = GET | CREATE | EDIT | DELETE | QUERY | BATCH
 	}
+	add := func(method, path string, h gin.HandlerFunc) {
+		routes = append(routes, objectRoute{method: method, path: path, handler: h})
+	}
+
+	// record the metrics, spans and logs of request
+	instrument := func(action int, view string, h gin.HandlerFunc) gin.HandlerFunc {
+		return obj.instrument(action, view, obj.trace(action, view, obj.logRequest(action, view, h)))
+	}
+	// negotiate JSON:API before the handlers
+	handle := func(action int, view string, h gin.HandlerFunc) gin.HandlerFunc {
+		h = obj.limitRate(action, h)
+		if obj.JSONAPI {
+			next := h
+			h = func(c *gin.Context) {
+				negotiateJSONAPI(c)
+				next(c)
+			}
+		}
+		return instrument(action, view, h)
+	}
 
+	// the methods of key routes, such as GET {name}/:key
+	keyMethods := make(map[string]struct{})
 	if allowMethods&GET != 0 {
-		r.GET(filepath.Join(p, ":key"), func(c *gin.Context) {
+		keyMethods[http.MethodGet] = struct{}{}
+		add(http.MethodGet, filepath.Join(p, ":key"), handle(GET, "", func(c *gin.Context) {
 			handleGetObject(c, obj)
-		})
+		}))
 	}
 	if allowMethods&CREATE != 0 {
-		r.PUT(p, func(c *gin.Context) {
+		add(http.MethodPut, p, handle(CREATE, "", func(c *gin.Context) {
 			handleCreateObject(c, obj)
-		})
+		}))
 	}
 	if allowMethods&EDIT != 0 {
-		r.PATCH(filepath.Join(p, ":key"), func(c *gin.Context) {
+		keyMethods[http.MethodPatch] = struct{}{}
+		add(http.MethodPatch, filepath.Join(p, ":key"), handle(EDIT, "", func(c *gin.Context) {
 			handleUpdateObject(c, obj)
-		})
+		}))
 	}
 	if allowMethods&DELETE != 0 {
-		r.DELETE(filepath.Join(p, ":key"), func(c *gin.Context) {
+		keyMethods[http.MethodDelete] = struct{}{}
+		add(http.MethodDelete, filepath.Join(p, ":key"), handle(DELETE, "", func(c *gin.Context) {
 			handleDeleteObject(c, obj)
-		})
+		}))
 	}
 
 	if allowMethods&QUERY != 0 {
-		r.POST(p, func(c *gin.Context) {
-			handleQueryObject(c, obj, DefaultPrepareQuery)
-		})
+		add(http.MethodPost, p, handle(QUERY, "", func(c *gin.Context) {
+			handleQueryObject(c, obj, nil)
+		}))
+		add(http.MethodGet, p, handle(QUERY, "", func(c *gin.Context) {
+			handleQueryObject(c, obj, nil)
+		}))
 	}
 
 	if allowMethods&BATCH != 0 {
-		r.DELETE(p, func(c *gin.Context) {
+		add(http.MethodDelete, p, handle(BATCH, "", func(c *gin.Context) {
 			handleBatchDelete(c, obj)
+		}))
+	}
+
+	if allowMethods&IMPORT != 0 {
+		add(http.MethodPost, filepath.Join(p, "import"), instrument(IMPORT, "", obj.limitRate(IMPORT, func(c *gin.Context) {
+			handleImportObjects(c, obj)
+		})))
+	}
+
+	if obj.Stream != nil {
+		add(http.MethodGet, filepath.Join(p, "events"), func(c *gin.Context) {
+			handleEvents(c, obj)
 		})
 	}
 
+	var errs []error
+	paths := make(map[string]struct{})
+	for _, route := range routes {
+		paths[route.method+" "+route.path] = struct{}{}
+	}
 	for i := 0; i < len(obj.Views); i++ {
 		v := &obj.Views[i]
-		if v.Name == "" {
-			return errors.New("with invalid view")
+		if v.Name == "" || strings.ContainsAny(v.Name, "/:*") {
+			errs = append(errs, fmt.Errorf("with invalid view %q", v.Name))
+			continue
 		}
 		if v.Method == "" {
 			v.Method = http.MethodPost
 		}
 		if v.Prepare == nil {
-			v.Prepare = DefaultPrepareQuery
+			if v.Method == http.MethodGet {
+				v.Prepare = DefaultPrepareURLQuery
+			} else {
+				v.Prepare = DefaultPrepareQuery
+			}
 		}
-		r.Handle(v.Method, filepath.Join(p, v.Name), func(ctx *gin.Context) {
-			handleQueryObject(ctx, obj, v.Prepare)
-		})
+
+		vp := filepath.Join(p, v.Name)
+		if _, ok := paths[v.Method+" "+vp]; ok {
+			errs = append(errs, fmt.Errorf("view %s conflicts with route %s %s", v.Name, v.Method, vp))
+			continue
+		}
+		if _, ok := keyMethods[v.Method]; ok && obj.isKeyLike(v.Name) {
+			errs = append(errs, fmt.Errorf("view %s conflicts with key route %s %s", v.Name, v.Method, filepath.Join(p, ":key")))
+			continue
+		}
+		paths[v.Method+" "+vp] = struct{}{}
+		add(v.Method, vp, handle(VIEW, v.Name, func(ctx *gin.Context) {
+			handleQueryObject(ctx, obj, v)
+		}))
 	}
+	return routes, errors.Join(errs...)
+}
 
-	return nil
+// isKeyLike check the name is the value of integer primary key, such as "1".
+func (obj *WebObject) isKeyLike(name string) bool {
+	switch obj.jsonToKinds[obj.jsonPKName] {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		_, err := strconv.ParseInt(name, 10, 64)
+		return err == nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, err := strconv.ParseUint(name, 10, 64)
+		return err == nil
+	}
+	return false
 }
 
 func RegisterObject(r gin.IRoutes, obj *WebObject) error {
 	return obj.RegisterObject(r)
 }
 
-func RegisterObjects(r gin.IRoutes, objs []WebObject) {
+// RegisterObjects register the objs, all the objs are validated before registering, so
+// nothing is registered when any object is invalid. The errors of objects are joined,
+// such as the duplicate names and the conflicted routes.
+func RegisterObjects(r gin.IRoutes, objs []WebObject) error {
+	routes, err := buildRoutes(objs)
+	if err != nil {
+		return err
+	}
+	for _, route := range routes {
+		r.Handle(route.method, route.path, route.handler)
+	}
+	return nil
+}
+
+// buildRoutes build the objs and return the routes of all objs.
+func buildRoutes(objs []WebObject) ([]objectRoute, error) {
+	var routes []objectRoute
+	var errs []error
+	names := make(map[string]struct{})
+	owners := make(map[string]string) // method and path => object name
 	for idx := range objs {
 		obj := &objs[idx]
-		if err := obj.RegisterObject(r); err != nil {
-			log.Fatalf("RegisterObject [%s] fail %v\n", obj.Name, err)
+		if err := obj.Build(); err != nil {
+			errs = append(errs, fmt.Errorf("RegisterObject [%s] fail %w", obj.Name, err))
+			continue
+		}
+
+		// the Name is filled by Build when empty
+		p := filepath.Join(obj.Group, obj.Name)
+		if _, ok := names[p]; ok {
+			errs = append(errs, fmt.Errorf("RegisterObject [%s] fail duplicate object %s", obj.Name, p))
+			continue
+		}
+		names[p] = struct{}{}
+
+		objRoutes, err := obj.routes()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("RegisterObject [%s] fail %w", obj.Name, err))
+			continue
+		}
+		for _, route := range objRoutes {
+			k := route.method + " " + route.path
+			if owner, ok := owners[k]; ok {
+				errs = append(errs, fmt.Errorf("RegisterObject [%s] fail route %s conflicts with object %s", obj.Name, k, owner))
+				continue
+			}
+			owners[k] = obj.Name
+		}
+		routes = append(routes, objRoutes...)
+	}
+
+	// the model of objects has the same resource type, the Name of the first object
+	types := make(map[reflect.Type]string)
+	for idx := range objs {
+		if rt := objs[idx].modelElem; rt != nil {
+			if _, ok := types[rt]; !ok {
+				types[rt] = objs[idx].Name
+			}
 		}
 	}
+	for idx := range objs {
+		objs[idx].jsonAPITypes = types
+	}
+	return routes, errors.Join(errs...)
 }
 
 // Build fill the properties of obj.
 func (obj *WebObject) Build() error {
-	if obj.GetDB == nil {
+	if obj.GetDB == nil && obj.SelectDB == nil {
 		return fmt.Errorf("without db")
 	}
 
@@ -247,6 +557,7 @@ func (obj *WebObject) Build() error {
 	if obj.Name == "" {
 		obj.Name = strings.ToLower(rt.Name())
 	}
+	obj.jsonAPITypes = map[reflect.Type]string{rt: obj.Name}
 
 	obj.gormPKName = getPkColumnName(rt)
 	if obj.gormPKName == "" {
@@ -257,7 +568,23 @@ func (obj *WebObject) Build() error {
 	obj.jsonToKinds = make(map[string]reflect.Kind)
 	obj.parseFields(rt)
 
-	return nil
+	if err := obj.parseVersionField(); err != nil {
+		return err
+	}
+	if err := obj.checkCacheKey(); err != nil {
+		return err
+	}
+	if err := obj.checkRateLimiter(); err != nil {
+		return err
+	}
+	if err := obj.checkWebhooks(); err != nil {
+		return err
+	}
+	if obj.Stream != nil && obj.Scope != nil {
+		// the events are filtered in memory, the rows of Scope can't be
+		return fmt.Errorf("%s Stream can't be used with Scope", obj.Name)
+	}
+	return obj.parseTenantField()
 }
 
 // parseFields parse the following properties according to struct tag:
@@ -318,97 +645,244 @@ func (obj *WebObject) parseFields(rt reflect.Type) {
 	}
 }
 
+// fieldJSONName return the json name of struct field name, ok is false for unknown or "-" field.
+func fieldJSONName(rt reflect.Type, name string) (string, bool) {
+	f, ok := rt.FieldByName(name)
+	if !ok {
+		return "", false
+	}
+	jsonName := strings.Split(f.Tag.Get("json"), ",")[0]
+	if jsonName == "-" {
+		return "", false
+	}
+	if jsonName == "" {
+		jsonName = f.Name
+	}
+	return jsonName, true
+}
+
 func handleGetObject(c *gin.Context, obj *WebObject) {
-	key := c.Param("key")
-	db := obj.GetDB(c, false)
+	includes, err := obj.getIncludes(c)
+	if err != nil {
+		handleError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	val, err := obj.getObject(c, c.Param("key"))
+	if err != nil {
+		handleActionError(c, err)
+		return
+	}
+
+	var out any
+	if isJSONAPI(c) {
+		out, err = obj.jsonAPIDocument(c, val, includes)
+	} else {
+		out, err = obj.stripUnreadable(c, val)
+	}
+	if err != nil {
+		handleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	body, err := json.Marshal(out)
+	if err != nil {
+		handleError(c, http.StatusInternalServerError, err)
+		return
+	}
+	obj.setVary(c)
+	renderConditionalBody(c, body, obj.getRepresentationETag(c, val, body), obj.getLastModified(val), obj.CacheControl)
+}
+
+// getObject load the object of key with the preloads, then authorize and BeforeRender it.
+func (obj *WebObject) getObject(c *gin.Context, key string) (any, error) {
+	db, err := obj.getDB(c, GET)
+	if err != nil {
+		return nil, &ActionError{Code: http.StatusForbidden, Err: err}
+	}
 
 	val := reflect.New(obj.modelElem).Interface() // ptr
 
-	// preload
-	if len(obj.preloads) > 0 {
-		for _, preload := range obj.preloads {
-			db = db.Preload(preload)
+	cacheKey, cached := obj.cacheKey(c, "get", key)
+	if !cached || !obj.getCache(cacheKey, val) {
+		// preload
+		if len(obj.preloads) > 0 {
+			for _, preload := range obj.preloads {
+				db = db.Preload(preload)
+			}
+		}
+
+		db, cancel := obj.QueryLimits.withTimeout(c, db)
+		defer cancel()
+
+		result := db.Where(obj.gormPKName, key).Take(val)
+		if result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return nil, &ActionError{Code: http.StatusNotFound, Err: errNotFound}
+			}
+			return nil, queryError(c, result.Error)
+		}
+		if cached {
+			obj.setCache(cacheKey, val)
 		}
 	}
+	obj.observeRows(c, 1)
 
-	result := db.Where(obj.gormPKName, key).Take(val)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			handleError(c, http.StatusNotFound, "not found")
-		} else {
-			handleError(c, http.StatusInternalServerError, result.Error)
+	if err := obj.authorizeAction(c, GET, val); err != nil {
+		return nil, err
+	}
+
+	if obj.BeforeRender != nil {
+		if err := obj.beforeRender(c, val); err != nil {
+			return nil, &ActionError{Code: http.StatusInternalServerError, Err: err}
 		}
+	}
+	return val, nil
+}
+
+func handleCreateObject(c *gin.Context, obj *WebObject) {
+	vals, err := obj.bindVals(c)
+	if err != nil {
+		handleBindError(c, err)
 		return
 	}
 
-	if obj.BeforeRender != nil {
-		if err := obj.BeforeRender(c, val); err != nil {
+	val, err := obj.createObject(c, vals)
+	if err != nil {
+		handleActionError(c, err)
+		return
+	}
+
+	if isJSONAPI(c) {
+		doc, err := obj.jsonAPIDocument(c, val, nil)
+		if err != nil {
 			handleError(c, http.StatusInternalServerError, err)
 			return
 		}
+		doc.Links = nil
+		obj.setETag(c, val)
+		c.Header("Location", path.Join(c.Request.URL.Path, obj.getKey(val)))
+		c.JSON(http.StatusCreated, doc)
+		return
+	}
+
+	out, err := obj.stripUnreadable(c, val)
+	if err != nil {
+		handleError(c, http.StatusInternalServerError, err)
+		return
 	}
 
-	c.JSON(http.StatusOK, val)
+	obj.setETag(c, val)
+	c.JSON(http.StatusOK, out)
 }
 
-func handleCreateObject(c *gin.Context, obj *WebObject) {
-	var vals map[string]any
-	if err := c.BindJSON(&vals); err != nil {
-		handleError(c, http.StatusBadRequest, err)
-		return
+// createObject create the object of vals (json format key) with the field policies, tenant,
+// Authorizer and BeforeCreate, return the created object.
+func (obj *WebObject) createObject(c *gin.Context, vals map[string]any) (any, error) {
+	if err := obj.checkWritable(c, vals); err != nil {
+		return nil, &ActionError{Code: http.StatusForbidden, Err: err}
+	}
+
+	var tenant any
+	if obj.TenantField != "" {
+		var err error
+		if tenant, err = obj.getTenant(c); err != nil {
+			return nil, &ActionError{Code: http.StatusForbidden, Err: err}
+		}
+		if err := obj.checkTenant(vals, tenant); err != nil {
+			return nil, &ActionError{Code: http.StatusForbidden, Err: err}
+		}
 	}
 
 	val := reflect.New(obj.modelElem).Interface()
 
-	// fix mapstructure decode time.Time
-	// try parse time from different layout
 	config := mapstructure.DecoderConfig{
-		DecodeHook: func(f reflect.Type, t reflect.Type, data any) (any, error) {
-			if f.Kind() != reflect.String || t != reflect.TypeOf(time.Time{}) {
-				return data, nil
-			}
-			layouts := []string{time.RFC3339, "2006-01-02T15:04", time.DateTime, time.DateOnly}
-			for _, layout := range layouts {
-				if val, err := time.Parse(layout, data.(string)); err == nil {
-					return val, nil
-				}
-			}
-			return data, nil
-		},
-		Result: &val,
+		DecodeHook: decodeTime,
+		Result:     &val,
 	}
 	decoder, _ := mapstructure.NewDecoder(&config)
 	if err := decoder.Decode(vals); err != nil {
-		handleError(c, http.StatusBadRequest, err)
-		return
+		return nil, &ActionError{Code: http.StatusBadRequest, Err: err}
+	}
+
+	if obj.TenantField != "" {
+		obj.setTenant(val, tenant)
+	}
+
+	if err := obj.authorizeAction(c, CREATE, val); err != nil {
+		return nil, err
 	}
 
 	if obj.BeforeCreate != nil {
-		if err := obj.BeforeCreate(c, val, vals); err != nil {
-			handleError(c, http.StatusBadRequest, err)
-			return
+		if err := obj.beforeCreate(c, val, vals); err != nil {
+			return nil, &ActionError{Code: http.StatusBadRequest, Err: err}
 		}
 	}
 
-	result := obj.GetDB(c, true).Create(val)
-	if result.Error != nil {
-		handleError(c, http.StatusInternalServerError, result.Error)
-		return
+	err := obj.transaction(obj.openDB(c, CREATE), func(tx *gorm.DB) error {
+		if err := tx.Create(val).Error; err != nil {
+			return err
+		}
+		return obj.recordChanges(c, tx, CREATE, nil, val)
+	})
+	if err != nil {
+		return nil, writeError(err)
 	}
+	obj.invalidateCache()
+	obj.notify(c, CREATE, nil, val)
+	return val, nil
+}
 
-	c.JSON(http.StatusOK, val)
+// decodeTime fix mapstructure decode time.Time,
+// try parse time from different layout
+func decodeTime(f reflect.Type, t reflect.Type, data any) (any, error) {
+	if f.Kind() != reflect.String || t != reflect.TypeOf(time.Time{}) {
+		return data, nil
+	}
+	layouts := []string{time.RFC3339, "2006-01-02T15:04", time.DateTime, time.DateOnly}
+	for _, layout := range layouts {
+		if val, err := time.Parse(layout, data.(string)); err == nil {
+			return val, nil
+		}
+	}
+	return data, nil
 }
 
 func handleUpdateObject(c *gin.Context, obj *WebObject) {
-	key := c.Param("key")
+	inputVals, err := obj.bindVals(c)
+	if err != nil {
+		handleBindError(c, err)
+		return
+	}
 
-	var inputVals map[string]any
-	if err := c.BindJSON(&inputVals); err != nil {
-		handleError(c, http.StatusBadRequest, err)
+	model, err := obj.updateObject(c, c.Param("key"), inputVals, c.GetHeader("If-Match"))
+	if err != nil {
+		handleActionError(c, err)
 		return
 	}
 
-	db := obj.GetDB(c, false)
+	obj.setETag(c, model)
+	renderOK(c)
+}
+
+// updateObject update the EditFields of inputVals (json format key) with the field policies,
+// tenant, Authorizer, version and BeforeUpdate, return the reloaded object for ETag.
+func (obj *WebObject) updateObject(c *gin.Context, key string, inputVals map[string]any, ifMatch string) (any, error) {
+	if err := obj.checkWritable(c, inputVals); err != nil {
+		return nil, &ActionError{Code: http.StatusForbidden, Err: err}
+	}
+
+	db, err := obj.getDB(c, EDIT)
+	if err != nil {
+		return nil, &ActionError{Code: http.StatusForbidden, Err: err}
+	}
+
+	if obj.TenantField != "" {
+		tenant, _ := obj.getTenant(c)
+		if err := obj.checkTenant(inputVals, tenant); err != nil {
+			return nil, &ActionError{Code: http.StatusForbidden, Err: err}
+		}
+	}
 
 	var vals map[string]any = map[string]any{}
 	// can't edit primaryKey
@@ -418,6 +892,13 @@ func handleUpdateObject(c *gin.Context, obj *WebObject) {
 		if v == nil {
 			continue
 		}
+		// version and tenant are maintained by server
+		if obj.VersionField != "" && k == obj.versionJsonName {
+			continue
+		}
+		if obj.TenantField != "" && k == obj.tenantJsonName {
+			continue
+		}
 		// Check the kind to be edited.
 		kind, ok := obj.jsonToKinds[k]
 		if !ok {
@@ -430,8 +911,7 @@ func handleUpdateObject(c *gin.Context, obj *WebObject) {
 		}
 
 		if !checkType(kind, reflect.TypeOf(v).Kind()) {
-			handleError(c, http.StatusBadRequest, fname+" type not match")
-			return
+			return nil, &ActionError{Code: http.StatusBadRequest, Err: errors.New(fname + " type not match")}
 		}
 
 		vals[fname] = v
@@ -450,35 +930,90 @@ func handleUpdateObject(c *gin.Context, obj *WebObject) {
 	}
 
 	if len(vals) == 0 {
-		handleError(c, http.StatusBadRequest, "not changed")
-		return
+		return nil, &ActionError{Code: http.StatusBadRequest, Err: errors.New("not changed")}
 	}
 
-	if obj.BeforeUpdate != nil {
-		val := reflect.New(obj.modelElem).Interface()
+	var val any
+	if obj.BeforeUpdate != nil || obj.VersionField != "" || obj.TenantField != "" ||
+		obj.Authorizer != nil || obj.Scope != nil || obj.trackChanges() {
+		val = reflect.New(obj.modelElem).Interface()
 		if err := db.First(val, obj.gormPKName, key).Error; err != nil {
-			handleError(c, http.StatusNotFound, "not found")
-			return
+			return nil, &ActionError{Code: http.StatusNotFound, Err: errNotFound}
 		}
-		if err := obj.BeforeUpdate(c, val, inputVals); err != nil {
+	}
+
+	if err := obj.authorizeAction(c, EDIT, val); err != nil {
+		return nil, err
+	}
+
+	if obj.VersionField != "" {
+		if code, err := obj.checkVersion(ifMatch, val, inputVals); err != nil {
+			return nil, &ActionError{Code: code, Err: err}
+		}
+	}
+
+	if obj.BeforeUpdate != nil {
+		if err := obj.beforeUpdate(c, val, inputVals); err != nil {
+			return nil, &ActionError{Code: http.StatusBadRequest, Err: err}
+		}
+	}
+
+	model := reflect.New(obj.modelElem).Interface()
+	err = obj.transaction(db, func(tx *gorm.DB) error {
+		q := tx.Model(model).Where(obj.gormPKName, key)
+		if obj.VersionField != "" {
+			q = obj.withVersion(q, val)
+			obj.nextVersion(vals)
+		}
+		result := q.Updates(vals)
+		if result.Error != nil {
+			return result.Error
+		}
+		if obj.VersionField != "" && result.RowsAffected == 0 {
+			return errVersionMismatch
+		}
+		if obj.VersionField == "" && !obj.trackChanges() {
+			return nil
+		}
+		// reload for ETag and changes
+		if err := tx.First(model, obj.gormPKName, key).Error; err != nil {
+			return err
+		}
+		return obj.recordChanges(c, tx, EDIT, val, model)
+	})
+	if err != nil {
+		return nil, writeError(err)
+	}
+	obj.invalidateCache()
+	if obj.trackChanges() {
+		obj.notify(c, EDIT, val, model)
+	}
+	return model, nil
+}
+
+func handleDeleteObject(c *gin.Context, obj *WebObject) {
+	var vals map[string]any
+	if obj.VersionField != "" && c.Request.ContentLength > 0 {
+		if err := c.BindJSON(&vals); err != nil {
 			handleError(c, http.StatusBadRequest, err)
 			return
 		}
 	}
 
-	model := reflect.New(obj.modelElem).Interface()
-	result := db.Model(model).Where(obj.gormPKName, key).Updates(vals)
-	if result.Error != nil {
-		handleError(c, http.StatusInternalServerError, result.Error)
+	if err := obj.deleteObject(c, c.Param("key"), vals, c.GetHeader("If-Match")); err != nil {
+		handleActionError(c, err)
 		return
 	}
-
-	c.JSON(http.StatusOK, true)
+	renderOK(c)
 }
 
-func handleDeleteObject(c *gin.Context, obj *WebObject) {
-	key := c.Param("key")
-	db := obj.GetDB(c, false)
+// deleteObject delete the object of key with Authorizer, version and BeforeDelete,
+// vals is the version value of request body for VersionField.
+func (obj *WebObject) deleteObject(c *gin.Context, key string, vals map[string]any, ifMatch string) error {
+	db, err := obj.getDB(c, DELETE)
+	if err != nil {
+		return &ActionError{Code: http.StatusForbidden, Err: err}
+	}
 
 	val := reflect.New(obj.modelElem).Interface()
 
@@ -486,60 +1021,238 @@ func handleDeleteObject(c *gin.Context, obj *WebObject) {
 	result := db.First(val, obj.gormPKName, key)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			handleError(c, http.StatusNotFound, "not found")
-		} else {
-			handleError(c, http.StatusInternalServerError, result.Error)
+			return &ActionError{Code: http.StatusNotFound, Err: errNotFound}
+		}
+		return &ActionError{Code: http.StatusInternalServerError, Err: result.Error}
+	}
+
+	if err := obj.authorizeAction(c, DELETE, val); err != nil {
+		return err
+	}
+
+	if obj.VersionField != "" {
+		if code, err := obj.checkVersion(ifMatch, val, vals); err != nil {
+			return &ActionError{Code: code, Err: err}
 		}
-		return
 	}
 
 	if obj.BeforeDelete != nil {
-		if err := obj.BeforeDelete(c, val); err != nil {
+		if err := obj.beforeDelete(c, val); err != nil {
+			return &ActionError{Code: http.StatusBadRequest, Err: err}
+		}
+	}
+
+	err = obj.transaction(db, func(tx *gorm.DB) error {
+		q := tx
+		if obj.VersionField != "" {
+			q = obj.withVersion(q, val)
+		}
+		result := q.Delete(val)
+		if result.Error != nil {
+			return result.Error
+		}
+		if obj.VersionField != "" && result.RowsAffected == 0 {
+			return errVersionMismatch
+		}
+		return obj.recordChanges(c, tx, DELETE, val, nil)
+	})
+	if err != nil {
+		return writeError(err)
+	}
+	obj.invalidateCache()
+	obj.notify(c, DELETE, val, nil)
+	return nil
+}
+
+// handleBatchDelete delete the objects of keys, such as ["1","2"]. The objects with
+// VersionField require the pairs of key and version, such as [{"id":1,"version":3}].
+func handleBatchDelete(c *gin.Context, obj *WebObject) {
+	var form []string
+	var versions map[string]string
+	if obj.VersionField == "" {
+		if err := c.BindJSON(&form); err != nil {
 			handleError(c, http.StatusBadRequest, err)
 			return
 		}
+	} else {
+		var code int
+		var err error
+		if form, versions, code, err = obj.bindBatchVersions(c); err != nil {
+			handleError(c, code, err)
+			return
+		}
 	}
 
-	result = db.Delete(val)
-	if result.Error != nil {
-		handleError(c, http.StatusInternalServerError, result.Error)
+	if !obj.authorize(c, BATCH, nil) {
+		return
+	}
+
+	db, err := obj.getDB(c, BATCH)
+	if err != nil {
+		handleError(c, http.StatusForbidden, err)
+		return
+	}
+
+	// load the objects to be deleted for versions and changes
+	var items reflect.Value
+	if obj.trackChanges() || versions != nil {
+		items = reflect.New(reflect.SliceOf(obj.modelElem))
+		if err := db.Where(fmt.Sprintf("`%s` IN ?", obj.gormPKName), form).Find(items.Interface()).Error; err != nil {
+			handleError(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if versions != nil {
+		if err := obj.checkBatchVersions(items.Elem(), versions); err != nil {
+			handleError(c, http.StatusConflict, err)
+			return
+		}
+	}
+
+	err = obj.transaction(db, func(tx *gorm.DB) error {
+		if versions != nil {
+			for i := 0; i < items.Elem().Len(); i++ {
+				item := items.Elem().Index(i).Addr().Interface()
+				result := obj.withVersion(tx, item).Delete(item)
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					return errVersionMismatch
+				}
+			}
+		} else {
+			val := reflect.New(obj.modelElem).Interface()
+			if err := tx.Delete(&val, form).Error; err != nil {
+				return err
+			}
+		}
+		if !obj.trackChanges() {
+			return nil
+		}
+		for i := 0; i < items.Elem().Len(); i++ {
+			if err := obj.recordChanges(c, tx, BATCH, items.Elem().Index(i).Addr().Interface(), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		handleWriteError(c, err)
 		return
 	}
+	obj.invalidateCache()
+	if obj.trackChanges() {
+		for i := 0; i < items.Elem().Len(); i++ {
+			obj.notify(c, BATCH, items.Elem().Index(i).Addr().Interface(), nil)
+		}
+	}
 
-	c.JSON(http.StatusOK, true)
+	renderOK(c)
 }
 
-func handleBatchDelete(c *gin.Context, obj *WebObject) {
-	var form []string
-	if err := c.BindJSON(&form); err != nil {
+// handleQueryObject handle the query of obj, or the query view of obj when view is not nil.
+func handleQueryObject(c *gin.Context, obj *WebObject, view *QueryView) {
+	action := QUERY
+	prepareQuery := DefaultPrepareQuery
+	cacheControl := obj.CacheControl
+	if view != nil {
+		action = VIEW
+		prepareQuery = view.Prepare
+		if view.CacheControl != "" {
+			cacheControl = view.CacheControl
+		}
+	} else if c.Request.Method == http.MethodGet {
+		prepareQuery = DefaultPrepareURLQuery
+	}
+
+	if !obj.authorize(c, action, nil) {
+		return
+	}
+
+	db, err := obj.getDB(c, action)
+	if err != nil {
+		handleError(c, http.StatusForbidden, err)
+		return
+	}
+
+	db, form, err := prepareQuery(db, c)
+	if err != nil {
 		handleError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	db := obj.GetDB(c, false)
+	includes, err := obj.getIncludes(c)
+	if err != nil {
+		handleError(c, http.StatusBadRequest, err)
+		return
+	}
 
-	val := reflect.New(obj.modelElem).Interface()
-	r := db.Delete(&val, form)
-	if r.Error != nil {
-		handleError(c, http.StatusInternalServerError, r.Error)
+	hiddenFields, err := obj.prepareForm(c, form)
+	if err != nil {
+		handleActionError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, true)
-}
+	db, cancel := obj.QueryLimits.withTimeout(c, db)
+	defer cancel()
 
-func handleQueryObject(c *gin.Context, obj *WebObject, prepareQuery PrepareQuery) {
-	db, form, err := prepareQuery(obj.GetDB(c, false), c)
+	format, err := getExportFormat(c, form)
 	if err != nil {
 		handleError(c, http.StatusBadRequest, err)
 		return
 	}
+	if format != "" {
+		if obj.RateLimiter != nil && !obj.allowRate(c, exportRateKey, obj.RateLimiter.Export) {
+			return
+		}
+		handleExportObjects(c, db, obj, form, format, hiddenFields)
+		return
+	}
+
+	r, err := obj.queryObjects(c, db, view, form)
+	if err != nil {
+		handleActionError(c, err)
+		return
+	}
+
+	// the collection is validated by the ETag of body only
+	obj.setVary(c)
+	if isJSONAPI(c) {
+		doc, err := obj.jsonAPIQueryDocument(c, view, r, form, includes, hiddenFields)
+		if err != nil {
+			handleError(c, http.StatusInternalServerError, err)
+			return
+		}
+		renderConditional(c, doc, "", time.Time{}, cacheControl)
+		return
+	}
+
+	if r.Items, err = obj.stripFields(r.Items, hiddenFields); err != nil {
+		handleError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	renderConditional(c, r, "", time.Time{}, cacheControl)
+}
+
+// prepareForm check the QueryLimits of form, keep the filters, orders and search fields
+// which are allowed and readable, return the unreadable and unselected fields to hide.
+func (obj *WebObject) prepareForm(c *gin.Context, form *QueryForm) (map[string]struct{}, error) {
+	if err := obj.QueryLimits.check(form); err != nil {
+		return nil, &ActionError{Code: http.StatusBadRequest, Err: err}
+	}
+	// the unreadable fields can't be filtered, ordered or searched.
+	unreadableFields := obj.unreadableFields(c)
 
 	// Use struct{} makes map like set.
 	var filterFields = make(map[string]struct{})
 	for _, k := range obj.FilterFields {
 		filterFields[k] = struct{}{}
 	}
+	for k := range unreadableFields {
+		delete(filterFields, k)
+	}
 	if len(filterFields) > 0 {
 		var stripFilters []Filter
 		for i := 0; i < len(form.Filters); i++ {
@@ -552,6 +1265,7 @@ func handleQueryObject(c *gin.Context, obj *WebObject, prepareQuery PrepareQuery
 			if _, ok := filterFields[field]; !ok {
 				continue
 			}
+			filter.Value = parseFilterValue(filter.Value, obj.jsonToKinds[filter.Name])
 			filter.Name = getColumnName(obj.modelElem, field)
 			stripFilters = append(stripFilters, filter)
 		}
@@ -564,6 +1278,9 @@ func handleQueryObject(c *gin.Context, obj *WebObject, prepareQuery PrepareQuery
 	for _, k := range obj.OrderFields {
 		orderFields[k] = struct{}{}
 	}
+	for k := range unreadableFields {
+		delete(orderFields, k)
+	}
 	if len(orderFields) > 0 {
 		var stripOrders []Order
 		for i := 0; i < len(form.Orders); i++ {
@@ -586,6 +1303,9 @@ func handleQueryObject(c *gin.Context, obj *WebObject, prepareQuery PrepareQuery
 	if form.Keyword != "" {
 		form.searchFields = []string{}
 		for _, v := range obj.SearchFields {
+			if _, ok := unreadableFields[v]; ok {
+				continue
+			}
 			form.searchFields = append(form.searchFields, getColumnName(obj.modelElem, v))
 		}
 	}
@@ -598,10 +1318,39 @@ func handleQueryObject(c *gin.Context, obj *WebObject, prepareQuery PrepareQuery
 		form.ViewFields = stripViewFields
 	}
 
-	r, err := QueryObjects(db, obj, form)
-	if err != nil {
-		handleError(c, http.StatusInternalServerError, err)
-		return
+	// the unselected fields are hidden in response
+	hiddenFields := unreadableFields
+	if len(form.Fields) > 0 {
+		var err error
+		if hiddenFields, err = obj.selectFields(form, unreadableFields); err != nil {
+			return nil, &ActionError{Code: http.StatusBadRequest, Err: err}
+		}
+	}
+
+	setSpanAttributes(c, Attribute{AttrFilters, len(form.Filters)})
+	logFilters(c, form.Filters)
+	return hiddenFields, nil
+}
+
+// queryObjects query the objects of form with the cache, then BeforeRender the objects.
+func (obj *WebObject) queryObjects(c *gin.Context, db *gorm.DB, view *QueryView, form *QueryForm) (QueryResult[any], error) {
+	var r QueryResult[any]
+	var err error
+	var cacheKey string
+	cached := false
+	if formKey, err := queryCacheKey(view, form); err == nil {
+		cacheKey, cached = obj.cacheKey(c, "query", formKey)
+	}
+	if !cached || !obj.getCache(cacheKey, &r) {
+		if r, err = QueryObjects(db, obj, form); err != nil {
+			return r, queryError(c, err)
+		}
+		if cached {
+			obj.setCache(cacheKey, &r)
+		}
+	}
+	if items := reflect.ValueOf(r.Items); items.Kind() == reflect.Slice {
+		obj.observeRows(c, items.Len())
 	}
 
 	if obj.BeforeRender != nil {
@@ -609,47 +1358,91 @@ func handleQueryObject(c *gin.Context, obj *WebObject, prepareQuery PrepareQuery
 		if vals.Kind() == reflect.Slice {
 			for i := 0; i < vals.Len(); i++ {
 				v := vals.Index(i).Addr().Interface()
-				if err := obj.BeforeRender(c, v); err != nil {
-					handleError(c, http.StatusInternalServerError, err)
-					return
+				if err := obj.beforeRender(c, v); err != nil {
+					return r, &ActionError{Code: http.StatusInternalServerError, Err: err}
 				}
 				vals.Index(i).Set(reflect.ValueOf(v).Elem())
 			}
 		}
 	}
-
-	c.JSON(http.StatusOK, r)
+	return r, nil
 }
 
-// QueryObjects execute query and return data.
-func QueryObjects(db *gorm.DB, obj *WebObject, form *QueryForm) (r QueryResult[any], err error) {
-	// the real name of the db tableName
-	tableName := db.NamingStrategy.TableName(obj.modelElem.Name())
+// selectFields select the columns of form.Fields (json names) with ViewFields, the primary
+// key is always selected. Return the unreadable and unselected fields to hide.
+func (obj *WebObject) selectFields(form *QueryForm, unreadableFields map[string]struct{}) (map[string]struct{}, error) {
+	preloads := make(map[string]struct{})
+	for _, v := range obj.preloads {
+		preloads[v] = struct{}{}
+	}
 
-	for _, v := range form.Filters {
-		if q := v.GetQuery(); q != "" {
-			db = db.Where(fmt.Sprintf("%s.%s", tableName, q), v.Value)
+	selected := make(map[string]struct{})
+	var columns []string
+	withPreload := false
+	for _, name := range form.Fields {
+		field, ok := obj.jsonToFields[name]
+		if !ok {
+			continue
+		}
+		if f, ok := obj.modelElem.FieldByName(field); !ok || f.Anonymous {
+			continue
+		}
+		if _, ok := unreadableFields[field]; ok {
+			continue
+		}
+		if _, ok := selected[field]; ok {
+			continue
 		}
+		selected[field] = struct{}{}
+		if _, ok := preloads[field]; ok {
+			withPreload = true
+		} else {
+			columns = append(columns, getColumnName(obj.modelElem, field))
+		}
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("invalid fields")
 	}
 
-	for _, v := range form.Orders {
-		if q := v.GetQuery(); q != "" {
-			db = db.Order(fmt.Sprintf("%s.%s", tableName, q))
+	hiddenFields := make(map[string]struct{})
+	for k := range unreadableFields {
+		hiddenFields[k] = struct{}{}
+	}
+	for _, field := range obj.jsonToFields {
+		if _, ok := selected[field]; !ok {
+			hiddenFields[field] = struct{}{}
 		}
 	}
 
-	if form.Keyword != "" && len(form.searchFields) > 0 {
-		var query []string
-		for _, v := range form.searchFields {
-			query = append(query, fmt.Sprintf("%s.%s LIKE @keyword", tableName, v))
-		}
-		searchKey := strings.Join(query, " OR ")
-		db = db.Where(searchKey, sql.Named("keyword", "%"+form.Keyword+"%"))
+	// the foreign keys of preloads are unknown, so select all columns
+	if withPreload {
+		return hiddenFields, nil
 	}
 
+	pkColumn := getColumnName(obj.modelElem, obj.jsonToFields[obj.jsonPKName])
+	if _, ok := selected[obj.jsonToFields[obj.jsonPKName]]; !ok {
+		columns = append([]string{pkColumn}, columns...)
+	}
 	if len(form.ViewFields) > 0 {
-		db = db.Select(form.ViewFields)
+		views := make(map[string]struct{})
+		for _, v := range form.ViewFields {
+			views[v] = struct{}{}
+		}
+		var viewColumns []string
+		for _, v := range columns {
+			if _, ok := views[v]; ok {
+				viewColumns = append(viewColumns, v)
+			}
+		}
+		columns = viewColumns
 	}
+	form.ViewFields = columns
+	return hiddenFields, nil
+}
+
+// QueryObjects execute query and return data.
+func QueryObjects(db *gorm.DB, obj *WebObject, form *QueryForm) (r QueryResult[any], err error) {
+	db = buildQuery(db, obj, form)
 
 	r.Pos = form.Pos
 	r.Limit = form.Limit
@@ -657,7 +1450,10 @@ func QueryObjects(db *gorm.DB, obj *WebObject, form *QueryForm) (r QueryResult[a
 
 	var count int64
 	model := reflect.New(obj.modelElem).Interface()
-	if err := db.Model(model).Count(&count).Error; err != nil {
+	_, span := startDBSpan(db, "gormpher.count", Attribute{AttrFilters, len(form.Filters)})
+	err = db.Model(model).Count(&count).Error
+	endSpan(span, err)
+	if err != nil {
 		return r, err
 	}
 	if count <= 0 {
@@ -680,7 +1476,10 @@ func QueryObjects(db *gorm.DB, obj *WebObject, form *QueryForm) (r QueryResult[a
 		}
 	}
 
+	db, span = startDBSpan(db, "gormpher.find", Attribute{AttrFilters, len(form.Filters)})
 	result := db.Offset(offset).Limit(form.Limit).Find(items.Interface())
+	span.SetAttributes(Attribute{AttrRows, int(result.RowsAffected)})
+	endSpan(span, result.Error)
 	if result.Error != nil {
 		return r, result.Error
 	}
@@ -689,6 +1488,38 @@ func QueryObjects(db *gorm.DB, obj *WebObject, form *QueryForm) (r QueryResult[a
 	return r, nil
 }
 
+// buildQuery apply the filters, orders, keyword and view fields of form to db.
+func buildQuery(db *gorm.DB, obj *WebObject, form *QueryForm) *gorm.DB {
+	// the real name of the db tableName
+	tableName := db.NamingStrategy.TableName(obj.modelElem.Name())
+
+	for _, v := range form.Filters {
+		if q := v.GetQuery(); q != "" {
+			db = db.Where(fmt.Sprintf("%s.%s", tableName, q), v.Value)
+		}
+	}
+
+	for _, v := range form.Orders {
+		if q := v.GetQuery(); q != "" {
+			db = db.Order(fmt.Sprintf("%s.%s", tableName, q))
+		}
+	}
+
+	if form.Keyword != "" && len(form.searchFields) > 0 {
+		var query []string
+		for _, v := range form.searchFields {
+			query = append(query, fmt.Sprintf("%s.%s LIKE @keyword", tableName, v))
+		}
+		searchKey := strings.Join(query, " OR ")
+		db = db.Where(searchKey, sql.Named("keyword", "%"+form.Keyword+"%"))
+	}
+
+	if len(form.ViewFields) > 0 {
+		db = db.Select(form.ViewFields)
+	}
+	return db
+}
+
 // DefaultPrepareQuery return default QueryForm.
 func DefaultPrepareQuery(db *gorm.DB, c *gin.Context) (*gorm.DB, *QueryForm, error) {
 	var form QueryForm
@@ -698,6 +1529,99 @@ func DefaultPrepareQuery(db *gorm.DB, c *gin.Context) (*gorm.DB, *QueryForm, err
 		}
 	}
 
+	form.fixPosLimit()
+	return db, &form, nil
+}
+
+// DefaultPrepareURLQuery return QueryForm from the URL query parameters, for GET query:
+//
+//	pos=0&limit=10&pagination=true&keyword=alice
+//	filter[name]=alice&filter[age][greater_or_equal]=18&filter[id][in]=1,2
+//	sort=-createdAt,name
+//	fields=id,name
+//
+// The JSON:API pagination page[offset]&page[limit] or page[number]&page[size] is also accepted.
+func DefaultPrepareURLQuery(db *gorm.DB, c *gin.Context) (*gorm.DB, *QueryForm, error) {
+	var form QueryForm
+	query := c.Request.URL.Query()
+
+	var err error
+	getInt := func(name string) int {
+		v := query.Get(name)
+		if v == "" || err != nil {
+			return 0
+		}
+		n, e := strconv.Atoi(v)
+		if e != nil {
+			err = fmt.Errorf("invalid %s", name)
+		}
+		return n
+	}
+
+	switch {
+	case query.Has("page[number]") || query.Has("page[size]"):
+		form.Pagination = true
+		form.Pos = getInt("page[number]")
+		form.Limit = getInt("page[size]")
+	case query.Has("page[offset]") || query.Has("page[limit]"):
+		form.Pos = getInt("page[offset]")
+		form.Limit = getInt("page[limit]")
+	default:
+		form.Pagination, _ = strconv.ParseBool(query.Get("pagination"))
+		form.Pos = getInt("pos")
+		form.Limit = getInt("limit")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	form.Keyword = query.Get("keyword")
+	form.Export = query.Get("export")
+
+	// the filters in order of keys, so the statements are stable
+	var keys []string
+	for k := range query {
+		if strings.HasPrefix(k, "filter[") && strings.HasSuffix(k, "]") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		vs := query[k]
+		// filter[name] or filter[name][op]
+		parts := strings.Split(k[len("filter["):len(k)-1], "][")
+		filter := Filter{Name: parts[0], Op: "="}
+		if len(parts) > 1 {
+			filter.Op = parts[1]
+		}
+		switch filter.Op {
+		case "in", "IN", "not_in", "NOT_IN":
+			filter.Value = strings.Split(vs[0], ",")
+		default:
+			filter.Value = vs[0]
+		}
+		form.Filters = append(form.Filters, filter)
+	}
+
+	if orders := query.Get("sort"); orders != "" {
+		for _, name := range strings.Split(orders, ",") {
+			if strings.HasPrefix(name, "-") {
+				form.Orders = append(form.Orders, Order{Name: name[1:], Op: "desc"})
+			} else {
+				form.Orders = append(form.Orders, Order{Name: name, Op: "asc"})
+			}
+		}
+	}
+
+	if fields := query.Get("fields"); fields != "" {
+		form.Fields = strings.Split(fields, ",")
+	}
+
+	form.fixPosLimit()
+	return db, &form, nil
+}
+
+func (form *QueryForm) fixPosLimit() {
 	if form.Pagination {
 		if form.Pos < 1 {
 			form.Pos = 1
@@ -711,8 +1635,6 @@ func DefaultPrepareQuery(db *gorm.DB, c *gin.Context) (*gorm.DB, *QueryForm, err
 	if form.Limit <= 0 || form.Limit > MaxQueryLimit {
 		form.Limit = DefaultQueryLimit
 	}
-
-	return db, &form, nil
 }
 
 /*
@@ -737,6 +1659,10 @@ func checkType(goKind, jsonKind reflect.Kind) bool {
 }
 
 func handleError(c *gin.Context, code int, err any) {
+	if isJSONAPI(c) {
+		handleJSONAPIError(c, code, err)
+		return
+	}
 	switch err := err.(type) {
 	case error:
 		c.AbortWithStatusJSON(code, gin.H{"error": err.Error()})
@@ -748,3 +1674,16 @@ func handleError(c *gin.Context, code int, err any) {
 		c.AbortWithStatusJSON(code, gin.H{"error": fmt.Sprintf("unknown error: %v", err)})
 	}
 }
+
+// handleWriteError respond the error of write transaction.
+func handleWriteError(c *gin.Context, err error) {
+	handleActionError(c, writeError(err))
+}
+
+// writeError return the ActionError of write transaction, 409 for the version mismatch.
+func writeError(err error) error {
+	if errors.Is(err, errVersionMismatch) {
+		return &ActionError{Code: http.StatusConflict, Err: err}
+	}
+	return &ActionError{Code: http.StatusInternalServerError, Err: err}
+}
@@ -1235,3 +1235,163 @@ func TestPreloadQuery(t *testing.T) {
 		assert.Equal(t, "company-2", us2[1].Company.Name)
 	}
 }
+
+func TestQueryWithURL(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{})
+
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 9})
+	db.Create(&tuser{ID: 2, Name: "bob", Age: 10})
+	db.Create(&tuser{ID: 3, Name: "clash", Age: 11})
+
+	r := gin.Default()
+	RegisterObjects(r, []WebObject{
+		{
+			Name:         "user",
+			Model:        tuser{},
+			FilterFields: []string{"Name", "Age"},
+			OrderFields:  []string{"ID", "Age"},
+			SearchFields: []string{"Name"},
+			GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			Views: []QueryView{
+				{
+					Name:   "adults",
+					Method: http.MethodGet,
+					Prepare: func(db *gorm.DB, c *gin.Context) (*gorm.DB, *QueryForm, error) {
+						db, form, err := DefaultPrepareURLQuery(db, c)
+						return db.Where("age >= ?", 10), form, err
+					},
+				},
+				{Name: "all", Method: http.MethodGet},
+			},
+		},
+	})
+	c := NewTestClient(r)
+
+	query := func(url string) (int, map[string]any) {
+		w := c.Get(url)
+		var res map[string]any
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code, res
+	}
+
+	code, res := query("/user?filter[age][greater_or_equal]=10&sort=-age&pos=0&limit=1")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(2), res["total"])
+	items := res["items"].([]any)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "clash", items[0].(map[string]any)["name"])
+
+	// the filter of unknown field is ignored
+	_, res = query("/user?filter[name][in]=alice,bob&filter[password]=x&keyword=li")
+	assert.Equal(t, float64(1), res["total"])
+
+	_, res = query("/user?filter[name]=bob&fields=name,unknown")
+	item := res["items"].([]any)[0].(map[string]any)
+	assert.Equal(t, map[string]any{"name": "bob"}, item)
+
+	code, _ = query("/user?fields=unknown")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = query("/user?limit=abc")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// coexist with GET views
+	_, res = query("/user/adults?sort=age&fields=id,age")
+	items = res["items"].([]any)
+	assert.Len(t, items, 2)
+	assert.Equal(t, map[string]any{"id": float64(2), "age": float64(10)}, items[0])
+
+	_, res = query("/user/all?filter[age]=9")
+	assert.Equal(t, float64(1), res["total"])
+
+	_, res = query("/user/1")
+	assert.Equal(t, "alice", res["name"])
+}
+
+func TestPrepareURLQueryOrder(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	for i := 0; i < 10; i++ {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/user?filter[name]=a&filter[age][>]=1&filter[id][in]=1,2&filter[email]=b", nil)
+		_, form, err := DefaultPrepareURLQuery(db, c)
+		assert.Nil(t, err)
+		var names []string
+		for _, f := range form.Filters {
+			names = append(names, f.Name)
+		}
+		assert.Equal(t, []string{"age", "email", "id", "name"}, names)
+	}
+}
+
+func TestRegisterObjectsErrors(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{})
+	getDB := func(c *gin.Context, isCreate bool) *gorm.DB { return db }
+
+	r := gin.New()
+	err := RegisterObjects(r, []WebObject{
+		{Name: "user", Model: tuser{}, GetDB: getDB, Views: []QueryView{{Name: "adults"}}},
+		{Name: "nodb", Model: tuser{}},
+		{Name: "user", Model: tuser{}, GetDB: getDB},
+		{Name: "adults", Group: "user", Model: tuser{}, GetDB: getDB},
+		{
+			Name:         "order",
+			Model:        tuser{},
+			GetDB:        getDB,
+			AllowMethods: GET | QUERY | IMPORT,
+			Views: []QueryView{
+				{Name: "import"},
+				{Name: "1", Method: http.MethodGet},
+				{Name: ":id"},
+				{Name: "all", Method: http.MethodGet},
+			},
+		},
+	})
+	assert.Equal(t, strings.Join([]string{
+		"RegisterObject [nodb] fail without db",
+		"RegisterObject [user] fail duplicate object user",
+		"RegisterObject [adults] fail route POST user/adults conflicts with object user",
+		"RegisterObject [order] fail view import conflicts with route POST order/import",
+		"view 1 conflicts with key route GET order/:key",
+		`with invalid view ":id"`,
+	}, "\n"), err.Error())
+	// nothing is registered
+	assert.Empty(t, r.Routes())
+
+	err = RegisterObjects(r, []WebObject{
+		{Name: "user", Model: tuser{}, GetDB: getDB, Views: []QueryView{{Name: "adults"}}},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, r.Routes(), 8)
+
+	// the default names of models
+	named := gin.New()
+	err = RegisterObjects(named, []WebObject{
+		{Model: tuser{}, GetDB: getDB},
+		{Model: tnote{}, GetDB: getDB},
+		{Model: &tnote{}, GetDB: getDB},
+	})
+	assert.Equal(t, "RegisterObject [tnote] fail duplicate object tnote", err.Error())
+
+	assert.Empty(t, named.Routes())
+	err = RegisterObjects(named, []WebObject{
+		{Model: tuser{}, GetDB: getDB},
+		{Model: tnote{}, GetDB: getDB},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, named.Routes(), 14)
+
+	// the admin routes
+	err = RegisterObjectsWithAdmin(r.Group("admin"), []WebObject{
+		{Name: "object", Model: tuser{}, GetDB: getDB},
+		{Name: "v1", Model: tuser{}, GetDB: getDB, AllowMethods: QUERY},
+		{Name: "nodb", Model: tuser{}},
+	})
+	assert.Equal(t, strings.Join([]string{
+		"RegisterObject [nodb] fail without db",
+		"RegisterObjectWithAdmin route GET object/:key conflicts with admin route GET object/:name",
+		"RegisterObjectWithAdmin route GET v1 conflicts with admin route GET v1",
+	}, "\n"), err.Error())
+	assert.Len(t, r.Routes(), 8)
+}
@@ -0,0 +1,215 @@
+package gormpher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultOutboxBatchSize   = 100
+	DefaultOutboxInterval    = time.Second
+	DefaultOutboxMaxAttempts = 10
+)
+
+// EventPublisher publish the events of create, update, delete and batch delete.
+// tx is the transaction of the write, the write is rolled back when error is returned.
+type EventPublisher interface {
+	Publish(ctx *gin.Context, tx *gorm.DB, ev Event) error
+}
+
+// EventPublisherFunc is an adapter to allow the use of ordinary functions as EventPublisher.
+type EventPublisherFunc func(ctx *gin.Context, tx *gorm.DB, ev Event) error
+
+func (f EventPublisherFunc) Publish(ctx *gin.Context, tx *gorm.DB, ev Event) error {
+	return f(ctx, tx, ev)
+}
+
+// EventSink deliver the event to the external system, such as message queue.
+// ev.ID is the id of OutboxEvent, which can be used for deduplication.
+type EventSink interface {
+	Deliver(ctx context.Context, ev Event) error
+}
+
+// EventSinkFunc is an adapter to allow the use of ordinary functions as EventSink.
+type EventSinkFunc func(ctx context.Context, ev Event) error
+
+func (f EventSinkFunc) Deliver(ctx context.Context, ev Event) error {
+	return f(ctx, ev)
+}
+
+// OutboxEvent is the event waiting for delivery, need to be migrated by user:
+//
+//	db.AutoMigrate(&gormpher.OutboxEvent{})
+type OutboxEvent struct {
+	ID            uint            `json:"id" gorm:"primarykey"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	Object        string          `json:"object" gorm:"size:64"`
+	Type          string          `json:"type" gorm:"size:16"`
+	ObjectKey     string          `json:"key" gorm:"size:128"`
+	Payload       json.RawMessage `json:"payload"` // Event
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"nextAttemptAt" gorm:"index"`
+	DeliveredAt   *time.Time      `json:"deliveredAt" gorm:"index"`
+	LastError     string          `json:"lastError"`
+}
+
+// Outbox is the transactional outbox, it writes events into OutboxEvent
+// in the same transaction of the write, and OutboxDispatcher delivers them.
+type Outbox struct{}
+
+func (Outbox) Publish(ctx *gin.Context, tx *gorm.DB, ev Event) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return tx.Session(&gorm.Session{NewDB: true}).Create(&OutboxEvent{
+		Object:        ev.Object,
+		Type:          ev.Type,
+		ObjectKey:     ev.Key,
+		Payload:       payload,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+// OutboxDispatcher deliver the OutboxEvents to Sink in order of id, the failed
+// event is retried with backoff until MaxAttempts. The events of the same object
+// key are delivered in order, the later events wait until the failed one is
+// delivered, or given up after MaxAttempts. Only one dispatcher should run on
+// the same table.
+type OutboxDispatcher struct {
+	DB          *gorm.DB
+	Sink        EventSink
+	BatchSize   int           // default 100
+	Interval    time.Duration // the interval of polling, default 1s
+	MaxAttempts int           // default 10
+	// Backoff return the delay before the next attempt, default 1s * 2^(attempts-1), max 5m.
+	Backoff func(attempts int) time.Duration
+	// Logger log the failed deliveries and the errors of dispatching, default slog.Default().
+	Logger *slog.Logger
+}
+
+// Run dispatch the events until ctx is done.
+func (d *OutboxDispatcher) Run(ctx context.Context) error {
+	return runDispatcher(ctx, d.Interval, d.batchSize(), d.DispatchOnce, dispatcherLogger(d.Logger))
+}
+
+// DispatchOnce deliver a batch of pending events, return the number of events attempted.
+func (d *OutboxDispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	maxAttempts := d.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultOutboxMaxAttempts
+	}
+
+	// skip the events behind the pending event of the same key, which waits for retry
+	now := time.Now()
+	table := d.DB.NamingStrategy.TableName("OutboxEvent")
+	var events []OutboxEvent
+	err := d.DB.WithContext(ctx).
+		Where("delivered_at IS NULL AND attempts < ? AND next_attempt_at <= ?", maxAttempts, now).
+		Where(fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %[1]s p WHERE p.object = %[1]s.object AND p.object_key = %[1]s.object_key "+
+			"AND p.id < %[1]s.id AND p.delivered_at IS NULL AND p.attempts < ? AND p.next_attempt_at > ?)", table), maxAttempts, now).
+		Order("id").Limit(d.batchSize()).Find(&events).Error
+	if err != nil {
+		return 0, err
+	}
+
+	// the keys failed in this batch
+	failed := make(map[[2]string]struct{})
+	n := 0
+	for i := range events {
+		oe := &events[i]
+		key := [2]string{oe.Object, oe.ObjectKey}
+		if _, ok := failed[key]; ok {
+			continue
+		}
+		n++
+
+		var ev Event
+		err := json.Unmarshal(oe.Payload, &ev)
+		if err == nil {
+			ev.ID = uint64(oe.ID)
+			err = d.Sink.Deliver(ctx, ev)
+		}
+
+		vals := map[string]any{"attempts": oe.Attempts + 1}
+		if err != nil {
+			failed[key] = struct{}{}
+			dispatcherLogger(d.Logger).Warn("gormpher outbox delivery failed", "id", oe.ID,
+				"object", oe.Object, "key", oe.ObjectKey, "attempts", oe.Attempts+1, "error", err)
+			vals["last_error"] = err.Error()
+			vals["next_attempt_at"] = time.Now().Add(d.backoff(oe.Attempts + 1))
+		} else {
+			vals["last_error"] = ""
+			vals["delivered_at"] = time.Now()
+		}
+		if err := d.DB.WithContext(ctx).Model(oe).Updates(vals).Error; err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (d *OutboxDispatcher) batchSize() int {
+	if d.BatchSize <= 0 {
+		return DefaultOutboxBatchSize
+	}
+	return d.BatchSize
+}
+
+func (d *OutboxDispatcher) backoff(attempts int) time.Duration {
+	if d.Backoff != nil {
+		return d.Backoff(attempts)
+	}
+	return defaultBackoff(attempts)
+}
+
+// dispatcherLogger return logger, or slog.Default() when nil.
+func dispatcherLogger(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
+
+// runDispatcher call dispatchOnce every interval until ctx is done,
+// the next batch is dispatched at once when the batch is full.
+// The errors of dispatchOnce are logged, and retried at the next interval.
+func runDispatcher(ctx context.Context, interval time.Duration, batchSize int, dispatchOnce func(ctx context.Context) (int, error), logger *slog.Logger) error {
+	if interval <= 0 {
+		interval = DefaultOutboxInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := dispatchOnce(ctx)
+			if err != nil && ctx.Err() == nil {
+				logger.Error("gormpher dispatch failed", "error", err)
+			}
+			if err != nil || n < batchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// defaultBackoff return 1s * 2^(attempts-1), max 5m.
+func defaultBackoff(attempts int) time.Duration {
+	delay := time.Second << (attempts - 1)
+	if delay <= 0 || delay > 5*time.Minute {
+		delay = 5 * time.Minute
+	}
+	return delay
+}
@@ -0,0 +1,153 @@
+package gormpher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestOutbox(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{}, OutboxEvent{})
+
+	r := gin.Default()
+	RegisterObjects(r, []WebObject{
+		{
+			Name:       "user",
+			Model:      tuser{},
+			EditFields: []string{"Name", "Age"},
+			GetDB:      func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			Publisher:  Outbox{},
+		},
+	})
+
+	c := NewTestClient(r)
+	c.CallPut("/user", map[string]any{"name": "alice", "age": 9}, nil)
+	c.CallPut("/user", map[string]any{"name": "bob", "age": 10}, nil)
+	c.CallPatch("/user/1", map[string]any{"age": 20}, nil)
+	c.CallDelete("/user", []string{"1", "2"}, nil)
+
+	var count int64
+	db.Model(&OutboxEvent{}).Count(&count)
+	assert.Equal(t, int64(5), count)
+
+	// the sink fails on the first attempt
+	var delivered []Event
+	failed := false
+	var logs bytes.Buffer
+	d := OutboxDispatcher{
+		DB:      db,
+		Backoff: func(attempts int) time.Duration { return time.Hour },
+		Logger:  slog.New(slog.NewTextHandler(&logs, nil)),
+		Sink: EventSinkFunc(func(ctx context.Context, ev Event) error {
+			if !failed {
+				failed = true
+				return errors.New("sink unavailable")
+			}
+			delivered = append(delivered, ev)
+			return nil
+		}),
+	}
+
+	// the later events of user 1 wait for the failed one
+	n, err := d.DispatchOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	assert.Len(t, delivered, 2)
+	assert.Equal(t, "2", delivered[0].Key)
+	assert.Equal(t, "2", delivered[1].Key)
+	assert.Contains(t, logs.String(), "gormpher outbox delivery failed")
+	assert.Contains(t, logs.String(), "sink unavailable")
+
+	var oe OutboxEvent
+	db.First(&oe, 1)
+	assert.Nil(t, oe.DeliveredAt)
+	assert.Equal(t, 1, oe.Attempts)
+	assert.Equal(t, "sink unavailable", oe.LastError)
+
+	// in backoff
+	n, err = d.DispatchOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	// retry, the events of user 1 are delivered in order
+	db.Model(&oe).Update("next_attempt_at", time.Now())
+	n, err = d.DispatchOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	assert.Len(t, delivered, 5)
+
+	assert.Equal(t, uint64(1), delivered[2].ID)
+	assert.Equal(t, EventCreated, delivered[2].Type)
+	assert.Equal(t, "alice", delivered[2].Data["name"])
+	assert.Equal(t, EventUpdated, delivered[3].Type)
+	assert.Equal(t, EventDeleted, delivered[4].Type)
+
+	n, err = d.DispatchOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestOutboxRollback(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{}, OutboxEvent{})
+
+	r := gin.Default()
+	RegisterObjects(r, []WebObject{
+		{
+			Name:  "user",
+			Model: tuser{},
+			GetDB: func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			Publisher: EventPublisherFunc(func(ctx *gin.Context, tx *gorm.DB, ev Event) error {
+				return errors.New("publish failed")
+			}),
+		},
+	})
+
+	c := NewTestClient(r)
+	err := c.CallPut("/user", map[string]any{"name": "alice", "age": 9}, nil)
+	assert.NotNil(t, err)
+
+	var count int64
+	db.Model(&tuser{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestOutboxDispatcherRun(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(OutboxEvent{})
+
+	err := Outbox{}.Publish(nil, db, Event{Type: EventCreated, Object: "user", Key: "1"})
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	delivered := make(chan Event, 1)
+	d := OutboxDispatcher{
+		DB:       db,
+		Interval: 10 * time.Millisecond,
+		Sink: EventSinkFunc(func(ctx context.Context, ev Event) error {
+			delivered <- ev
+			return nil
+		}),
+	}
+
+	done := make(chan error)
+	go func() { done <- d.Run(ctx) }()
+
+	select {
+	case ev := <-delivered:
+		assert.Equal(t, "1", ev.Key)
+	case <-time.After(3 * time.Second):
+		t.Fatal("event not delivered")
+	}
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
@@ -0,0 +1,122 @@
+package gormpher
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FieldPolicy control the read and write permission of a field per request.
+type FieldPolicy struct {
+	// the struct field name, such as "Salary"
+	Field string
+	// CanRead return false to strip the field from get and query responses,
+	// it can't be filtered, ordered or searched either. nil means readable.
+	CanRead func(ctx *gin.Context) bool
+	// CanWrite return false to lock the field on create and update. nil means writable.
+	CanWrite func(ctx *gin.Context) bool
+	// RejectWrite respond 403 when a locked field is written,
+	// otherwise the field is dropped silently.
+	RejectWrite bool
+}
+
+// getJsonName return the json name of struct field name.
+func (obj *WebObject) getJsonName(field string) string {
+	for k, v := range obj.jsonToFields {
+		if v == field {
+			return k
+		}
+	}
+	return field
+}
+
+// unreadableFields return the struct field names can't be read by the request.
+func (obj *WebObject) unreadableFields(c *gin.Context) map[string]struct{} {
+	fields := make(map[string]struct{})
+	for _, p := range obj.FieldPolicies {
+		if p.CanRead != nil && !p.CanRead(c) {
+			fields[p.Field] = struct{}{}
+		}
+	}
+	return fields
+}
+
+// unwritableFields return the struct field names can't be written by the request.
+func (obj *WebObject) unwritableFields(c *gin.Context) map[string]struct{} {
+	fields := make(map[string]struct{})
+	for _, p := range obj.FieldPolicies {
+		if p.CanWrite != nil && !p.CanWrite(c) {
+			fields[p.Field] = struct{}{}
+		}
+	}
+	return fields
+}
+
+// checkWritable drop the locked fields from vals (json format key),
+// return error when a locked field with RejectWrite is written.
+func (obj *WebObject) checkWritable(c *gin.Context, vals map[string]any) error {
+	for _, p := range obj.FieldPolicies {
+		if p.CanWrite == nil || p.CanWrite(c) {
+			continue
+		}
+		jsonName := obj.getJsonName(p.Field)
+		if _, ok := vals[jsonName]; !ok {
+			continue
+		}
+		if p.RejectWrite {
+			return fmt.Errorf("%s is not writable", jsonName)
+		}
+		delete(vals, jsonName)
+	}
+	return nil
+}
+
+// stripUnreadable remove the unreadable fields from the object or the slice of objects,
+// return val itself when all fields are readable.
+func (obj *WebObject) stripUnreadable(c *gin.Context, val any) (any, error) {
+	return obj.stripFields(val, obj.unreadableFields(c))
+}
+
+// stripFields remove the fields from the object or the slice of objects,
+// return val itself when fields is empty.
+func (obj *WebObject) stripFields(val any, fields map[string]struct{}) (any, error) {
+	if len(fields) == 0 || val == nil {
+		return val, nil
+	}
+
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := reflect.ValueOf(val)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+
+	var items []map[string]json.RawMessage
+	if rv.Kind() == reflect.Slice {
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+	} else {
+		var item map[string]json.RawMessage
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	for _, item := range items {
+		for field := range fields {
+			delete(item, obj.getJsonName(field))
+		}
+	}
+
+	if rv.Kind() == reflect.Slice {
+		return items, nil
+	}
+	return items[0], nil
+}
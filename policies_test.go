@@ -0,0 +1,150 @@
+package gormpher
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type temployee struct {
+	ID     uint   `json:"id" gorm:"primarykey"`
+	Name   string `json:"name"`
+	Salary int    `json:"salary"`
+	Notes  string `json:"notes"`
+}
+
+func initPolicyTest(t *testing.T) (*gin.Engine, *gorm.DB) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(temployee{})
+
+	db.Create(&temployee{ID: 1, Name: "alice", Salary: 100, Notes: "good"})
+	db.Create(&temployee{ID: 2, Name: "bob", Salary: 200, Notes: "bad"})
+
+	isAdmin := func(ctx *gin.Context) bool {
+		return ctx.GetHeader("X-Role") == "admin"
+	}
+
+	r := gin.Default()
+	RegisterObjectsWithAdmin(r.Group("admin"), []WebObject{
+		{
+			Name:         "employee",
+			Model:        temployee{},
+			EditFields:   []string{"Name", "Salary", "Notes"},
+			FilterFields: []string{"Name", "Salary"},
+			OrderFields:  []string{"Salary"},
+			GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			FieldPolicies: []FieldPolicy{
+				{Field: "Salary", CanRead: isAdmin, CanWrite: isAdmin, RejectWrite: true},
+				{Field: "Notes", CanWrite: isAdmin},
+			},
+		},
+	})
+	return r, db
+}
+
+func TestFieldPolicies(t *testing.T) {
+	r, db := initPolicyTest(t)
+
+	send := func(method, path, role string, body any) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(b))
+		req.Header.Set("X-Role", role)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Get
+	{
+		w := send(http.MethodGet, "/admin/employee/1", "admin", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"salary":100`)
+
+		w = send(http.MethodGet, "/admin/employee/1", "user", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "salary")
+		assert.Contains(t, w.Body.String(), `"name":"alice"`)
+	}
+	// Query, can't filter by unreadable field
+	{
+		form := map[string]any{"filters": []map[string]any{{"name": "salary", "op": ">", "value": 150}}}
+
+		w := send(http.MethodPost, "/admin/employee", "admin", form)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var res QueryResult[[]map[string]any]
+		json.Unmarshal(w.Body.Bytes(), &res)
+		assert.Equal(t, 1, res.Total)
+		assert.Contains(t, res.Items[0], "salary")
+
+		w = send(http.MethodPost, "/admin/employee", "user", form)
+		assert.Equal(t, http.StatusOK, w.Code)
+		res = QueryResult[[]map[string]any]{}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		assert.Equal(t, 2, res.Total)
+		assert.NotContains(t, res.Items[0], "salary")
+	}
+	// Update
+	{
+		w := send(http.MethodPatch, "/admin/employee/1", "user", map[string]any{"salary": 1000})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = send(http.MethodPatch, "/admin/employee/1", "user", map[string]any{"name": "alice2", "notes": "changed"})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var e temployee
+		db.First(&e, 1)
+		assert.Equal(t, "alice2", e.Name)
+		assert.Equal(t, "good", e.Notes)
+
+		w = send(http.MethodPatch, "/admin/employee/1", "admin", map[string]any{"salary": 1000, "notes": "changed"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		db.First(&e, 1)
+		assert.Equal(t, 1000, e.Salary)
+		assert.Equal(t, "changed", e.Notes)
+	}
+	// Create
+	{
+		w := send(http.MethodPut, "/admin/employee", "user", map[string]any{"name": "clash", "salary": 1})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = send(http.MethodPut, "/admin/employee", "user", map[string]any{"name": "clash", "notes": "dropped"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "salary")
+		assert.Contains(t, w.Body.String(), `"notes":""`)
+	}
+}
+
+func TestFieldPoliciesAdmin(t *testing.T) {
+	r, _ := initPolicyTest(t)
+
+	get := func(role string) map[string]any {
+		req := httptest.NewRequest(http.MethodGet, "/admin/object/employee", nil)
+		req.Header.Set("X-Role", role)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var result map[string]any
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return result
+	}
+
+	result := get("admin")
+	assert.Len(t, result["fields"], 4)
+	assert.Len(t, result["edits"], 3)
+	assert.Len(t, result["readonly"], 0)
+
+	result = get("user")
+	assert.Equal(t, []any{"id", "name", "notes"}, result["fields"])
+	assert.Equal(t, []any{"name"}, result["edits"])
+	assert.Equal(t, []any{"name"}, result["filters"])
+	assert.Equal(t, []any{}, result["orders"])
+	assert.Equal(t, []any{"notes", "salary"}, result["readonly"])
+}
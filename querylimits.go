@@ -0,0 +1,79 @@
+package gormpher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// QueryLimits is the guardrails of query cost, the zero values are unlimited.
+// The query over limits is rejected with 400, and timed out with 408.
+// The limits are checked after the Prepare of views, so the filters and orders
+// added by Prepare count toward the limits too.
+type QueryLimits struct {
+	MaxFilters       int
+	MaxOrders        int
+	MaxInValues      int           // the max values of in and not_in filters
+	MaxKeywordLength int           // the max runes of keyword, which is searched by LIKE '%keyword%'
+	NoLeadingLike    bool          // reject the like filters starting with a wildcard, such as '%alice'
+	Timeout          time.Duration // the statement timeout of get, query and export, applied through the request context
+}
+
+var errQueryTimeout = errors.New("query timeout")
+
+// check return the error of form over limits.
+func (l *QueryLimits) check(form *QueryForm) error {
+	if l.MaxFilters > 0 && len(form.Filters) > l.MaxFilters {
+		return fmt.Errorf("too many filters, max %d", l.MaxFilters)
+	}
+	if l.MaxOrders > 0 && len(form.Orders) > l.MaxOrders {
+		return fmt.Errorf("too many orders, max %d", l.MaxOrders)
+	}
+	if l.MaxKeywordLength > 0 && len([]rune(form.Keyword)) > l.MaxKeywordLength {
+		return fmt.Errorf("keyword too long, max %d", l.MaxKeywordLength)
+	}
+	for _, f := range form.Filters {
+		switch strings.ToLower(f.Op) {
+		case "in", "not_in":
+			rv := reflect.ValueOf(f.Value)
+			if l.MaxInValues > 0 && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Len() > l.MaxInValues {
+				return fmt.Errorf("too many values of filter %s, max %d", f.Name, l.MaxInValues)
+			}
+		case "like":
+			if s, ok := f.Value.(string); ok && l.NoLeadingLike && strings.IndexAny(s, "%_") == 0 {
+				return fmt.Errorf("leading wildcard of filter %s", f.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// withTimeout set the statement timeout of db with the request context.
+func (l *QueryLimits) withTimeout(c *gin.Context, db *gorm.DB) (*gorm.DB, context.CancelFunc) {
+	if l.Timeout <= 0 {
+		return db, func() {}
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), l.Timeout)
+	c.Request = c.Request.WithContext(ctx)
+	return db.WithContext(ctx), cancel
+}
+
+// handleQueryError abort with 408 when the query is timed out, otherwise 500.
+func handleQueryError(c *gin.Context, err error) {
+	handleActionError(c, queryError(c, err))
+}
+
+// queryError return the ActionError of query, 408 when the query is timed out, otherwise 500.
+func queryError(c *gin.Context, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		return &ActionError{Code: http.StatusRequestTimeout, Err: errQueryTimeout}
+	}
+	return &ActionError{Code: http.StatusInternalServerError, Err: err}
+}
@@ -0,0 +1,109 @@
+package gormpher
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestQueryLimits(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{})
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 9})
+	db.Create(&tuser{ID: 2, Name: "bob", Age: 10})
+
+	obj := WebObject{
+		Name:         "user",
+		Model:        tuser{},
+		FilterFields: []string{"Name", "Age"},
+		OrderFields:  []string{"Age"},
+		SearchFields: []string{"Name"},
+		GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+		QueryLimits: QueryLimits{
+			MaxFilters:       2,
+			MaxOrders:        1,
+			MaxInValues:      3,
+			MaxKeywordLength: 5,
+			NoLeadingLike:    true,
+		},
+	}
+	r := gin.New()
+	err := RegisterObjects(r, []WebObject{obj})
+	assert.Nil(t, err)
+	client := NewTestClient(r)
+
+	query := func(body string) (int, string) {
+		w := client.Post("/user", []byte(body))
+		return w.Code, w.Body.String()
+	}
+
+	code, _ := query(`{"filters":[{"name":"age","op":"in","value":[9,10,11]},{"name":"name","op":"like","value":"ali%"}],"orders":[{"name":"age"}],"keyword":"alice"}`)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, body := query(`{"filters":[{"name":"age","op":">","value":1},{"name":"age","op":"<","value":100},{"name":"name","op":"=","value":"a"}]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body, "too many filters, max 2")
+
+	code, body = query(`{"orders":[{"name":"age"},{"name":"age","op":"desc"}]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body, "too many orders, max 1")
+
+	code, body = query(`{"filters":[{"name":"age","op":"not_in","value":[1,2,3,4]}]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body, "too many values of filter age, max 3")
+
+	code, body = query(`{"keyword":"` + strings.Repeat("a", 6) + `"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body, "keyword too long, max 5")
+
+	code, body = query(`{"filters":[{"name":"name","op":"like","value":"%ice"}]}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, body, "leading wildcard of filter name")
+
+	// the URL query
+	w := client.Get("/user?filter[age][in]=1,2,3,4")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestQueryTimeout(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{})
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 9})
+
+	r := gin.New()
+	err := RegisterObjects(r, []WebObject{
+		{
+			Name:        "user",
+			Model:       tuser{},
+			GetDB:       func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			QueryLimits: QueryLimits{Timeout: time.Nanosecond},
+		},
+		{
+			Name:        "user2",
+			Model:       tuser{},
+			GetDB:       func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			QueryLimits: QueryLimits{Timeout: time.Minute},
+		},
+	})
+	assert.Nil(t, err)
+	client := NewTestClient(r)
+
+	w := client.Get("/user/1")
+	assert.Equal(t, http.StatusRequestTimeout, w.Code)
+	assert.Contains(t, w.Body.String(), "query timeout")
+	w = client.Post("/user", []byte(`{}`))
+	assert.Equal(t, http.StatusRequestTimeout, w.Code)
+	w = client.Post("/user", []byte(`{"export":"csv"}`))
+	assert.Equal(t, http.StatusRequestTimeout, w.Code)
+
+	w = client.Get("/user2/1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = client.Post("/user2", []byte(`{}`))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,180 @@
+package gormpher
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit is the limit of token bucket, the zero Rate is unlimited.
+type RateLimit struct {
+	Rate  float64 // the tokens per second
+	Burst int     // the max tokens of bucket, at least 1 with Rate
+}
+
+// RateLimitStore take the tokens of buckets, such as MemoryRateLimitStore,
+// or the store shared by instances such as Redis.
+type RateLimitStore interface {
+	// Take take a token from the bucket of key, return the duration to wait when not allowed.
+	Take(key string, limit RateLimit) (bool, time.Duration)
+}
+
+// RateLimiter limit the requests of object by client identity and action, the requests
+// over limit are rejected with 429 and Retry-After header.
+type RateLimiter struct {
+	Store RateLimitStore // MemoryRateLimitStore when nil
+	// the client identity, such as user id. The default is the remote IP of connection,
+	// not the client IP of X-Forwarded-For, which can be forged by client unless the
+	// trusted proxies of gin are configured, then return c.ClientIP() here.
+	Identify func(c *gin.Context) string
+
+	Limit   RateLimit         // the limit of actions
+	Actions map[int]RateLimit // the limits of actions, override Limit, such as {BATCH: ...}
+	Export  RateLimit         // the limit of export queries, besides the limit of query
+}
+
+// NewRateLimiter return the limiter of rate and burst, the batch, import and export
+// are limited to 1/10 of rate and burst.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	strict := RateLimit{Rate: rate / 10, Burst: max(burst/10, 1)}
+	return &RateLimiter{
+		Store:   NewMemoryRateLimitStore(),
+		Limit:   RateLimit{Rate: rate, Burst: burst},
+		Actions: map[int]RateLimit{BATCH: strict, IMPORT: strict},
+		Export:  strict,
+	}
+}
+
+const exportRateKey = "export"
+
+// checkRateLimiter check the limits of RateLimiter, the bucket of Burst less than 1
+// rejects every request.
+func (obj *WebObject) checkRateLimiter() error {
+	l := obj.RateLimiter
+	if l == nil {
+		return nil
+	}
+	limits := map[string]RateLimit{"Limit": l.Limit, "Export": l.Export}
+	for action, limit := range l.Actions {
+		limits[ActionName(action)] = limit
+	}
+	for _, name := range sortedKeys(limits) {
+		if limit := limits[name]; limit.Rate > 0 && limit.Burst < 1 {
+			return fmt.Errorf("%s RateLimiter %s with Burst less than 1", obj.Name, name)
+		}
+	}
+	return nil
+}
+
+var defaultRateLimitStore = NewMemoryRateLimitStore()
+
+// limitRate reject the request of action over the limit.
+func (obj *WebObject) limitRate(action int, h gin.HandlerFunc) gin.HandlerFunc {
+	if obj.RateLimiter == nil {
+		return h
+	}
+	return func(c *gin.Context) {
+		limit, ok := obj.RateLimiter.Actions[action]
+		if !ok {
+			limit = obj.RateLimiter.Limit
+		}
+		if !obj.allowRate(c, ActionName(action), limit) {
+			return
+		}
+		h(c)
+	}
+}
+
+// allowRate take a token of the bucket of name, abort with 429 when not allowed.
+func (obj *WebObject) allowRate(c *gin.Context, name string, limit RateLimit) bool {
+	l := obj.RateLimiter
+	if l == nil || limit.Rate <= 0 {
+		return true
+	}
+	store := l.Store
+	if store == nil {
+		store = defaultRateLimitStore
+	}
+	identity := c.RemoteIP()
+	if l.Identify != nil {
+		identity = l.Identify(c)
+	}
+
+	ok, retryAfter := store.Take(identity+"|"+filepath.Join(obj.Group, obj.Name)+"|"+name, limit)
+	if ok {
+		return true
+	}
+	c.Header("Retry-After", strconv.Itoa(max(int(math.Ceil(retryAfter.Seconds())), 1)))
+	handleError(c, http.StatusTooManyRequests, "too many requests")
+	return false
+}
+
+// MemoryRateLimitStore is the RateLimitStore in memory, the idle buckets are removed.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	takes   int
+	now     func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	limit  RateLimit
+}
+
+// the buckets are swept every sweepTakes
+const sweepTakes = 1024
+
+// NewMemoryRateLimitStore return the empty MemoryRateLimitStore.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, limit RateLimit) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.takes++
+	if s.takes%sweepTakes == 0 {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+}
+
+// sweep remove the full buckets, which are the same as the new buckets.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
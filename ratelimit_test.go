@@ -0,0 +1,127 @@
+package gormpher
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return now }
+	limit := RateLimit{Rate: 2, Burst: 2}
+
+	ok, _ := store.Take("a", limit)
+	assert.True(t, ok)
+	ok, _ = store.Take("a", limit)
+	assert.True(t, ok)
+	ok, retryAfter := store.Take("a", limit)
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	// the other bucket
+	ok, _ = store.Take("b", limit)
+	assert.True(t, ok)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = store.Take("a", limit)
+	assert.True(t, ok)
+	ok, _ = store.Take("a", limit)
+	assert.False(t, ok)
+
+	// the full buckets are swept
+	now = now.Add(time.Minute)
+	store.takes = sweepTakes - 1
+	store.Take("c", limit)
+	assert.Len(t, store.buckets, 1)
+}
+
+func TestRateLimiter(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{})
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 9})
+
+	limiter := NewRateLimiter(0.1, 20)
+	assert.Equal(t, RateLimit{Rate: 0.01, Burst: 2}, limiter.Export)
+	assert.Equal(t, RateLimit{Rate: 0.01, Burst: 2}, limiter.Actions[BATCH])
+	limiter.Limit.Burst = 2
+	limiter.Actions[QUERY] = RateLimit{Rate: 0.1, Burst: 10}
+	limiter.Identify = func(c *gin.Context) string {
+		return c.GetHeader("X-User")
+	}
+
+	r := gin.New()
+	err := RegisterObjects(r, []WebObject{
+		{
+			Name:        "user",
+			Model:       tuser{},
+			GetDB:       func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			RateLimiter: limiter,
+		},
+	})
+	assert.Nil(t, err)
+
+	send := func(method, path, user string) *http.Response {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("X-User", user)
+		req.Header.Set("Accept", "application/json")
+		resp, _ := NewTestClient(r).RoundTrip(req)
+		return resp
+	}
+
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/user/1", "alice").StatusCode)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/user/1", "alice").StatusCode)
+	resp := send(http.MethodGet, "/user/1", "alice")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "10", resp.Header.Get("Retry-After"))
+
+	// the other client and action
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/user/1", "bob").StatusCode)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/user", "alice").StatusCode)
+
+	// the export is limited besides the query
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/user?export=csv", "bob").StatusCode)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/user?export=csv", "bob").StatusCode)
+	resp = send(http.MethodGet, "/user?export=ndjson", "carol")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = send(http.MethodGet, "/user?export=csv", "carol")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = send(http.MethodGet, "/user?export=csv", "carol")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "100", resp.Header.Get("Retry-After"))
+}
+
+func TestRateLimiterDefaults(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{})
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 9})
+	getDB := func(c *gin.Context, isCreate bool) *gorm.DB { return db }
+
+	// the zero Burst is rejected
+	obj := WebObject{Name: "user", Model: tuser{}, GetDB: getDB, RateLimiter: NewRateLimiter(1, 0)}
+	assert.ErrorContains(t, obj.Build(), "user RateLimiter Limit with Burst less than 1")
+	obj.RateLimiter = NewRateLimiter(1, 10)
+	obj.RateLimiter.Actions[QUERY] = RateLimit{Rate: 1}
+	assert.ErrorContains(t, obj.Build(), "user RateLimiter query with Burst less than 1")
+
+	// the client identity is the remote IP, not X-Forwarded-For
+	r := gin.New()
+	err := RegisterObjects(r, []WebObject{
+		{Name: "user", Model: tuser{}, GetDB: getDB, RateLimiter: NewRateLimiter(0.1, 1)},
+	})
+	assert.Nil(t, err)
+	send := func(forwardedFor string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/user/1", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		return NewTestClient(r).SendReq("/user/1", req).Code
+	}
+	assert.Equal(t, http.StatusOK, send("10.0.0.1"))
+	assert.Equal(t, http.StatusTooManyRequests, send("10.0.0.2"))
+}
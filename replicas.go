@@ -0,0 +1,84 @@
+package gormpher
+
+import (
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const usePrimaryKey = "gormpher:use_primary"
+
+// DefaultStickyCookie is the cookie name of ReplicaRouter stickiness.
+const DefaultStickyCookie = "gormpher_primary"
+
+// UsePrimary route the reads of request to primary, such as the request
+// which must read its own writes.
+func UsePrimary(c *gin.Context) {
+	c.Set(usePrimaryKey, true)
+}
+
+// ReplicaRouter route the reads (get, query and view) to replicas in round robin, and
+// the writes to primary. The reads are routed to primary when UsePrimary is called in
+// the request, or in StickyDuration after the writes of client by the sticky cookie:
+//
+//	router := NewReplicaRouter(primary, replica1, replica2)
+//	router.StickyDuration = 5 * time.Second
+//	obj := WebObject{Name: "user", Model: User{}, SelectDB: router.SelectDB}
+type ReplicaRouter struct {
+	Primary        *gorm.DB
+	Replicas       []*gorm.DB
+	StickyDuration time.Duration // for read-your-writes, zero disables the sticky cookie
+	StickyCookie   string        // DefaultStickyCookie when empty
+
+	next atomic.Uint32
+}
+
+// NewReplicaRouter return the router of primary and replicas.
+func NewReplicaRouter(primary *gorm.DB, replicas ...*gorm.DB) *ReplicaRouter {
+	return &ReplicaRouter{Primary: primary, Replicas: replicas}
+}
+
+// SelectDB return the db of action, it's the SelectDB of WebObject.
+func (r *ReplicaRouter) SelectDB(c *gin.Context, obj *WebObject, action int) *gorm.DB {
+	switch action {
+	case GET, QUERY, VIEW:
+		if len(r.Replicas) == 0 || c.GetBool(usePrimaryKey) || r.isSticky(c) {
+			return r.Primary
+		}
+		n := r.next.Add(1)
+		return r.Replicas[int(n-1)%len(r.Replicas)]
+	}
+
+	// the writes of request, the following reads use primary
+	UsePrimary(c)
+	if r.StickyDuration > 0 {
+		deadline := time.Now().Add(r.StickyDuration)
+		maxAge := int(math.Ceil(r.StickyDuration.Seconds()))
+		c.SetCookie(r.cookieName(), strconv.FormatInt(deadline.UnixMilli(), 10), maxAge, "/", "", false, true)
+	}
+	return r.Primary
+}
+
+// isSticky check the sticky cookie of client is not expired.
+func (r *ReplicaRouter) isSticky(c *gin.Context) bool {
+	if r.StickyDuration <= 0 {
+		return false
+	}
+	v, err := c.Cookie(r.cookieName())
+	if err != nil {
+		return false
+	}
+	deadline, err := strconv.ParseInt(v, 10, 64)
+	return err == nil && time.Now().UnixMilli() < deadline
+}
+
+func (r *ReplicaRouter) cookieName() string {
+	if r.StickyCookie == "" {
+		return DefaultStickyCookie
+	}
+	return r.StickyCookie
+}
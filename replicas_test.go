@@ -0,0 +1,102 @@
+package gormpher
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSelectDB(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{})
+	db.Create(&tuser{ID: 1, Name: "alice", Age: 9})
+
+	var actions []string
+	r := gin.New()
+	err := RegisterObjects(r, []WebObject{
+		{
+			Name:       "user",
+			Model:      tuser{},
+			EditFields: []string{"Name"},
+			SelectDB: func(c *gin.Context, obj *WebObject, action int) *gorm.DB {
+				actions = append(actions, obj.Name+" "+ActionName(action))
+				return db
+			},
+			Views:        []QueryView{{Name: "all"}},
+			AllowMethods: GET | CREATE | EDIT | DELETE | QUERY | BATCH | IMPORT,
+		},
+	})
+	assert.Nil(t, err)
+	client := NewTestClient(r)
+
+	var ok bool
+	assert.Nil(t, client.CallGet("/user/1", nil, &tuser{}))
+	assert.Nil(t, client.CallPost("/user", QueryForm{}, nil))
+	assert.Nil(t, client.CallPost("/user/all", QueryForm{}, nil))
+	assert.Nil(t, client.CallPut("/user", tuser{ID: 2, Name: "bob"}, nil))
+	assert.Nil(t, client.CallPatch("/user/2", map[string]any{"name": "bob2"}, &ok))
+	assert.Nil(t, client.CallDelete("/user/2", nil, &ok))
+	assert.Nil(t, client.CallDelete("/user", []string{"1"}, &ok))
+	code, _ := postImport(client, "/user/import", MimeNDJSON, []byte(`{"id":3,"name":"clash"}`))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{
+		"user get", "user query", "user view", "user create", "user edit",
+		"user delete", "user batch", "user edit", "user import",
+	}, actions)
+}
+
+func TestReplicaRouter(t *testing.T) {
+	primary, _ := gorm.Open(sqlite.Open("file:primary?mode=memory&cache=shared"), nil)
+	replica, _ := gorm.Open(sqlite.Open("file:replica?mode=memory&cache=shared"), nil)
+	primary.AutoMigrate(tuser{})
+	replica.AutoMigrate(tuser{})
+	primary.Create(&tuser{ID: 1, Name: "primary"})
+	replica.Create(&tuser{ID: 1, Name: "replica"})
+
+	router := NewReplicaRouter(primary, replica)
+	router.StickyDuration = time.Minute
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if c.GetHeader("X-Primary") != "" {
+			UsePrimary(c)
+		}
+	})
+	err := RegisterObjects(r, []WebObject{
+		{
+			Name:       "user",
+			Model:      tuser{},
+			EditFields: []string{"Name"},
+			SelectDB:   router.SelectDB,
+		},
+	})
+	assert.Nil(t, err)
+	client := NewTestClient(r)
+
+	get := func(header string) string {
+		req, _ := http.NewRequest(http.MethodGet, "/user/1", nil)
+		if header != "" {
+			req.Header.Set("X-Primary", header)
+		}
+		w := client.SendReq("/user/1", req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+
+	assert.Contains(t, get(""), "replica")
+	assert.Contains(t, get("1"), "primary")
+
+	// the writes go to primary, and the client reads its writes by the sticky cookie
+	var ok bool
+	err = client.CallPatch("/user/1", map[string]any{"name": "primary2"}, &ok)
+	assert.Nil(t, err)
+	assert.Contains(t, get(""), "primary2")
+
+	// the other client without the sticky cookie
+	w := NewTestClient(r).Get("/user/1")
+	assert.Contains(t, w.Body.String(), "replica")
+}
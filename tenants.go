@@ -0,0 +1,143 @@
+package gormpher
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errTenantRequired = errors.New("tenant is required")
+
+// TenantResolver return the tenant of the request, such as tenant id.
+// Return error to reject the request with 403.
+type TenantResolver func(ctx *gin.Context) (any, error)
+
+// TenantFromHeader resolve tenant from the request header, such as "X-Tenant-ID".
+func TenantFromHeader(name string) TenantResolver {
+	return func(ctx *gin.Context) (any, error) {
+		if v := ctx.GetHeader(name); v != "" {
+			return v, nil
+		}
+		return nil, errTenantRequired
+	}
+}
+
+// TenantFromContext resolve tenant from the gin context key, which is set by
+// the authentication middleware, such as ctx.Set("tenantId", user.TenantID).
+func TenantFromContext(key string) TenantResolver {
+	return func(ctx *gin.Context) (any, error) {
+		if v, ok := ctx.Get(key); ok && v != nil {
+			return v, nil
+		}
+		return nil, errTenantRequired
+	}
+}
+
+// TenantFromClaims resolve tenant from the JWT claims stored in gin context key.
+// The claims must be verified by the authentication middleware, and stored
+// as map[string]any, such as jwt.MapClaims.
+func TenantFromClaims(key, claim string) TenantResolver {
+	return func(ctx *gin.Context) (any, error) {
+		v, ok := ctx.Get(key)
+		if !ok {
+			return nil, errTenantRequired
+		}
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+			return nil, errTenantRequired
+		}
+		val := rv.MapIndex(reflect.ValueOf(claim).Convert(rv.Type().Key()))
+		if !val.IsValid() || val.IsZero() {
+			return nil, errTenantRequired
+		}
+		return val.Interface(), nil
+	}
+}
+
+// parseTenantField check TenantField and fill tenantColumn, tenantJsonName.
+func (obj *WebObject) parseTenantField() error {
+	if obj.TenantField == "" {
+		return nil
+	}
+	if obj.TenantResolver == nil {
+		return fmt.Errorf("%s without tenant resolver", obj.Name)
+	}
+	if _, ok := obj.modelElem.FieldByName(obj.TenantField); !ok {
+		return fmt.Errorf("%s not has tenant field %s", obj.Name, obj.TenantField)
+	}
+	obj.tenantColumn = getColumnName(obj.modelElem, obj.TenantField)
+	obj.tenantJsonName = obj.getJsonName(obj.TenantField)
+	return nil
+}
+
+// getTenant resolve the tenant of request, and convert to the type of TenantField.
+func (obj *WebObject) getTenant(c *gin.Context) (any, error) {
+	tenant, err := obj.TenantResolver(c)
+	if err != nil {
+		return nil, err
+	}
+	f, _ := obj.modelElem.FieldByName(obj.TenantField)
+	v, err := convertValue(tenant, f.Type)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant: %v", err)
+	}
+	return v.Interface(), nil
+}
+
+// withTenant constrain db with the tenant column.
+func (obj *WebObject) withTenant(db *gorm.DB, tenant any) *gorm.DB {
+	return db.Where(fmt.Sprintf("`%s` = ?", obj.tenantColumn), tenant)
+}
+
+// setTenant set the tenant field of vptr.
+func (obj *WebObject) setTenant(vptr any, tenant any) {
+	rv := reflect.ValueOf(vptr).Elem()
+	rv.FieldByName(obj.TenantField).Set(reflect.ValueOf(tenant))
+}
+
+// checkTenant reject the vals (json format key) which try to change tenant.
+func (obj *WebObject) checkTenant(vals map[string]any, tenant any) error {
+	v, ok := vals[obj.tenantJsonName]
+	if !ok || v == nil {
+		return nil
+	}
+	f, _ := obj.modelElem.FieldByName(obj.TenantField)
+	rv, err := convertValue(v, f.Type)
+	if err != nil || rv.Interface() != tenant {
+		return fmt.Errorf("%s is not writable", obj.tenantJsonName)
+	}
+	return nil
+}
+
+// convertValue convert v to type t, parse the string when t is number.
+func convertValue(v any, t reflect.Type) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if t.Kind() == reflect.String && rv.IsValid() && rv.Kind() != reflect.String {
+		// avoid converting number to rune
+		rv = reflect.ValueOf(fmt.Sprintf("%v", v))
+	}
+	if s, ok := v.(string); ok {
+		switch {
+		case isIntegerKind(t.Kind()) && t.Kind() >= reflect.Uint:
+			n, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				return rv, err
+			}
+			rv = reflect.ValueOf(n)
+		case isIntegerKind(t.Kind()):
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return rv, err
+			}
+			rv = reflect.ValueOf(n)
+		}
+	}
+	if !rv.IsValid() || !rv.Type().ConvertibleTo(t) {
+		return rv, fmt.Errorf("can't convert %v to %s", v, t)
+	}
+	return rv.Convert(t), nil
+}
@@ -0,0 +1,148 @@
+package gormpher
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type tproduct struct {
+	ID       uint   `json:"id" gorm:"primarykey"`
+	TenantID uint   `json:"tenantId"`
+	Name     string `json:"name"`
+}
+
+func TestTenant(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tproduct{})
+
+	db.Create(&tproduct{ID: 1, TenantID: 1, Name: "apple"})
+	db.Create(&tproduct{ID: 2, TenantID: 1, Name: "banana"})
+	db.Create(&tproduct{ID: 3, TenantID: 2, Name: "cherry"})
+
+	r := gin.Default()
+	err := RegisterObject(r, &WebObject{
+		Name:           "product",
+		Model:          tproduct{},
+		EditFields:     []string{"Name", "TenantID"},
+		TenantField:    "TenantID",
+		TenantResolver: TenantFromHeader("X-Tenant-ID"),
+		GetDB:          func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+		Views: []QueryView{
+			{Name: "all", Method: http.MethodGet},
+		},
+	})
+	assert.Nil(t, err)
+
+	send := func(method, path, tenant string, body any) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(b))
+		if tenant != "" {
+			req.Header.Set("X-Tenant-ID", tenant)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Without tenant
+	{
+		w := send(http.MethodPost, "/product", "", nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w = send(http.MethodPut, "/product", "", map[string]any{"name": "durian"})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	}
+	// Query and view
+	{
+		var res QueryResult[[]tproduct]
+		w := send(http.MethodPost, "/product", "1", nil)
+		json.Unmarshal(w.Body.Bytes(), &res)
+		assert.Equal(t, 2, res.Total)
+
+		w = send(http.MethodGet, "/product/all", "2", nil)
+		json.Unmarshal(w.Body.Bytes(), &res)
+		assert.Equal(t, 1, res.Total)
+		assert.Equal(t, "cherry", res.Items[0].Name)
+	}
+	// Get
+	{
+		w := send(http.MethodGet, "/product/3", "1", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = send(http.MethodGet, "/product/3", "2", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	// Create, tenant is set by server
+	{
+		w := send(http.MethodPut, "/product", "2", map[string]any{"name": "durian"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"tenantId":2`)
+
+		w = send(http.MethodPut, "/product", "2", map[string]any{"name": "fig", "tenantId": 1})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	}
+	// Update, tenant can't be changed
+	{
+		w := send(http.MethodPatch, "/product/3", "1", map[string]any{"name": "hacked"})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = send(http.MethodPatch, "/product/3", "2", map[string]any{"tenantId": 1})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = send(http.MethodPatch, "/product/3", "2", map[string]any{"name": "grape", "tenantId": 2})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var p tproduct
+		db.First(&p, 3)
+		assert.Equal(t, "grape", p.Name)
+		assert.Equal(t, uint(2), p.TenantID)
+	}
+	// Delete and batch delete
+	{
+		w := send(http.MethodDelete, "/product/1", "2", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = send(http.MethodDelete, "/product", "2", []string{"1", "2", "3"})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var count int64
+		db.Model(&tproduct{}).Where("tenant_id", 1).Count(&count)
+		assert.Equal(t, int64(2), count)
+		db.Model(&tproduct{}).Where("tenant_id", 2).Count(&count)
+		assert.Equal(t, int64(1), count)
+	}
+}
+
+func TestTenantResolver(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+	_, err := TenantFromContext("tenantId")(c)
+	assert.NotNil(t, err)
+	_, err = TenantFromClaims("claims", "tid")(c)
+	assert.NotNil(t, err)
+
+	c.Set("tenantId", uint(5))
+	c.Set("claims", map[string]any{"tid": "abc"})
+
+	v, err := TenantFromContext("tenantId")(c)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(5), v)
+
+	v, err = TenantFromClaims("claims", "tid")(c)
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", v)
+
+	obj := WebObject{
+		Model:       tproduct{},
+		TenantField: "TenantID",
+		GetDB:       func(c *gin.Context, isCreate bool) *gorm.DB { return nil },
+	}
+	assert.NotNil(t, obj.Build()) // without resolver
+}
@@ -49,6 +49,15 @@ func (c *TestClient) SendReq(path string, req *http.Request) *httptest.ResponseR
 	return w
 }
 
+// RoundTrip serve the request with the handler, so TestClient is the Transport of
+// http.Client, such as the HTTPClient of Client[T].
+func (c *TestClient) RoundTrip(req *http.Request) (*http.Response, error) {
+	w := c.SendReq(req.URL.Path, req)
+	resp := w.Result()
+	resp.Request = req
+	return resp, nil
+}
+
 // Get return *httptest.ResponseRecorder
 func (c *TestClient) Get(path string) *httptest.ResponseRecorder {
 	req, _ := http.NewRequest(http.MethodGet, path, nil)
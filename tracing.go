@@ -0,0 +1,298 @@
+package gormpher
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Tracer start the spans of WebObject actions, the nil Tracer is no-op. It's implemented
+// by MemoryTracer, or an adapter of OpenTelemetry such as:
+//
+//	type otelTracer struct{ trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string, attrs ...gormpher.Attribute) (context.Context, gormpher.Span) {
+//		ctx, span := t.Tracer.Start(ctx, name, trace.WithAttributes(toKeyValues(attrs)...))
+//		return ctx, otelSpan{span}
+//	}
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is the span started by Tracer.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Attribute is the attribute of span, the value is string, int, bool or []string.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// the attributes of spans
+const (
+	AttrObject     = "gormpher.object"
+	AttrAction     = "gormpher.action"
+	AttrView       = "gormpher.view"
+	AttrKey        = "gormpher.key"
+	AttrFilters    = "gormpher.filters"
+	AttrRows       = "gormpher.rows"
+	AttrPreloads   = "gormpher.preloads"
+	AttrStatusCode = "http.status_code"
+)
+
+const (
+	traceSpanKey = "gormpher:trace_span"
+	traceDBKey   = "gormpher:trace"
+)
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(attrs ...Attribute) {}
+func (noopSpan) RecordError(err error)            {}
+func (noopSpan) End()                             {}
+
+// traceState is the span context of db, stored in the db settings.
+type traceState struct {
+	tracer Tracer
+	ctx    context.Context
+	object string
+}
+
+// trace start the span of route, the request context is the span context.
+func (obj *WebObject) trace(action int, view string, h gin.HandlerFunc) gin.HandlerFunc {
+	if obj.Tracer == nil {
+		return h
+	}
+	return func(c *gin.Context) {
+		attrs := []Attribute{{AttrAction, ActionName(action)}}
+		if view != "" {
+			attrs = append(attrs, Attribute{AttrView, view})
+		}
+		if key := c.Param("key"); key != "" {
+			attrs = append(attrs, Attribute{AttrKey, key})
+		}
+		span, end := obj.startSpan(c, "gormpher."+ActionName(action), attrs...)
+		c.Set(traceSpanKey, span)
+		h(c)
+		span.SetAttributes(Attribute{AttrStatusCode, c.Writer.Status()})
+		var err error
+		if e := c.Errors.Last(); e != nil {
+			err = e.Err
+		}
+		end(err)
+	}
+}
+
+// startSpan start the child span of request, the request context is the span context until end.
+func (obj *WebObject) startSpan(c *gin.Context, name string, attrs ...Attribute) (Span, func(err error)) {
+	if obj.Tracer == nil {
+		return noopSpan{}, func(err error) {}
+	}
+	parent := c.Request.Context()
+	ctx, span := obj.Tracer.Start(parent, name, append([]Attribute{{AttrObject, obj.Name}}, attrs...)...)
+	c.Request = c.Request.WithContext(ctx)
+	return span, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.End()
+		c.Request = c.Request.WithContext(parent)
+	}
+}
+
+// setSpanAttributes set the attributes of request span.
+func setSpanAttributes(c *gin.Context, attrs ...Attribute) {
+	if span, ok := c.Value(traceSpanKey).(Span); ok {
+		span.SetAttributes(attrs...)
+	}
+}
+
+// openDB return the db of SelectDB or GetDB, traced, logged and instrumented with the request.
+// The import is the create of GetDB, as it writes in transaction.
+func (obj *WebObject) openDB(c *gin.Context, action int) *gorm.DB {
+	_, end := obj.startSpan(c, "gormpher.GetDB")
+	var db *gorm.DB
+	if obj.SelectDB != nil {
+		db = obj.SelectDB(c, obj, action)
+	} else {
+		db = obj.GetDB(c, action == CREATE || action == IMPORT)
+	}
+	end(nil)
+	return obj.traceDB(c, logDB(c, instrumentDB(c, db)))
+}
+
+// registered callbacks of gorm.Config
+var traceCallbacks sync.Map
+
+// traceDB start the spans of db with the request span as parent, the spans
+// of preloads require the callbacks registered by RegisterCallbacks.
+func (obj *WebObject) traceDB(c *gin.Context, db *gorm.DB) *gorm.DB {
+	if obj.Tracer == nil {
+		return db
+	}
+	state := &traceState{tracer: obj.Tracer, ctx: c.Request.Context(), object: obj.Name}
+	// new session, so the traced db can be reused by multiple statements
+	return db.Set(traceDBKey, state).Session(&gorm.Session{})
+}
+
+// startDBSpan start the child span of db, return the db within the span.
+func startDBSpan(db *gorm.DB, name string, attrs ...Attribute) (*gorm.DB, Span) {
+	v, ok := db.Get(traceDBKey)
+	if !ok {
+		return db, noopSpan{}
+	}
+	state := v.(*traceState)
+	ctx, span := state.tracer.Start(state.ctx, name, append([]Attribute{{AttrObject, state.object}}, attrs...)...)
+	// new session, so the span is not set to the statement of db
+	db = db.Session(&gorm.Session{}).Set(traceDBKey, &traceState{tracer: state.tracer, ctx: ctx, object: state.object})
+	return db, span
+}
+
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// registerTraceCallbacks register the span of preloads, which are loaded after the query.
+func registerTraceCallbacks(db *gorm.DB) {
+	const spanKey = "gormpher:trace_preload"
+	before := func(db *gorm.DB) {
+		if db.Error != nil || len(db.Statement.Preloads) == 0 {
+			return
+		}
+		v, ok := db.Get(traceDBKey)
+		if !ok {
+			return
+		}
+		state := v.(*traceState)
+		preloads := make([]string, 0, len(db.Statement.Preloads))
+		for name := range db.Statement.Preloads {
+			preloads = append(preloads, name)
+		}
+		sort.Strings(preloads)
+		_, span := state.tracer.Start(state.ctx, "gormpher.preload",
+			Attribute{AttrObject, state.object}, Attribute{AttrPreloads, preloads})
+		db.InstanceSet(spanKey, span)
+	}
+	after := func(db *gorm.DB) {
+		if v, ok := db.InstanceGet(spanKey); ok {
+			endSpan(v.(Span), db.Error)
+		}
+	}
+
+	callback := db.Callback()
+	callback.Query().Before("gorm:preload").Register("gormpher:trace_before_preload", before)
+	callback.Query().After("gorm:preload").Register("gormpher:trace_after_preload", after)
+}
+
+// the hooks in spans
+func (obj *WebObject) beforeCreate(c *gin.Context, vptr any, vals map[string]any) error {
+	_, end := obj.startSpan(c, "gormpher.BeforeCreate")
+	err := obj.BeforeCreate(c, vptr, vals)
+	end(err)
+	return err
+}
+
+func (obj *WebObject) beforeUpdate(c *gin.Context, vptr any, vals map[string]any) error {
+	_, end := obj.startSpan(c, "gormpher.BeforeUpdate")
+	err := obj.BeforeUpdate(c, vptr, vals)
+	end(err)
+	return err
+}
+
+func (obj *WebObject) beforeDelete(c *gin.Context, vptr any) error {
+	_, end := obj.startSpan(c, "gormpher.BeforeDelete")
+	err := obj.BeforeDelete(c, vptr)
+	end(err)
+	return err
+}
+
+func (obj *WebObject) beforeRender(c *gin.Context, vptr any) error {
+	_, end := obj.startSpan(c, "gormpher.BeforeRender")
+	err := obj.BeforeRender(c, vptr)
+	end(err)
+	return err
+}
+
+// MemoryTracer is the Tracer which records the ended spans in memory, for tests.
+type MemoryTracer struct {
+	mu    sync.Mutex
+	spans []*MemorySpan
+}
+
+// MemorySpan is the span recorded by MemoryTracer.
+type MemorySpan struct {
+	Name       string
+	Parent     *MemorySpan
+	Attributes map[string]any
+	Err        error
+	StartTime  time.Time
+	EndTime    time.Time
+
+	tracer *MemoryTracer
+}
+
+type memorySpanKey struct{}
+
+// NewMemoryTracer return the empty MemoryTracer.
+func NewMemoryTracer() *MemoryTracer {
+	return &MemoryTracer{}
+}
+
+func (t *MemoryTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	parent, _ := ctx.Value(memorySpanKey{}).(*MemorySpan)
+	span := &MemorySpan{
+		Name:       name,
+		Parent:     parent,
+		Attributes: make(map[string]any),
+		StartTime:  time.Now(),
+		tracer:     t,
+	}
+	span.SetAttributes(attrs...)
+	return context.WithValue(ctx, memorySpanKey{}, span), span
+}
+
+// Spans return the ended spans in the order of ending.
+func (t *MemoryTracer) Spans() []*MemorySpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*MemorySpan(nil), t.spans...)
+}
+
+// Reset clear the recorded spans.
+func (t *MemoryTracer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = nil
+}
+
+func (s *MemorySpan) SetAttributes(attrs ...Attribute) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	for _, attr := range attrs {
+		s.Attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *MemorySpan) RecordError(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.Err = err
+}
+
+func (s *MemorySpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.EndTime = time.Now()
+	s.tracer.spans = append(s.tracer.spans, s)
+}
@@ -0,0 +1,146 @@
+package gormpher
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type ttask struct {
+	ID      uint   `json:"id" gorm:"primarykey"`
+	Title   string `json:"title"`
+	OwnerID uint   `json:"ownerId"`
+	Owner   *tuser `json:"owner" gorm:"foreignKey:OwnerID"`
+}
+
+func initTracingTest(t *testing.T) (*TestClient, *MemoryTracer) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{}, ttask{})
+	db.Create(&tuser{ID: 1, Name: "alice"})
+	db.Create(&ttask{ID: 1, Title: "write", OwnerID: 1})
+	db.Create(&ttask{ID: 2, Title: "read", OwnerID: 1})
+	RegisterCallbacks(db)
+
+	tracer := NewMemoryTracer()
+	r := gin.Default()
+	RegisterObjects(r.Group("api"), []WebObject{
+		{
+			Name:         "task",
+			Model:        ttask{},
+			EditFields:   []string{"Title"},
+			FilterFields: []string{"Title"},
+			GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			Tracer:       tracer,
+			BeforeCreate: func(ctx *gin.Context, vptr any, vals map[string]any) error {
+				if vptr.(*ttask).Title == "" {
+					return errors.New("title is required")
+				}
+				return nil
+			},
+			BeforeRender: func(ctx *gin.Context, vptr any) error {
+				// the request context is the hook span
+				_, span := tracer.Start(ctx.Request.Context(), "render")
+				span.End()
+				return nil
+			},
+		},
+	})
+	return NewTestClient(r), tracer
+}
+
+// spanNames return the names of spans with the parent names, such as "gormpher.GetDB<gormpher.get".
+func spanNames(spans []*MemorySpan) []string {
+	var names []string
+	for _, span := range spans {
+		name := span.Name
+		for p := span.Parent; p != nil; p = p.Parent {
+			name += "<" + p.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestTracingGet(t *testing.T) {
+	client, tracer := initTracingTest(t)
+
+	w := client.Get("/api/task/1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	spans := tracer.Spans()
+	assert.Equal(t, []string{
+		"gormpher.GetDB<gormpher.get",
+		"gormpher.preload<gormpher.get",
+		"render<gormpher.BeforeRender<gormpher.get",
+		"gormpher.BeforeRender<gormpher.get",
+		"gormpher.get",
+	}, spanNames(spans))
+
+	get := spans[len(spans)-1]
+	assert.Equal(t, "task", get.Attributes[AttrObject])
+	assert.Equal(t, "get", get.Attributes[AttrAction])
+	assert.Equal(t, "1", get.Attributes[AttrKey])
+	assert.Equal(t, 1, get.Attributes[AttrRows])
+	assert.Equal(t, http.StatusOK, get.Attributes[AttrStatusCode])
+	assert.Equal(t, []string{"Owner"}, spans[1].Attributes[AttrPreloads])
+	assert.Nil(t, get.Err)
+
+	tracer.Reset()
+	w = client.Get("/api/task/100")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	spans = tracer.Spans()
+	assert.Equal(t, []string{"gormpher.GetDB<gormpher.get", "gormpher.get"}, spanNames(spans))
+	assert.EqualError(t, spans[1].Err, "not found")
+	assert.Equal(t, http.StatusNotFound, spans[1].Attributes[AttrStatusCode])
+}
+
+func TestTracingQuery(t *testing.T) {
+	client, tracer := initTracingTest(t)
+
+	w := client.Post("/api/task", []byte(`{"filters":[{"name":"title","op":"<>","value":"none"}]}`))
+	assert.Equal(t, http.StatusOK, w.Code)
+	spans := tracer.Spans()
+	assert.Equal(t, []string{
+		"gormpher.GetDB<gormpher.query",
+		"gormpher.count<gormpher.query",
+		"gormpher.preload<gormpher.find<gormpher.query",
+		"gormpher.find<gormpher.query",
+		"render<gormpher.BeforeRender<gormpher.query",
+		"gormpher.BeforeRender<gormpher.query",
+		"render<gormpher.BeforeRender<gormpher.query",
+		"gormpher.BeforeRender<gormpher.query",
+		"gormpher.query",
+	}, spanNames(spans))
+
+	find := spans[3]
+	assert.Equal(t, 1, find.Attributes[AttrFilters])
+	assert.Equal(t, 2, find.Attributes[AttrRows])
+	query := spans[len(spans)-1]
+	assert.Equal(t, 1, query.Attributes[AttrFilters])
+	assert.Equal(t, 2, query.Attributes[AttrRows])
+}
+
+func TestTracingHooks(t *testing.T) {
+	client, tracer := initTracingTest(t)
+
+	var result ttask
+	err := client.CallPut("/api/task", ttask{Title: "sleep"}, &result)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"gormpher.BeforeCreate<gormpher.create",
+		"gormpher.GetDB<gormpher.create",
+		"gormpher.create",
+	}, spanNames(tracer.Spans()))
+
+	tracer.Reset()
+	err = client.CallPut("/api/task", ttask{}, &result)
+	assert.NotNil(t, err)
+	spans := tracer.Spans()
+	assert.Equal(t, []string{"gormpher.BeforeCreate<gormpher.create", "gormpher.create"}, spanNames(spans))
+	assert.EqualError(t, spans[0].Err, "title is required")
+	assert.Equal(t, http.StatusBadRequest, spans[1].Attributes[AttrStatusCode])
+}
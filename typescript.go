@@ -0,0 +1,372 @@
+package gormpher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// tsPrelude is the common types and request of generated client.
+const tsPrelude = `// Code generated by gormpher. DO NOT EDIT.
+
+export type Key = string | number
+export type FilterOp = '=' | '<>' | 'in' | 'not_in' | 'like' | '>' | '>=' | '<' | '<='
+export type OrderOp = 'asc' | 'desc'
+
+export interface Filter<K extends string = string> {
+  name: K
+  op: FilterOp
+  value: any
+}
+
+export interface Order<K extends string = string> {
+  name: K
+  op: OrderOp
+}
+
+export interface QueryForm<F extends string = string, O extends string = string> {
+  pagination?: boolean
+  pos?: number
+  limit?: number
+  keyword?: string
+  filters?: Filter<F>[]
+  orders?: Order<O>[]
+  fields?: string[]
+}
+
+export interface QueryResult<T> {
+  total?: number
+  pos?: number
+  limit?: number
+  keyword?: string
+  items: T[]
+}
+
+export class ApiError extends Error {
+  constructor(public status: number, message: string) {
+    super(message)
+  }
+}
+
+export interface ClientOptions {
+  baseURL?: string
+  headers?: Record<string, string>
+  fetch?: typeof fetch
+}
+
+// toURLQuery encode the form as the query parameters of GET query.
+export function toURLQuery(form: QueryForm = {}): string {
+  const params = new URLSearchParams()
+  if (form.pagination)
+    params.set('pagination', 'true')
+  if (form.pos)
+    params.set('pos', String(form.pos))
+  if (form.limit)
+    params.set('limit', String(form.limit))
+  if (form.keyword)
+    params.set('keyword', form.keyword)
+  for (const f of form.filters || [])
+    params.append(` + "`filter[${f.name}][${f.op}]`" + `, Array.isArray(f.value) ? f.value.join(',') : String(f.value))
+  if (form.orders?.length)
+    params.set('sort', form.orders.map(o => (o.op === 'desc' ? '-' : '') + o.name).join(','))
+  if (form.fields?.length)
+    params.set('fields', form.fields.join(','))
+  const query = params.toString()
+  return query ? ` + "`?${query}`" + ` : ''
+}
+
+function createRequest(options: ClientOptions) {
+  const doFetch = options.fetch || fetch
+  return async <T>(method: string, path: string, data?: any): Promise<T> => {
+    const resp = await doFetch((options.baseURL || '') + path, {
+      method,
+      body: data === undefined ? undefined : JSON.stringify(data),
+      headers: { 'Content-Type': 'application/json', ...options.headers },
+    })
+    if (resp.status < 200 || resp.status >= 300) {
+      let reason = await resp.text()
+      if (/json/i.test(resp.headers.get('Content-Type') || ''))
+        reason = JSON.parse(reason).error || reason
+      throw new ApiError(resp.status, reason || resp.statusText)
+    }
+    return await resp.json()
+  }
+}
+`
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+var (
+	deletedAtType     = reflect.TypeOf(gorm.DeletedAt{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// tsGenerator collect the interfaces of models and the nested structs.
+type tsGenerator struct {
+	names map[reflect.Type]string
+	types map[string]reflect.Type
+	order []reflect.Type
+}
+
+// GenerateTypeScript write the TypeScript interfaces of models and the fetch client
+// of objs, the objects must be registered or built. Such as:
+//
+//	const client = createClient({ baseURL: '/api' })
+//	const r = await client.user.query({ filters: [{ name: 'age', op: '>=', value: 18 }] })
+func GenerateTypeScript(w io.Writer, objs []WebObject) error {
+	g := &tsGenerator{
+		names: make(map[reflect.Type]string),
+		types: make(map[string]reflect.Type),
+	}
+	for i := range objs {
+		if objs[i].modelElem == nil {
+			if err := objs[i].Build(); err != nil {
+				return err
+			}
+		}
+		if _, err := g.typeName(objs[i].modelElem); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(tsPrelude)
+
+	// the nested structs are appended to order while writing
+	for i := 0; i < len(g.order); i++ {
+		if err := g.writeInterface(&buf, g.order[i]); err != nil {
+			return err
+		}
+	}
+
+	buf.WriteString("\nexport function createClient(options: ClientOptions = {}) {\n")
+	buf.WriteString("  const request = createRequest(options)\n")
+	buf.WriteString("  return {\n")
+	for i := range objs {
+		g.writeObject(&buf, &objs[i])
+	}
+	buf.WriteString("  }\n}\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// typeName return the interface name of struct, such as "User".
+func (g *tsGenerator) typeName(rt reflect.Type) (string, error) {
+	if name, ok := g.names[rt]; ok {
+		return name, nil
+	}
+	name := strings.ToUpper(rt.Name()[:1]) + rt.Name()[1:]
+	if _, ok := g.types[name]; ok {
+		return "", fmt.Errorf("duplicate type %s of %s", name, rt.PkgPath())
+	}
+	g.names[rt] = name
+	g.types[name] = rt
+	g.order = append(g.order, rt)
+	return name, nil
+}
+
+func (g *tsGenerator) writeInterface(buf *bytes.Buffer, rt reflect.Type) error {
+	fmt.Fprintf(buf, "\nexport interface %s {\n", g.names[rt])
+	if err := g.writeFields(buf, rt); err != nil {
+		return err
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+// writeFields write the json fields of struct, the embedded structs are squashed.
+func (g *tsGenerator) writeFields(buf *bytes.Buffer, rt reflect.Type) error {
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")
+		name := tag[0]
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := g.writeFields(buf, ft); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		typ, err := g.tsType(f.Type)
+		if err != nil {
+			return err
+		}
+		optional := ""
+		for _, opt := range tag[1:] {
+			switch opt {
+			case "omitempty":
+				optional = "?"
+			case "string":
+				typ = "string"
+			}
+		}
+		fmt.Fprintf(buf, "  %s%s: %s\n", tsPropertyName(name), optional, typ)
+	}
+	return nil
+}
+
+// tsType return the TypeScript type of encoding/json value.
+func (g *tsGenerator) tsType(rt reflect.Type) (string, error) {
+	nullable := false
+	if rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+		nullable = true
+	}
+
+	var typ string
+	switch {
+	case rt == reflect.TypeOf(time.Time{}):
+		typ = "string"
+	case rt == deletedAtType:
+		typ, nullable = "string", true
+	case rt.Implements(jsonMarshalerType) || reflect.PtrTo(rt).Implements(jsonMarshalerType):
+		typ = "any"
+	default:
+		switch rt.Kind() {
+		case reflect.Bool:
+			typ = "boolean"
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			typ = "number"
+		case reflect.String:
+			typ = "string"
+		case reflect.Slice, reflect.Array:
+			if rt.Kind() == reflect.Slice && rt.Elem().Kind() == reflect.Uint8 {
+				typ = "string" // base64
+				break
+			}
+			elem, err := g.tsType(rt.Elem())
+			if err != nil {
+				return "", err
+			}
+			if strings.Contains(elem, " ") {
+				elem = "(" + elem + ")"
+			}
+			typ = elem + "[]"
+			nullable = nullable || rt.Kind() == reflect.Slice
+		case reflect.Map:
+			elem, err := g.tsType(rt.Elem())
+			if err != nil {
+				return "", err
+			}
+			typ = "Record<string, " + elem + ">"
+			nullable = true
+		case reflect.Struct:
+			if rt.Name() == "" {
+				typ = "any"
+				break
+			}
+			name, err := g.typeName(rt)
+			if err != nil {
+				return "", err
+			}
+			typ = name
+		default:
+			typ = "any"
+		}
+	}
+
+	if nullable && typ != "any" {
+		typ += " | null"
+	}
+	return typ, nil
+}
+
+func tsPropertyName(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(name, "'", "\\'"))
+}
+
+// tsUnion return the union of json names of fields, such as 'name' | 'age'.
+func (obj *WebObject) tsUnion(fields []string) string {
+	var names []string
+	for _, name := range fields {
+		if jsonName, ok := fieldJSONName(obj.modelElem, name); ok {
+			names = append(names, fmt.Sprintf("'%s'", jsonName))
+		}
+	}
+	if len(names) == 0 {
+		return "never"
+	}
+	return strings.Join(names, " | ")
+}
+
+// writeObject write the methods of obj according to AllowMethods and Views.
+func (g *tsGenerator) writeObject(buf *bytes.Buffer, obj *WebObject) {
+	allowMethods := obj.AllowMethods
+	if allowMethods == 0 {
+		allowMethods = GET | CREATE | EDIT | DELETE | QUERY | BATCH
+	}
+	t := g.names[obj.modelElem]
+	p := path.Join("/", obj.Group, obj.Name)
+	form := fmt.Sprintf("QueryForm<%s, %s>", obj.tsUnion(obj.FilterFields), obj.tsUnion(obj.OrderFields))
+	keyPath := fmt.Sprintf("`%s/${encodeURIComponent(key)}`", p)
+
+	fmt.Fprintf(buf, "    %s: {\n", tsPropertyName(obj.Name))
+	if allowMethods&GET != 0 {
+		fmt.Fprintf(buf, "      get: (key: Key) => request<%s>('GET', %s),\n", t, keyPath)
+	}
+	if allowMethods&CREATE != 0 {
+		fmt.Fprintf(buf, "      create: (item: Partial<%s>) => request<%s>('PUT', '%s', item),\n", t, t, p)
+	}
+	if allowMethods&EDIT != 0 {
+		fmt.Fprintf(buf, "      edit: (key: Key, vals: Partial<Pick<%s, %s>>) => request<boolean>('PATCH', %s, vals),\n", t, obj.tsUnion(obj.EditFields), keyPath)
+	}
+	if allowMethods&DELETE != 0 {
+		fmt.Fprintf(buf, "      delete: (key: Key) => request<boolean>('DELETE', %s),\n", keyPath)
+	}
+	if allowMethods&QUERY != 0 {
+		fmt.Fprintf(buf, "      query: (form: %s = {}) => request<QueryResult<%s>>('POST', '%s', form),\n", form, t, p)
+	}
+	if allowMethods&BATCH != 0 {
+		if obj.VersionField != "" {
+			// the pairs of key and version
+			pair := fmt.Sprintf("Pick<%s, '%s' | '%s'>", t, obj.jsonPKName, obj.versionJsonName)
+			fmt.Fprintf(buf, "      batch: (items: %s[]) => request<boolean>('DELETE', '%s', items),\n", pair, p)
+		} else {
+			fmt.Fprintf(buf, "      batch: (keys: Key[]) => request<boolean>('DELETE', '%s', keys.map(String)),\n", p)
+		}
+	}
+	if len(obj.Views) > 0 {
+		buf.WriteString("      views: {\n")
+		for _, v := range obj.Views {
+			vp := path.Join(p, v.Name)
+			method := v.Method
+			if method == "" {
+				method = http.MethodPost
+			}
+			if method == http.MethodGet {
+				fmt.Fprintf(buf, "        %s: (form: %s = {}) => request<QueryResult<%s>>('GET', '%s' + toURLQuery(form)),\n", tsPropertyName(v.Name), form, t, vp)
+			} else {
+				fmt.Fprintf(buf, "        %s: (form: %s = {}) => request<QueryResult<%s>>('%s', '%s', form),\n", tsPropertyName(v.Name), form, t, method, vp)
+			}
+		}
+		buf.WriteString("      },\n")
+	}
+	buf.WriteString("    },\n")
+}
@@ -0,0 +1,171 @@
+package gormpher
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type tsBase struct {
+	CreatedAt time.Time      `json:"createdAt"`
+	DeletedAt gorm.DeletedAt `json:"deletedAt"`
+}
+
+type tsProfile struct {
+	Bio   string            `json:"bio"`
+	Links map[string]string `json:"links"`
+}
+
+type tsAccount struct {
+	ID uint `json:"id" gorm:"primarykey"`
+	tsBase
+	Name     string     `json:"name"`
+	Age      int        `json:"age,omitempty"`
+	Balance  int64      `json:"balance,string"`
+	Enabled  *bool      `json:"enabled"`
+	Tags     []string   `json:"tags"`
+	Avatar   []byte     `json:"avatar"`
+	Profile  tsProfile  `json:"profile"`
+	Friends  []*tuser   `json:"friends" gorm:"many2many:account_friends"`
+	Extra    any        `json:"x-extra"`
+	Password string     `json:"-"`
+	LastSeen *time.Time `json:"lastSeen,omitempty"`
+	internal int
+}
+
+// updateTypeScript regenerate the fixture: go test -run TestTypeScriptFixture -update
+var updateTypeScript = flag.Bool("update", false, "update testdata/client.ts")
+
+func tsTestObjects(db *gorm.DB) []WebObject {
+	return []WebObject{
+		{
+			Name:         "account",
+			Group:        "v1",
+			Model:        tsAccount{},
+			GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			EditFields:   []string{"Name", "Enabled"},
+			FilterFields: []string{"Name", "Age"},
+			OrderFields:  []string{"ID"},
+			Views: []QueryView{
+				{Name: "active", Method: http.MethodGet},
+				{Name: "recent"},
+			},
+		},
+		{
+			Name:         "user",
+			Model:        &tuser{},
+			GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			AllowMethods: GET | QUERY,
+		},
+	}
+}
+
+func TestGenerateTypeScript(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	objs := tsTestObjects(db)
+
+	var buf bytes.Buffer
+	err := GenerateTypeScript(&buf, objs)
+	assert.Nil(t, err)
+	ts := buf.String()
+
+	assert.True(t, strings.HasPrefix(ts, "// Code generated by gormpher. DO NOT EDIT."))
+	assert.Contains(t, ts, `export interface TsAccount {
+  id: number
+  createdAt: string
+  deletedAt: string | null
+  name: string
+  age?: number
+  balance: string
+  enabled: boolean | null
+  tags: string[] | null
+  avatar: string
+  profile: TsProfile
+  friends: (Tuser | null)[] | null
+  'x-extra': any
+  lastSeen?: string | null
+}`)
+	assert.Contains(t, ts, "export interface TsProfile {\n  bio: string\n  links: Record<string, string> | null\n}")
+	assert.Contains(t, ts, "export interface Tuser {\n")
+
+	assert.Contains(t, ts, `    account: {
+      get: (key: Key) => request<TsAccount>('GET', `+"`/v1/account/${encodeURIComponent(key)}`"+`),
+      create: (item: Partial<TsAccount>) => request<TsAccount>('PUT', '/v1/account', item),
+      edit: (key: Key, vals: Partial<Pick<TsAccount, 'name' | 'enabled'>>) => request<boolean>('PATCH', `+"`/v1/account/${encodeURIComponent(key)}`"+`, vals),
+      delete: (key: Key) => request<boolean>('DELETE', `+"`/v1/account/${encodeURIComponent(key)}`"+`),
+      query: (form: QueryForm<'name' | 'age', 'id'> = {}) => request<QueryResult<TsAccount>>('POST', '/v1/account', form),
+      batch: (keys: Key[]) => request<boolean>('DELETE', '/v1/account', keys.map(String)),
+      views: {
+        active: (form: QueryForm<'name' | 'age', 'id'> = {}) => request<QueryResult<TsAccount>>('GET', '/v1/account/active' + toURLQuery(form)),
+        recent: (form: QueryForm<'name' | 'age', 'id'> = {}) => request<QueryResult<TsAccount>>('POST', '/v1/account/recent', form),
+      },
+    },`)
+	assert.Contains(t, ts, `    user: {
+      get: (key: Key) => request<Tuser>('GET', `+"`/user/${encodeURIComponent(key)}`"+`),
+      query: (form: QueryForm<never, never> = {}) => request<QueryResult<Tuser>>('POST', '/user', form),
+    },`)
+
+	// batch delete of versioned object
+	type Product struct {
+		ID      uint `json:"id" gorm:"primarykey"`
+		Version int  `json:"version"`
+	}
+	buf.Reset()
+	err = GenerateTypeScript(&buf, []WebObject{{
+		Name:         "product",
+		Model:        Product{},
+		GetDB:        objs[0].GetDB,
+		VersionField: "Version",
+	}})
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), "      batch: (items: Pick<Product, 'id' | 'version'>[]) => request<boolean>('DELETE', '/product', items),\n")
+
+	// without db
+	err = GenerateTypeScript(&buf, []WebObject{{Model: tuser{}}})
+	assert.NotNil(t, err)
+}
+
+// TestTypeScriptFixture compare the generated client with the committed fixture,
+// the changes of generated client must be reviewed in testdata/client.ts.
+func TestTypeScriptFixture(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	var buf bytes.Buffer
+	err := GenerateTypeScript(&buf, tsTestObjects(db))
+	assert.Nil(t, err)
+
+	fixture := filepath.Join("testdata", "client.ts")
+	if *updateTypeScript {
+		assert.Nil(t, os.WriteFile(fixture, buf.Bytes(), 0644))
+	}
+	want, err := os.ReadFile(fixture)
+	assert.Nil(t, err)
+	assert.Equal(t, string(want), buf.String(), "run go test -run TestTypeScriptFixture -update to regenerate")
+}
+
+func TestAdminTypeScript(t *testing.T) {
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	r := gin.Default()
+	RegisterObjectsWithAdmin(r.Group("admin"), []WebObject{
+		{
+			Name:  "user",
+			Model: tuser{},
+			GetDB: func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+		},
+	})
+
+	w := NewTestClient(r).Get("/admin/client.ts")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/typescript")
+	assert.Contains(t, w.Body.String(), "export function createClient(")
+	assert.Contains(t, w.Body.String(), "create: (item: Partial<Tuser>) => request<Tuser>('PUT', '/user', item),")
+}
@@ -0,0 +1,252 @@
+package gormpher
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+var (
+	errVersionRequired = errors.New("If-Match header or version is required")
+	errVersionMismatch = errors.New("object has been modified")
+)
+
+// parseVersionField check VersionField and fill versionColumn, versionJsonName.
+// The version field must be an integer (bumped on every update) or a time.Time,
+// such as UpdatedAt (refreshed by gorm on every update).
+func (obj *WebObject) parseVersionField() error {
+	if obj.VersionField == "" {
+		return nil
+	}
+
+	f, ok := obj.modelElem.FieldByName(obj.VersionField)
+	if !ok {
+		return fmt.Errorf("%s not has version field %s", obj.Name, obj.VersionField)
+	}
+
+	ft := f.Type
+	if ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+	if ft != timeType && !isIntegerKind(ft.Kind()) {
+		return fmt.Errorf("%s version field %s must be integer or time.Time", obj.Name, obj.VersionField)
+	}
+
+	obj.versionColumn = getColumnName(obj.modelElem, obj.VersionField)
+	obj.versionJsonName = obj.VersionField
+	for k, v := range obj.jsonToFields {
+		if v == obj.VersionField {
+			obj.versionJsonName = k
+			break
+		}
+	}
+	return nil
+}
+
+// getVersion return the version of vptr as string, such as "3" or "1679043443000000000".
+// The second return value is the raw value of version column, used in where condition.
+func (obj *WebObject) getVersion(vptr any) (string, any) {
+	rv := reflect.ValueOf(vptr)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	fv := rv.FieldByName(obj.VersionField)
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return "", nil
+		}
+		fv = fv.Elem()
+	}
+	return formatVersion(fv.Interface()), fv.Interface()
+}
+
+func formatVersion(v any) string {
+	switch v := v.(type) {
+	case time.Time:
+		return strconv.FormatInt(v.UnixNano(), 10)
+	case float64:
+		return strconv.FormatInt(int64(v), 10)
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return strconv.FormatInt(t.UnixNano(), 10)
+		}
+		return v
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// getETag return the ETag of vptr generated from VersionField,
+// return empty string when VersionField is not set.
+func (obj *WebObject) getETag(vptr any) string {
+	if obj.VersionField == "" {
+		return ""
+	}
+	if version, _ := obj.getVersion(vptr); version != "" {
+		return `"` + version + `"`
+	}
+	return ""
+}
+
+// setETag write the ETag header of vptr, only work with VersionField.
+func (obj *WebObject) setETag(c *gin.Context, vptr any) {
+	if etag := obj.getETag(vptr); etag != "" {
+		c.Header("ETag", etag)
+	}
+}
+
+// checkVersion compare the version of vptr with the If-Match header or
+// the version value in request body, return http status code and error when failed.
+// - 428, neither If-Match nor version is provided
+// - 412, If-Match not match
+// - 409, version in body not match
+func (obj *WebObject) checkVersion(ifMatch string, vptr any, vals map[string]any) (int, error) {
+	current, _ := obj.getVersion(vptr)
+
+	if ifMatch != "" {
+		if !matchVersion(ifMatch, current) {
+			return http.StatusPreconditionFailed, errVersionMismatch
+		}
+		return http.StatusOK, nil
+	}
+
+	v, ok := vals[obj.versionJsonName]
+	if !ok || v == nil {
+		return http.StatusPreconditionRequired, errVersionRequired
+	}
+	if formatVersion(v) != current {
+		return http.StatusConflict, errVersionMismatch
+	}
+	return http.StatusOK, nil
+}
+
+// bindBatchVersions bind the batch delete form of versioned obj, the pairs of
+// key and version such as [{"id":1,"version":3}], return the keys and versions by key.
+// - 400, the form is invalid or the key is missing
+// - 428, the plain key or the pair without version is provided
+func (obj *WebObject) bindBatchVersions(c *gin.Context) ([]string, map[string]string, int, error) {
+	var form []any
+	if err := c.BindJSON(&form); err != nil {
+		return nil, nil, http.StatusBadRequest, err
+	}
+
+	keys := make([]string, 0, len(form))
+	versions := make(map[string]string, len(form))
+	for _, item := range form {
+		pair, ok := item.(map[string]any)
+		if !ok {
+			return nil, nil, http.StatusPreconditionRequired, errVersionRequired
+		}
+		key, ok := pair[obj.jsonPKName]
+		if !ok || key == nil {
+			return nil, nil, http.StatusBadRequest, fmt.Errorf("%s is required", obj.jsonPKName)
+		}
+		v, ok := pair[obj.versionJsonName]
+		if !ok || v == nil {
+			return nil, nil, http.StatusPreconditionRequired, errVersionRequired
+		}
+
+		k := formatKey(key)
+		keys = append(keys, k)
+		versions[k] = formatVersion(v)
+	}
+	return keys, versions, http.StatusOK, nil
+}
+
+// checkBatchVersions compare the versions of loaded items with the versions by key,
+// the missing object is treated as modified (deleted) concurrently.
+func (obj *WebObject) checkBatchVersions(items reflect.Value, versions map[string]string) error {
+	if items.Len() != len(versions) {
+		return errVersionMismatch
+	}
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i).Addr().Interface()
+		current, _ := obj.getVersion(item)
+		if versions[obj.getKey(item)] != current {
+			return errVersionMismatch
+		}
+	}
+	return nil
+}
+
+func formatKey(v any) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// matchETag check If-Match header, such as `"3"`, `W/"3"`, `"2", "3"` or `*`.
+func matchETag(header, version string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		tag = strings.TrimPrefix(tag, "W/")
+		if strings.Trim(tag, `"`) == version {
+			return true
+		}
+	}
+	return false
+}
+
+// matchVersion check If-Match header like matchETag, the representation
+// variant of ETag is ignored, such as `"3+1a2b3c4d"` matches version 3.
+func matchVersion(header, version string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		tag = strings.Trim(strings.TrimPrefix(tag, "W/"), `"`)
+		if tag == version {
+			return true
+		}
+		if i := strings.LastIndex(tag, "+"); i > 0 && tag[:i] == version {
+			return true
+		}
+	}
+	return false
+}
+
+// withVersion constrain db with the loaded version, so the write
+// affects no rows when the object has been modified concurrently.
+func (obj *WebObject) withVersion(db *gorm.DB, vptr any) *gorm.DB {
+	_, raw := obj.getVersion(vptr)
+	if raw == nil {
+		return db.Where(fmt.Sprintf("`%s` IS NULL", obj.versionColumn))
+	}
+	return db.Where(fmt.Sprintf("`%s` = ?", obj.versionColumn), raw)
+}
+
+// nextVersion add the bumped version into vals, integer version only.
+// time.Time version such as UpdatedAt is maintained by gorm.
+func (obj *WebObject) nextVersion(vals map[string]any) {
+	f, _ := obj.modelElem.FieldByName(obj.VersionField)
+	ft := f.Type
+	if ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+	if isIntegerKind(ft.Kind()) {
+		vals[obj.VersionField] = gorm.Expr(fmt.Sprintf("COALESCE(`%s`, 0) + 1", obj.versionColumn))
+	}
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package gormpher
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestVersionField(t *testing.T) {
+	type Product struct {
+		ID      uint   `json:"id" gorm:"primarykey"`
+		Name    string `json:"name"`
+		Version int    `json:"version"`
+	}
+
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(Product{})
+	db.Create(&Product{ID: 1, Name: "apple", Version: 1})
+	db.Create(&Product{ID: 2, Name: "banana", Version: 1})
+	db.Create(&Product{ID: 3, Name: "cherry", Version: 1})
+	db.Create(&Product{ID: 4, Name: "durian", Version: 2})
+
+	r := gin.Default()
+	err := RegisterObject(r, &WebObject{
+		Model:        Product{},
+		EditFields:   []string{"Name"},
+		VersionField: "Version",
+		GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+	})
+	assert.Nil(t, err)
+
+	send := func(method, path, ifMatch string, body any) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(b))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// ETag on get
+	{
+		w := send(http.MethodGet, "/product/1", "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+	}
+	// Without version
+	{
+		w := send(http.MethodPatch, "/product/1", "", map[string]any{"name": "pear"})
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	}
+	// If-Match
+	{
+		w := send(http.MethodPatch, "/product/1", `"1"`, map[string]any{"name": "pear"})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+		w = send(http.MethodPatch, "/product/1", `"1"`, map[string]any{"name": "peach"})
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	}
+	// Version in body, can't be edited by client
+	{
+		w := send(http.MethodPatch, "/product/1", "", map[string]any{"name": "peach", "version": 1})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = send(http.MethodPatch, "/product/1", "", map[string]any{"name": "peach", "version": 2})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var product Product
+		db.First(&product, 1)
+		assert.Equal(t, "peach", product.Name)
+		assert.Equal(t, 3, product.Version)
+	}
+	// Delete
+	{
+		w := send(http.MethodDelete, "/product/2", "", nil)
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+		w = send(http.MethodDelete, "/product/2", `"2"`, nil)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+		w = send(http.MethodDelete, "/product/2", "", map[string]any{"version": 1})
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	// Batch delete
+	{
+		w := send(http.MethodDelete, "/product", "", []string{"3", "4"})
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+		w = send(http.MethodDelete, "/product", "", []any{map[string]any{"id": 3}})
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+		w = send(http.MethodDelete, "/product", "", []any{map[string]any{"version": 1}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// product 4 is version 2
+		w = send(http.MethodDelete, "/product", "", []any{
+			map[string]any{"id": 3, "version": 1},
+			map[string]any{"id": 4, "version": 1},
+		})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		// product 2 has been deleted
+		w = send(http.MethodDelete, "/product", "", []any{
+			map[string]any{"id": 2, "version": 1},
+			map[string]any{"id": 3, "version": 1},
+		})
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		var count int64
+		db.Model(&Product{}).Count(&count)
+		assert.Equal(t, int64(3), count)
+
+		w = send(http.MethodDelete, "/product", "", []any{
+			map[string]any{"id": 3, "version": 1},
+			map[string]any{"id": "4", "version": "2"},
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		db.Model(&Product{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+	}
+}
+
+func TestVersionFieldUpdatedAt(t *testing.T) {
+	type Product struct {
+		ID        uint      `json:"id" gorm:"primarykey"`
+		UpdatedAt time.Time `json:"updatedAt"`
+		Name      string    `json:"name"`
+	}
+
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(Product{})
+	db.Create(&Product{ID: 1, Name: "apple"})
+
+	r := gin.Default()
+	err := RegisterObject(r, &WebObject{
+		Model:        Product{},
+		EditFields:   []string{"Name"},
+		VersionField: "UpdatedAt",
+		GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+	})
+	assert.Nil(t, err)
+
+	client := NewTestClient(r)
+
+	var product Product
+	err = client.CallGet("/product/1", nil, &product)
+	assert.Nil(t, err)
+
+	err = client.CallPatch("/product/1", map[string]any{"name": "pear", "updatedAt": product.UpdatedAt}, nil)
+	assert.Nil(t, err)
+
+	// stale updatedAt
+	err = client.CallPatch("/product/1", map[string]any{"name": "peach", "updatedAt": product.UpdatedAt}, nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), errVersionMismatch.Error())
+}
+
+func TestVersionFieldInvalid(t *testing.T) {
+	type Product struct {
+		ID   uint   `json:"id" gorm:"primarykey"`
+		Name string `json:"name"`
+	}
+
+	obj := WebObject{
+		Model:        Product{},
+		VersionField: "Name",
+		GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return nil },
+	}
+	assert.NotNil(t, obj.Build())
+
+	obj.VersionField = "Version"
+	assert.NotNil(t, obj.Build())
+}
+
+func TestMatchETag(t *testing.T) {
+	assert.True(t, matchETag(`"3"`, "3"))
+	assert.True(t, matchETag(`W/"3"`, "3"))
+	assert.True(t, matchETag(`"2", "3"`, "3"))
+	assert.True(t, matchETag(`*`, "3"))
+	assert.False(t, matchETag(`"2"`, "3"))
+}
+
+func TestMatchVersion(t *testing.T) {
+	assert.True(t, matchVersion(`"3+1a2b3c4d"`, "3"))
+	assert.True(t, matchVersion(`W/"3"`, "3"))
+	assert.True(t, matchVersion(`"a+b"`, "a+b"))
+	assert.True(t, matchVersion(`*`, "3"))
+	assert.False(t, matchVersion(`"2+1a2b3c4d"`, "3"))
+}
@@ -0,0 +1,290 @@
+package gormpher
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultWebhookTimeout = 10 * time.Second
+	maxWebhookResponse    = 1024
+)
+
+// Webhook headers
+const (
+	HeaderWebhookEvent     = "X-Webhook-Event"
+	HeaderWebhookDelivery  = "X-Webhook-Delivery"
+	HeaderWebhookTimestamp = "X-Webhook-Timestamp"
+	HeaderWebhookSignature = "X-Webhook-Signature"
+)
+
+// Webhook is the HTTP callback of WebObject changes. The payload is the Event
+// in JSON, the id of Event is the id of WebhookDelivery, POST to URL with headers:
+//
+//	X-Webhook-Event: created
+//	X-Webhook-Delivery: 1 // the id of WebhookDelivery, for deduplication of retries
+//	X-Webhook-Timestamp: 1679043443
+//	X-Webhook-Signature: sha256=hex(hmac_sha256(Secret, timestamp + "." + payload))
+type Webhook struct {
+	ID     string // the id of subscription, unique in the object, default is URL
+	URL    string
+	Events []string // the event types, such as EventCreated, empty means all
+	Secret string   // the key of HMAC signature, no signature when empty
+}
+
+// WebhookDelivery is the delivery log of webhook, need to be migrated by user:
+//
+//	db.AutoMigrate(&gormpher.WebhookDelivery{})
+type WebhookDelivery struct {
+	ID            uint            `json:"id" gorm:"primarykey"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	UpdatedAt     time.Time       `json:"updatedAt"`
+	Object        string          `json:"object" gorm:"size:64;index"`
+	EventType     string          `json:"eventType" gorm:"size:16"`
+	ObjectKey     string          `json:"key" gorm:"size:128"`
+	WebhookID     string          `json:"webhookId" gorm:"size:512"`
+	URL           string          `json:"url" gorm:"size:512"`
+	Payload       json.RawMessage `json:"payload"`
+	Timestamp     int64           `json:"timestamp"` // the timestamp of the last attempt
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"nextAttemptAt" gorm:"index"`
+	DeliveredAt   *time.Time      `json:"deliveredAt" gorm:"index"`
+	StatusCode    int             `json:"statusCode"`
+	Response      string          `json:"response"`
+	LastError     string          `json:"lastError"`
+}
+
+// WebhookDeliveryObject return a read-only WebObject to query the delivery log.
+func WebhookDeliveryObject(db *gorm.DB) WebObject {
+	return WebObject{
+		Name:         "webhook_delivery",
+		Model:        WebhookDelivery{},
+		AllowMethods: GET | QUERY,
+		FilterFields: []string{"CreatedAt", "Object", "EventType", "ObjectKey", "WebhookID", "URL", "StatusCode", "DeliveredAt"},
+		OrderFields:  []string{"ID", "CreatedAt"},
+		GetDB:        func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+	}
+}
+
+// SignWebhook return the signature of payload, such as "sha256=...".
+func SignWebhook(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook check the signature of payload, for the receiver.
+func VerifyWebhook(secret string, timestamp int64, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhook(secret, timestamp, payload)), []byte(signature))
+}
+
+// id return the ID of webhook, or URL when empty.
+func (w *Webhook) id() string {
+	if w.ID == "" {
+		return w.URL
+	}
+	return w.ID
+}
+
+// checkWebhooks check the ids of webhooks are unique, the subscriptions
+// to the same URL must have the different ID.
+func (obj *WebObject) checkWebhooks() error {
+	ids := make(map[string]struct{})
+	for i := range obj.Webhooks {
+		id := obj.Webhooks[i].id()
+		if _, ok := ids[id]; ok {
+			return fmt.Errorf("%s duplicate webhook %s", obj.Name, id)
+		}
+		ids[id] = struct{}{}
+	}
+	return nil
+}
+
+func (w *Webhook) accept(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, v := range w.Events {
+		if v == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueueWebhooks write the WebhookDeliveries of ev in transaction, the id of
+// payload is the id of delivery, so the receiver can deduplicate the retries.
+func (obj *WebObject) enqueueWebhooks(tx *gorm.DB, ev Event) error {
+	tx = tx.Session(&gorm.Session{NewDB: true})
+	for i := range obj.Webhooks {
+		hook := &obj.Webhooks[i]
+		if !hook.accept(ev.Type) {
+			continue
+		}
+
+		delivery := WebhookDelivery{
+			Object:        ev.Object,
+			EventType:     ev.Type,
+			ObjectKey:     ev.Key,
+			WebhookID:     hook.id(),
+			URL:           hook.URL,
+			NextAttemptAt: time.Now(),
+		}
+		if err := tx.Create(&delivery).Error; err != nil {
+			return err
+		}
+		ev.ID = uint64(delivery.ID)
+		payload, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&delivery).Update("payload", payload).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WebhookDispatcher deliver the pending WebhookDeliveries in order of id,
+// the failed delivery is retried with backoff until MaxAttempts, and the
+// status code, response and error of the last attempt are logged.
+// Every attempt is signed with the current timestamp and the Secret of Webhook in Objects.
+// Only one dispatcher should run on the same table.
+type WebhookDispatcher struct {
+	DB          *gorm.DB
+	Objects     []WebObject   // the registered objects with Webhooks
+	Client      *http.Client  // default timeout 10s
+	BatchSize   int           // default 100
+	Interval    time.Duration // the interval of polling, default 1s
+	MaxAttempts int           // default 10
+	// Backoff return the delay before the next attempt, default 1s * 2^(attempts-1), max 5m.
+	Backoff func(attempts int) time.Duration
+	// Logger log the errors of dispatching, default slog.Default().
+	Logger *slog.Logger
+}
+
+// Run dispatch the webhooks until ctx is done.
+func (d *WebhookDispatcher) Run(ctx context.Context) error {
+	return runDispatcher(ctx, d.Interval, d.batchSize(), d.DispatchOnce, dispatcherLogger(d.Logger))
+}
+
+// DispatchOnce deliver a batch of pending webhooks, return the number of deliveries attempted.
+func (d *WebhookDispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	maxAttempts := d.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultOutboxMaxAttempts
+	}
+
+	var deliveries []WebhookDelivery
+	err := d.DB.WithContext(ctx).
+		Where("delivered_at IS NULL AND attempts < ? AND next_attempt_at <= ?", maxAttempts, time.Now()).
+		Order("id").Limit(d.batchSize()).Find(&deliveries).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		statusCode, response, err := d.deliver(ctx, delivery)
+
+		vals := map[string]any{
+			"attempts":    delivery.Attempts + 1,
+			"timestamp":   delivery.Timestamp,
+			"status_code": statusCode,
+			"response":    response,
+		}
+		if err != nil {
+			vals["last_error"] = err.Error()
+			vals["next_attempt_at"] = time.Now().Add(d.backoff(delivery.Attempts + 1))
+		} else {
+			vals["last_error"] = ""
+			vals["delivered_at"] = time.Now()
+		}
+		if err := d.DB.WithContext(ctx).Model(delivery).Updates(vals).Error; err != nil {
+			return i, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// deliver POST the payload, return error when the status code is not 2xx.
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *WebhookDelivery) (int, string, error) {
+	delivery.Timestamp = time.Now().Unix()
+	hook := d.findWebhook(delivery)
+	if hook == nil {
+		return 0, "", fmt.Errorf("webhook %s of %s not found", delivery.WebhookID, delivery.Object)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookEvent, delivery.EventType)
+	req.Header.Set(HeaderWebhookDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(HeaderWebhookTimestamp, strconv.FormatInt(delivery.Timestamp, 10))
+	if hook.Secret != "" {
+		req.Header.Set(HeaderWebhookSignature, SignWebhook(hook.Secret, delivery.Timestamp, delivery.Payload))
+	}
+
+	client := d.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponse))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(body), fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+// findWebhook return the Webhook of delivery by object name and webhook id.
+func (d *WebhookDispatcher) findWebhook(delivery *WebhookDelivery) *Webhook {
+	for i := range d.Objects {
+		obj := &d.Objects[i]
+		if obj.Name != delivery.Object {
+			continue
+		}
+		for j := range obj.Webhooks {
+			if obj.Webhooks[j].id() == delivery.WebhookID {
+				return &obj.Webhooks[j]
+			}
+		}
+	}
+	return nil
+}
+
+func (d *WebhookDispatcher) batchSize() int {
+	if d.BatchSize <= 0 {
+		return DefaultOutboxBatchSize
+	}
+	return d.BatchSize
+}
+
+func (d *WebhookDispatcher) backoff(attempts int) time.Duration {
+	if d.Backoff != nil {
+		return d.Backoff(attempts)
+	}
+	return defaultBackoff(attempts)
+}
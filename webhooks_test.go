@@ -0,0 +1,204 @@
+package gormpher
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestWebhooks(t *testing.T) {
+	var mu sync.Mutex
+	var received []Event
+	fail := true
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		payload, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(HeaderWebhookTimestamp), 10, 64)
+		if time.Since(time.Unix(ts, 0)) > time.Minute {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if !VerifyWebhook("secret", ts, payload, r.Header.Get(HeaderWebhookSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if fail {
+			fail = false
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("try later"))
+			return
+		}
+
+		var ev Event
+		json.Unmarshal(payload, &ev)
+		assert.Equal(t, ev.Type, r.Header.Get(HeaderWebhookEvent))
+		received = append(received, ev)
+	}))
+	defer srv.Close()
+
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{}, WebhookDelivery{})
+
+	r := gin.Default()
+	deliveries := WebhookDeliveryObject(db)
+	objs := []WebObject{
+		{
+			Name:       "user",
+			Model:      tuser{},
+			EditFields: []string{"Name", "Age"},
+			GetDB:      func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			Webhooks: []Webhook{
+				{URL: srv.URL, Secret: "secret", Events: []string{EventCreated, EventDeleted}},
+			},
+		},
+		deliveries,
+	}
+	RegisterObjects(r, objs)
+
+	c := NewTestClient(r)
+	c.CallPut("/user", map[string]any{"name": "alice", "age": 9}, nil)
+	c.CallPatch("/user/1", map[string]any{"age": 20}, nil) // not subscribed
+	c.CallDelete("/user/1", nil, nil)
+
+	var count int64
+	db.Model(&WebhookDelivery{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	d := WebhookDispatcher{
+		DB:      db,
+		Objects: objs,
+		Backoff: func(attempts int) time.Duration { return 0 },
+	}
+
+	n, err := d.DispatchOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	// the first delivery failed, and logged
+	var delivery WebhookDelivery
+	db.First(&delivery, 1)
+	assert.NotZero(t, delivery.Timestamp)
+	assert.Nil(t, delivery.DeliveredAt)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.StatusCode)
+	assert.Equal(t, "try later", delivery.Response)
+	assert.Equal(t, "unexpected status 503", delivery.LastError)
+
+	n, err = d.DispatchOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	db.First(&delivery, 1)
+	assert.NotNil(t, delivery.DeliveredAt)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Equal(t, http.StatusOK, delivery.StatusCode)
+
+	mu.Lock()
+	assert.Len(t, received, 2)
+	assert.Equal(t, EventDeleted, received[0].Type)
+	assert.Equal(t, uint64(2), received[0].ID)
+	assert.Equal(t, EventCreated, received[1].Type)
+	assert.Equal(t, uint64(1), received[1].ID)
+	assert.Equal(t, "alice", received[1].Data["name"])
+	mu.Unlock()
+
+	// query the delivery log
+	var res QueryResult[[]WebhookDelivery]
+	err = c.CallPost("/webhook_delivery", QueryForm{
+		Filters: []Filter{{Name: "eventType", Op: "=", Value: EventCreated}},
+	}, &res)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, res.Total)
+	assert.Equal(t, srv.URL, res.Items[0].URL)
+
+	// the webhook is removed from objects
+	c.CallPut("/user", map[string]any{"name": "bob", "age": 9}, nil)
+	d.Objects = nil
+	n, err = d.DispatchOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	var last WebhookDelivery
+	db.Last(&last)
+	assert.Nil(t, last.DeliveredAt)
+	assert.Contains(t, last.LastError, "not found")
+}
+
+func TestWebhooksSameURL(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string][]uint64{} // secret => event ids
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		payload, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(HeaderWebhookTimestamp), 10, 64)
+		for _, secret := range []string{"a", "b"} {
+			if VerifyWebhook(secret, ts, payload, r.Header.Get(HeaderWebhookSignature)) {
+				var ev Event
+				json.Unmarshal(payload, &ev)
+				assert.Equal(t, r.Header.Get(HeaderWebhookDelivery), strconv.FormatUint(ev.ID, 10))
+				received[secret] = append(received[secret], ev.ID)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	db, _ := gorm.Open(sqlite.Open("file::memory:"), nil)
+	db.AutoMigrate(tuser{}, WebhookDelivery{})
+	objs := []WebObject{
+		{
+			Name:       "user",
+			Model:      tuser{},
+			EditFields: []string{"Name"},
+			GetDB:      func(c *gin.Context, isCreate bool) *gorm.DB { return db },
+			Webhooks: []Webhook{
+				{ID: "a", URL: srv.URL, Secret: "a", Events: []string{EventCreated}},
+				{ID: "b", URL: srv.URL, Secret: "b"},
+			},
+		},
+	}
+	r := gin.New()
+	assert.Nil(t, RegisterObjects(r, objs))
+	c := NewTestClient(r)
+	c.CallPut("/user", map[string]any{"name": "alice"}, nil)
+	c.CallPatch("/user/1", map[string]any{"name": "bob"}, nil)
+
+	d := WebhookDispatcher{DB: db, Objects: objs}
+	n, err := d.DispatchOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	mu.Lock()
+	assert.Equal(t, map[string][]uint64{"a": {1}, "b": {2, 3}}, received)
+	mu.Unlock()
+
+	// the subscriptions to the same URL must have the different ID
+	obj := WebObject{
+		Name:     "user",
+		Model:    tuser{},
+		GetDB:    objs[0].GetDB,
+		Webhooks: []Webhook{{URL: srv.URL, Secret: "a"}, {URL: srv.URL, Secret: "b"}},
+	}
+	assert.ErrorContains(t, obj.Build(), "user duplicate webhook "+srv.URL)
+}
+
+func TestSignWebhook(t *testing.T) {
+	payload := []byte(`{"type":"created"}`)
+	sig := SignWebhook("secret", 1679043443, payload)
+	assert.Contains(t, sig, "sha256=")
+	assert.True(t, VerifyWebhook("secret", 1679043443, payload, sig))
+	assert.False(t, VerifyWebhook("secret", 1679043444, payload, sig))
+	assert.False(t, VerifyWebhook("other", 1679043443, payload, sig))
+}